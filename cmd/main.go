@@ -61,6 +61,12 @@ func main() {
 	}
 	log.WithField("dbname", cfg.DBName).Info("Database connected successfully")
 
+	piiCipher, err := utils.NewPIICipher(cfg.PIIEncryptionKeys, cfg.PIIIndexKey)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize PII cipher")
+	}
+	models.SetPIICipher(piiCipher)
+
 	repo := repository.NewRepository(db)
 	processService, err := services.NewBusinessProcessService(repo.BusinessProcess)
 	if err != nil {
@@ -165,19 +171,54 @@ func setupRouter(broker *pubsub.Broker, db *gorm.DB, cfg *config.Config, process
 
 	repo := repository.NewRepository(db)
 
-	ticketService := services.NewTicketService(repo.Ticket, repo.Service, repo.ReceptionLog, repo.Patient, repo.Appointment)
-	doctorService := services.NewDoctorService(repo.Ticket, repo.Doctor, repo.Schedule, broker)
+	ticketService := services.NewTicketService(repo.Ticket, repo.Service, repo.ReceptionLog, repo.Patient, repo.Appointment, repo.Calendar, cfg.CheckInBeforeMinutes, cfg.CheckInAfterMinutes)
+	doctorService := services.NewDoctorService(repo.Ticket, repo.Doctor, repo.Schedule, repo.Cabinet, repo.Appointment, broker, cfg.CabinetAnnouncementsEnabled)
 	authService := services.NewAuthService(repo.Registrar, repo.Doctor, repo.Administrator, jwtManager)
 	databaseService := services.NewDatabaseService(repository.NewDatabaseRepository(db))
 	patientService := services.NewPatientService(repo.Patient)
-	appointmentService := services.NewAppointmentService(repo.Appointment, repo.Ticket)
+	bookingRuleService := services.NewBookingRuleService(repo.BookingRule, repo.Referral, repo.Patient, repo.Doctor)
+	auditService := services.NewAuditService(repo.Audit)
+	waitlistService := services.NewWaitlistService(repo.Waitlist, repo.Schedule, repo.Doctor, repo.Calendar, bookingRuleService, broker, auditService, cfg.WaitlistHoldMinutes)
+	appointmentService := services.NewAppointmentService(repo.Appointment, repo.Ticket, repo.Schedule, repo.Calendar, waitlistService, bookingRuleService)
+	bookingService := services.NewBookingService(repo.Appointment, repo.Schedule, repo.Patient, repo.Calendar, bookingRuleService, cfg.BookingMaxActivePerPatient)
+	followUpService := services.NewFollowUpService(repo.Appointment, repo.Ticket, repo.Schedule, repo.Doctor, bookingService, appointmentService)
 	cleanupService := services.NewCleanupService(repo.Cleanup)
-	tasksTimerService := services.NewTasksTimerService(cleanupService, cfg)
-	scheduleService := services.NewScheduleService(repo.Schedule, repo.Doctor)
+	scheduleService := services.NewScheduleService(repo.Schedule, repo.Doctor, repo.Calendar, repo.Cabinet)
+	scheduleTemplateService := services.NewScheduleTemplateService(repo.ScheduleTemplate, repo.Schedule, repo.Doctor, repo.Calendar, repo.Cabinet)
+	calendarService := services.NewCalendarService(repo.Calendar, repo.Doctor)
+	cabinetService := services.NewCabinetService(repo.Cabinet, cfg.CabinetAnnouncementsEnabled)
+	tasksTimerService := services.NewTasksTimerService(cleanupService, scheduleTemplateService, appointmentService, auditService, cfg)
 	adService := services.NewAdService(repo.Ad)
+	apiKeyService := services.NewAPIKeyService(repo.APIKey)
+
+	// Без записей приглашения в кабинет вызовы врачей остались бы без озвучки, поэтому проверяем их до старта
+	if cfg.CabinetAnnouncementsEnabled {
+		cabinets, err := cabinetService.GetAll(false)
+		if err != nil {
+			logger.Default().WithError(err).Fatal("Failed to load cabinets for announcement check")
+		}
+		numbers := make([]int, 0, len(cabinets))
+		for _, cabinet := range cabinets {
+			numbers = append(numbers, cabinet.Number)
+		}
+		if err := utils.CheckCabinetAnnouncementFiles(numbers, utils.AudioDir); err != nil {
+			logger.Default().WithError(err).Fatal("Cabinet announcements are enabled but recordings are missing: add them to the audio directory or set CABINET_ANNOUNCEMENTS=false")
+		}
+	}
+
+	// Журнал аудита должен подключаться до регистрации маршрутов
+	r.Use(middleware.Audit(auditService))
 
 	// Запускаем планировщик задач в фоне
 	go tasksTimerService.Start(context.Background())
+	// Закрываем истекшие предложения листа ожидания и передаем слоты следующим пациентам
+	go waitlistService.Start(context.Background())
+	// Шифруем документы пациентов, еще хранящиеся в открытом виде или зашифрованные старым ключом
+	go func() {
+		if _, err := patientService.ReencryptIdentifiers(); err != nil {
+			logger.Default().WithError(err).Error("Failed to re-encrypt patient identifiers")
+		}
+	}()
 
 	ticketHandler := handlers.NewTicketHandler(ticketService, cfg)
 	doctorHandler := handlers.NewDoctorHandler(doctorService, broker)
@@ -186,10 +227,19 @@ func setupRouter(broker *pubsub.Broker, db *gorm.DB, cfg *config.Config, process
 	databaseHandler := handlers.NewDatabaseHandler(databaseService)
 	audioHandler := handlers.NewAudioHandler(cfg)
 	patientHandler := handlers.NewPatientHandler(patientService)
+	auditHandler := handlers.NewAuditHandler(auditService)
 	appointmentHandler := handlers.NewAppointmentHandler(appointmentService)
+	waitlistHandler := handlers.NewWaitlistHandler(waitlistService, broker)
+	bookingHandler := handlers.NewBookingHandler(bookingService)
+	followUpHandler := handlers.NewFollowUpHandler(followUpService)
+	bookingRuleHandler := handlers.NewBookingRuleHandler(bookingRuleService)
 	scheduleHandler := handlers.NewScheduleHandler(scheduleService, broker)
+	scheduleTemplateHandler := handlers.NewScheduleTemplateHandler(scheduleTemplateService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	cabinetHandler := handlers.NewCabinetHandler(cabinetService)
 	processHandler := handlers.NewBusinessProcessHandler(processService)
 	adHandler := handlers.NewAdHandler(adService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
 
 	// SSE-эндпоинт для табло очереди регистратуры (reception)
 	r.GET("/tickets", middleware.CheckBusinessProcess(processService, "reception"), sseHandler(broker, "reception_sse"))
@@ -206,22 +256,54 @@ func setupRouter(broker *pubsub.Broker, db *gorm.DB, cfg *config.Config, process
 	}
 
 	// Админ-панель
-	admin := r.Group("/api/admin").Use(middleware.RequireAPIKey(cfg.InternalAPIKey))
+	admin := r.Group("/api/admin").Use(middleware.RequireAPIKey(apiKeyService, cfg.InternalAPIKey))
 	{
-		admin.POST("/create/doctor", authHandler.CreateDoctor)
-		admin.POST("/create/registrar", authHandler.CreateRegistrar)
-		admin.DELETE("/tickets/:id", registrarHandler.DeleteTicket)
-		admin.POST("/schedules", scheduleHandler.CreateSchedule)
-		admin.DELETE("/schedules/:id", scheduleHandler.DeleteSchedule)
-		admin.POST("/create/administrator", authHandler.CreateAdministrator)
-		admin.GET("/processes", processHandler.GetAllProcesses)
-		admin.PATCH("/processes/:name", processHandler.UpdateProcess)
-
-		admin.GET("/ads", adHandler.GetAllAds)
-		admin.POST("/ads", adHandler.CreateAd)
-		admin.GET("/ads/:id", adHandler.GetAdByID)
-		admin.PATCH("/ads/:id", adHandler.UpdateAd)
-		admin.DELETE("/ads/:id", adHandler.DeleteAd)
+		admin.POST("/create/doctor", middleware.RequireScope("admin:users"), authHandler.CreateDoctor)
+		admin.POST("/create/registrar", middleware.RequireScope("admin:users"), authHandler.CreateRegistrar)
+		admin.POST("/create/administrator", middleware.RequireScope("admin:users"), authHandler.CreateAdministrator)
+		admin.DELETE("/tickets/:id", middleware.RequireScope("admin:tickets"), registrarHandler.DeleteTicket)
+		admin.POST("/schedules", middleware.RequireScope("admin:schedules"), scheduleHandler.CreateSchedule)
+		admin.PATCH("/schedules/:id", middleware.RequireScope("admin:schedules"), scheduleHandler.UpdateSchedule)
+		admin.DELETE("/schedules/:id", middleware.RequireScope("admin:schedules"), scheduleHandler.DeleteSchedule)
+		admin.POST("/schedules/generate", middleware.RequireScope("admin:schedules"), scheduleTemplateHandler.GenerateSchedules)
+		admin.POST("/schedules/cancel", middleware.RequireScope("admin:schedules"), scheduleHandler.CancelSchedules)
+		admin.POST("/schedules/copy-week", middleware.RequireScope("admin:schedules"), scheduleHandler.CopyWeek)
+		admin.POST("/schedules/shift", middleware.RequireScope("admin:schedules"), scheduleHandler.ShiftSchedules)
+		admin.GET("/schedule-templates", middleware.RequireScope("admin:schedules"), scheduleTemplateHandler.GetAllTemplates)
+		admin.POST("/schedule-templates", middleware.RequireScope("admin:schedules"), scheduleTemplateHandler.CreateTemplate)
+		admin.PUT("/schedule-templates/:id", middleware.RequireScope("admin:schedules"), scheduleTemplateHandler.UpdateTemplate)
+		admin.DELETE("/schedule-templates/:id", middleware.RequireScope("admin:schedules"), scheduleTemplateHandler.DeleteTemplate)
+		admin.GET("/calendar/days", middleware.RequireScope("admin:schedules"), calendarHandler.GetClinicDays)
+		admin.PUT("/calendar/days/:date", middleware.RequireScope("admin:schedules"), calendarHandler.SetClinicDay)
+		admin.DELETE("/calendar/days/:date", middleware.RequireScope("admin:schedules"), calendarHandler.DeleteClinicDay)
+		admin.GET("/calendar/absences", middleware.RequireScope("admin:schedules"), calendarHandler.GetAbsences)
+		admin.POST("/calendar/absences", middleware.RequireScope("admin:schedules"), calendarHandler.CreateAbsence)
+		admin.DELETE("/calendar/absences/:id", middleware.RequireScope("admin:schedules"), calendarHandler.DeleteAbsence)
+		admin.GET("/cabinets", middleware.RequireScope("admin:cabinets"), cabinetHandler.GetAllCabinets)
+		admin.POST("/cabinets", middleware.RequireScope("admin:cabinets"), cabinetHandler.CreateCabinet)
+		admin.PATCH("/cabinets/:number", middleware.RequireScope("admin:cabinets"), cabinetHandler.UpdateCabinet)
+		admin.DELETE("/cabinets/:number", middleware.RequireScope("admin:cabinets"), cabinetHandler.DeleteCabinet)
+		admin.GET("/booking-rules", middleware.RequireScope("admin:booking_rules"), bookingRuleHandler.GetRules)
+		admin.POST("/booking-rules", middleware.RequireScope("admin:booking_rules"), bookingRuleHandler.CreateRule)
+		admin.PUT("/booking-rules/:id", middleware.RequireScope("admin:booking_rules"), bookingRuleHandler.UpdateRule)
+		admin.DELETE("/booking-rules/:id", middleware.RequireScope("admin:booking_rules"), bookingRuleHandler.DeleteRule)
+		admin.POST("/patients/:patient_id/identifiers", middleware.RequireScope("admin:pii"), patientHandler.RevealIdentifiers)
+		admin.GET("/patients/:patient_id/identifier-access", middleware.RequireScope("admin:pii"), patientHandler.GetIdentifierAccess)
+		admin.POST("/pii/rotate", middleware.RequireScope("admin:pii"), patientHandler.ReencryptIdentifiers)
+		admin.GET("/audit-logs", middleware.RequireScope("admin:audit"), auditHandler.GetAuditLogs)
+		admin.GET("/processes", middleware.RequireScope("admin:processes"), processHandler.GetAllProcesses)
+		admin.PATCH("/processes/:name", middleware.RequireScope("admin:processes"), processHandler.UpdateProcess)
+
+		admin.GET("/ads", middleware.RequireScope("admin:ads"), adHandler.GetAllAds)
+		admin.POST("/ads", middleware.RequireScope("admin:ads"), adHandler.CreateAd)
+		admin.GET("/ads/:id", middleware.RequireScope("admin:ads"), adHandler.GetAdByID)
+		admin.PATCH("/ads/:id", middleware.RequireScope("admin:ads"), adHandler.UpdateAd)
+		admin.DELETE("/ads/:id", middleware.RequireScope("admin:ads"), adHandler.DeleteAd)
+
+		admin.GET("/api-keys", middleware.RequireScope("admin:api_keys"), apiKeyHandler.GetAllAPIKeys)
+		admin.POST("/api-keys", middleware.RequireScope("admin:api_keys"), apiKeyHandler.CreateAPIKey)
+		admin.POST("/api-keys/:id/rotate", middleware.RequireScope("admin:api_keys"), apiKeyHandler.RotateAPIKey)
+		admin.DELETE("/api-keys/:id", middleware.RequireScope("admin:api_keys"), apiKeyHandler.RevokeAPIKey)
 	}
 
 	// Эндпоинты для терминала (terminal)
@@ -232,6 +314,9 @@ func setupRouter(broker *pubsub.Broker, db *gorm.DB, cfg *config.Config, process
 		tickets.POST("/print/selection", ticketHandler.Selection)
 		tickets.POST("/print/confirmation", ticketHandler.Confirmation)
 		tickets.POST("/appointment/phone", ticketHandler.CheckInByPhone)
+		tickets.POST("/appointment/oms", ticketHandler.CheckInByOMS)
+		tickets.POST("/appointment/passport", ticketHandler.CheckInByPassport)
+		tickets.POST("/appointment/code", ticketHandler.CheckInByCode)
 		tickets.GET("/download/:ticket_number", ticketHandler.DownloadTicket)
 		tickets.GET("/view/:ticket_number", ticketHandler.ViewTicket)
 	}
@@ -252,12 +337,17 @@ func setupRouter(broker *pubsub.Broker, db *gorm.DB, cfg *config.Config, process
 	{
 		protectedDoctorGroup.GET("/tickets/registered", doctorHandler.GetRegisteredTickets)
 		protectedDoctorGroup.GET("/tickets/in-progress", doctorHandler.GetInProgressTickets)
+		protectedDoctorGroup.GET("/tickets/invited", doctorHandler.GetInvitedTickets)
+		protectedDoctorGroup.POST("/invite", doctorHandler.InviteToCabinet)
+		protectedDoctorGroup.POST("/recall", doctorHandler.RecallToCabinet)
 		protectedDoctorGroup.POST("/start-appointment", doctorHandler.StartAppointment)
 		protectedDoctorGroup.POST("/complete-appointment", doctorHandler.CompleteAppointment)
 		protectedDoctorGroup.POST("/start-break", doctorHandler.StartBreak)
 		protectedDoctorGroup.POST("/end-break", doctorHandler.EndBreak)
 		protectedDoctorGroup.POST("/set-active", doctorHandler.SetDoctorActive)
 		protectedDoctorGroup.POST("/set-inactive", doctorHandler.SetDoctorInactive)
+		protectedDoctorGroup.GET("/follow-up/slots", followUpHandler.SearchSlots)
+		protectedDoctorGroup.POST("/follow-up/appointments", followUpHandler.Book)
 	}
 
 	// Эндпоинты для окна регистратора (registry)
@@ -271,23 +361,55 @@ func setupRouter(broker *pubsub.Broker, db *gorm.DB, cfg *config.Config, process
 		registrar.PATCH("/tickets/:id/status", registrarHandler.UpdateStatus)
 		registrar.GET("/patients/search", patientHandler.SearchPatients)
 		registrar.POST("/patients", patientHandler.CreatePatient)
+		registrar.GET("/patients/:patient_id", patientHandler.GetPatient)
+		registrar.PATCH("/patients/:patient_id", patientHandler.UpdatePatient)
+		registrar.DELETE("/patients/:patient_id", patientHandler.DeletePatient)
+		registrar.GET("/patients/:patient_id/duplicates", patientHandler.GetDuplicates)
+		registrar.GET("/patients/:patient_id/merges", patientHandler.GetMerges)
+		registrar.POST("/patients/:patient_id/merge", patientHandler.MergePatients)
+		registrar.POST("/patients/:patient_id/identifiers", patientHandler.RevealIdentifiers)
 		registrar.GET("/schedules/doctor/:doctor_id", appointmentHandler.GetDoctorSchedule)
 		registrar.POST("/appointments", appointmentHandler.CreateAppointment)
 		registrar.GET("/patients/:patient_id/appointments", appointmentHandler.GetPatientAppointments)
+		registrar.GET("/patients/:patient_id/referrals", bookingRuleHandler.GetReferrals)
+		registrar.POST("/patients/:patient_id/referrals", bookingRuleHandler.CreateReferral)
+		registrar.DELETE("/referrals/:id", bookingRuleHandler.DeleteReferral)
 		registrar.DELETE("/appointments/:id", appointmentHandler.DeleteAppointment)
+		registrar.POST("/appointments/:id/cancel", appointmentHandler.CancelAppointment)
+		registrar.POST("/appointments/:id/reschedule", appointmentHandler.RescheduleAppointment)
 		registrar.PATCH("/appointments/:id/confirm", appointmentHandler.ConfirmAppointment)
+		registrar.GET("/waitlist", waitlistHandler.GetEntries)
+		registrar.POST("/waitlist", waitlistHandler.CreateEntry)
+		registrar.DELETE("/waitlist/:id", waitlistHandler.CancelEntry)
+		registrar.GET("/waitlist/offers", waitlistHandler.GetOffers)
+		registrar.GET("/waitlist/offers/updates", waitlistHandler.OfferUpdates)
+		registrar.POST("/waitlist/offers/:id/accept", waitlistHandler.AcceptOffer)
+		registrar.POST("/waitlist/offers/:id/decline", waitlistHandler.DeclineOffer)
 		registrar.GET("/reports/daily", registrarHandler.GetDailyReport)
+		registrar.GET("/reports/daily/export", registrarHandler.ExportDailyReport)
+	}
+
+	// Публичная самозапись пациентов (booking)
+	booking := r.Group("/api/booking").
+		Use(middleware.CheckBusinessProcess(processService, "booking")).
+		Use(middleware.RateLimitByIP(cfg.BookingRateLimit))
+	{
+		booking.GET("/slots", bookingHandler.SearchSlots)
+		booking.POST("/appointments", bookingHandler.Book)
 	}
 
 	// Внешний API для базы данных (database)
 	dbAPI := r.Group("/api/database").
-		Use(middleware.RequireAPIKey(cfg.ExternalAPIKey)).
+		Use(middleware.RequireAPIKey(apiKeyService, cfg.ExternalAPIKey)).
 		Use(middleware.CheckBusinessProcess(processService, "database"))
 	{
-		dbAPI.POST("/:table/select", databaseHandler.GetData)
-		dbAPI.POST("/:table/insert", databaseHandler.InsertData)
-		dbAPI.PATCH("/:table/update", databaseHandler.UpdateData)
-		dbAPI.DELETE("/:table/delete", databaseHandler.DeleteData)
+		dbAPI.GET("/tables", databaseHandler.ListTables)
+		dbAPI.GET("/tables/:table", databaseHandler.GetTableSchema)
+		dbAPI.POST("/:table/select", middleware.RequireTableScope("select"), databaseHandler.GetData)
+		dbAPI.POST("/:table/export", middleware.RequireTableScope("select"), databaseHandler.ExportData)
+		dbAPI.POST("/:table/insert", middleware.RequireTableScope("insert"), databaseHandler.InsertData)
+		dbAPI.PATCH("/:table/update", middleware.RequireTableScope("update"), databaseHandler.UpdateData)
+		dbAPI.DELETE("/:table/delete", middleware.RequireTableScope("delete"), databaseHandler.DeleteData)
 	}
 
 	// Реклама используется табло регистратуры (reception)
@@ -301,6 +423,8 @@ func setupRouter(broker *pubsub.Broker, db *gorm.DB, cfg *config.Config, process
 	{
 		audioGroup.GET("/announce", audioHandler.GenerateAnnouncement)
 	}
+	// Приглашение в кабинет озвучивает табло у кабинета врача (queue_doctor)
+	r.GET("/api/audio/announce/cabinet", middleware.CheckBusinessProcess(processService, "queue_doctor"), audioHandler.GenerateCabinetAnnouncement)
 
 	// Эндпоинты для общего расписания (schedule)
 	scheduleGroup := r.Group("/api/schedules").Use(middleware.CheckBusinessProcess(processService, "schedule"))
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -29,6 +30,15 @@ type Config struct {
 	PrinterName                 string
 	MaintenanceTime             string
 	AudioBackgroundMusicEnabled bool
+	CabinetAnnouncementsEnabled bool
+	ScheduleGenerationDays      int
+	WaitlistHoldMinutes         int
+	BookingRateLimit            int
+	BookingMaxActivePerPatient  int
+	CheckInBeforeMinutes        int
+	CheckInAfterMinutes         int
+	PIIEncryptionKeys           string
+	PIIIndexKey                 string
 }
 
 // LoadConfig загружает переменные среды из .env и возвращает структуру Config
@@ -59,6 +69,15 @@ func LoadConfig() (*Config, error) {
 		PrinterName:                 getEnv("PRINTER"),
 		MaintenanceTime:             getEnv("MAINTENANCE_TIME", "00:00"),
 		AudioBackgroundMusicEnabled: getEnv("BACKGROUND_MUSIC", "true") == "true",
+		CabinetAnnouncementsEnabled: getEnv("CABINET_ANNOUNCEMENTS", "true") == "true",
+		ScheduleGenerationDays:      getEnvInt("SCHEDULE_GENERATION_DAYS", 14),
+		WaitlistHoldMinutes:         getEnvInt("WAITLIST_HOLD_MINUTES", 30),
+		BookingRateLimit:            getEnvInt("BOOKING_RATE_LIMIT", 20),
+		BookingMaxActivePerPatient:  getEnvInt("BOOKING_MAX_ACTIVE_PER_PATIENT", 3),
+		CheckInBeforeMinutes:        getEnvInt("CHECKIN_BEFORE_MINUTES", 60),
+		CheckInAfterMinutes:         getEnvInt("CHECKIN_AFTER_MINUTES", 15),
+		PIIEncryptionKeys:           getEnv("PII_ENCRYPTION_KEYS"),
+		PIIIndexKey:                 getEnv("PII_INDEX_KEY"),
 	}
 
 	// Валидация обязательных полей
@@ -77,6 +96,12 @@ func LoadConfig() (*Config, error) {
 	if cfg.DBName == "" {
 		return nil, errors.New("DB_NAME is not set in the environment")
 	}
+	if cfg.PIIEncryptionKeys == "" {
+		return nil, errors.New("PII_ENCRYPTION_KEYS is not set in the environment")
+	}
+	if cfg.PIIIndexKey == "" {
+		return nil, errors.New("PII_INDEX_KEY is not set in the environment")
+	}
 
 	return cfg, nil
 }
@@ -91,3 +116,16 @@ func getEnv(key string, defaultValue ...string) string {
 	}
 	return ""
 }
+
+// getEnvInt получает целочисленную переменную окружения; при отсутствии или ошибке разбора возвращает значение по умолчанию
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+)
+
+// utf8BOM нужен, чтобы Excel распознал кириллицу в CSV.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// csvFlushEvery - через сколько строк сбрасывать буфер в выходной поток.
+const csvFlushEvery = 500
+
+type csvWriter struct {
+	out   io.Writer
+	w     *csv.Writer
+	count int
+}
+
+func newCSVWriter(out io.Writer) (*csvWriter, error) {
+	w := csv.NewWriter(out)
+	// Excel в русской локали ожидает ';' в качестве разделителя.
+	w.Comma = ';'
+	w.UseCRLF = true
+	return &csvWriter{out: out, w: w}, nil
+}
+
+func (c *csvWriter) WriteHeader(titles []string) error {
+	if _, err := c.out.Write(utf8BOM); err != nil {
+		return err
+	}
+	return c.w.Write(titles)
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = FormatValue(v)
+	}
+	if err := c.w.Write(record); err != nil {
+		return err
+	}
+	c.count++
+	if c.count%csvFlushEvery == 0 {
+		return c.flush()
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	return c.flush()
+}
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	if f, ok := c.out.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые форматы выгрузки.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Column описывает столбец выгрузки: ключ в строке данных и заголовок в файле.
+type Column struct {
+	Key   string
+	Title string
+}
+
+// Writer построчно записывает табличные данные в файл выгрузки.
+// Вывод начинается с WriteHeader, поэтому до его вызова ответ еще можно заменить сообщением об ошибке.
+// Реализации не буферизуют весь набор данных и периодически сбрасывают вывод.
+type Writer interface {
+	WriteHeader(titles []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// NewWriter создает Writer для указанного формата.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV, "":
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат выгрузки: %s", format)
+	}
+}
+
+// NormalizeFormat возвращает формат в нижнем регистре; пустой формат означает CSV.
+func NormalizeFormat(format string) string {
+	format = strings.ToLower(format)
+	if format == "" {
+		return FormatCSV
+	}
+	return format
+}
+
+// ContentType возвращает MIME-тип для формата выгрузки.
+func ContentType(format string) string {
+	if NormalizeFormat(format) == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FileName формирует имя файла выгрузки с датой.
+func FileName(base, format string, now time.Time) string {
+	return fmt.Sprintf("%s_%s.%s", base, now.Format("2006-01-02"), NormalizeFormat(format))
+}
+
+// Titles возвращает заголовки столбцов в порядке их следования.
+func Titles(columns []Column) []string {
+	titles := make([]string, len(columns))
+	for i, col := range columns {
+		titles[i] = col.Title
+	}
+	return titles
+}
+
+// WriteMaps записывает строку, взяв значения из map по ключам столбцов.
+func WriteMaps(w Writer, columns []Column, row map[string]interface{}) error {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		values[i] = row[col.Key]
+	}
+	return w.WriteRow(values)
+}
+
+// FormatValue приводит значение к строковому представлению для ячейки.
+// Указатели разыменовываются, nil дает пустую ячейку, время выводится без часового пояса.
+func FormatValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch val := v.Interface().(type) {
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05")
+	case []byte:
+		return string(val)
+	case fmt.Stringer:
+		return val.String()
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// isNumeric сообщает, можно ли записать значение в ячейку как число.
+func isNumeric(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+)
+
+// xlsxFlushEvery - через сколько строк сбрасывать сжатые данные в выходной поток.
+const xlsxFlushEvery = 500
+
+// Статические части книги XLSX с единственным листом.
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Данные" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`},
+}
+
+const (
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter пишет книгу XLSX потоково: лист формируется строками прямо в zip-архив,
+// строки хранятся как inline-строки без таблицы общих строк.
+type xlsxWriter struct {
+	out   io.Writer
+	zip   *zip.Writer
+	sheet io.Writer
+	buf   bytes.Buffer
+	count int
+}
+
+func newXLSXWriter(out io.Writer) (*xlsxWriter, error) {
+	return &xlsxWriter{out: out}, nil
+}
+
+// WriteHeader начинает архив: записывает статические части книги и первую строку листа.
+func (x *xlsxWriter) WriteHeader(titles []string) error {
+	x.zip = zip.NewWriter(x.out)
+	for _, part := range xlsxStaticParts {
+		f, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	x.sheet = sheet
+
+	x.buf.Reset()
+	x.buf.WriteString(xlsxSheetStart)
+	x.buf.WriteString("<row>")
+	for _, title := range titles {
+		x.writeStringCell(title, true)
+	}
+	x.buf.WriteString("</row>")
+	_, err = x.sheet.Write(x.buf.Bytes())
+	return err
+}
+
+func (x *xlsxWriter) WriteRow(values []interface{}) error {
+	x.buf.Reset()
+	x.buf.WriteString("<row>")
+	for _, v := range values {
+		if isNumeric(v) {
+			x.buf.WriteString("<c><v>")
+			x.buf.WriteString(FormatValue(v))
+			x.buf.WriteString("</v></c>")
+			continue
+		}
+		x.writeStringCell(FormatValue(v), false)
+	}
+	x.buf.WriteString("</row>")
+	if _, err := x.sheet.Write(x.buf.Bytes()); err != nil {
+		return err
+	}
+
+	x.count++
+	if x.count%xlsxFlushEvery == 0 {
+		return x.flush()
+	}
+	return nil
+}
+
+func (x *xlsxWriter) Close() error {
+	if x.zip == nil {
+		if err := x.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(x.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.zip.Close(); err != nil {
+		return err
+	}
+	if f, ok := x.out.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func (x *xlsxWriter) writeStringCell(value string, bold bool) {
+	if bold {
+		x.buf.WriteString(`<c t="inlineStr" s="1"><is><t xml:space="preserve">`)
+	} else {
+		x.buf.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	}
+	_ = xml.EscapeText(&x.buf, []byte(value))
+	x.buf.WriteString("</t></is></c>")
+}
+
+func (x *xlsxWriter) flush() error {
+	if err := x.zip.Flush(); err != nil {
+		return err
+	}
+	if f, ok := x.out.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
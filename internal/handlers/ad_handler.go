@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/services"
 	"net/http"
@@ -119,6 +120,7 @@ func (h *AdHandler) CreateAd(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "ads", EntityID: strconv.FormatUint(uint64(ad.ID), 10), After: ad.ToResponse()})
 	c.JSON(http.StatusCreated, ad.ToResponse())
 }
 
@@ -148,6 +150,10 @@ func (h *AdHandler) UpdateAd(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetByID(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
 	ad, err := h.service.Update(uint(id), &req)
 	if err != nil {
 		if err.Error() == "ad with id "+c.Param("id")+" not found" {
@@ -157,6 +163,11 @@ func (h *AdHandler) UpdateAd(c *gin.Context) {
 		}
 		return
 	}
+	change := models.AuditChange{Entity: "ads", EntityID: c.Param("id"), After: ad.ToResponse()}
+	if before != nil {
+		change.Before = before.ToResponse()
+	}
+	middleware.AuditChange(c, change)
 	c.JSON(http.StatusOK, ad.ToResponse())
 }
 
@@ -178,11 +189,18 @@ func (h *AdHandler) DeleteAd(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetByID(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
 	if err := h.service.Delete(uint(id)); err != nil {
 		logger.Default().WithError(err).Error("Failed to delete ad")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete ad"})
 		return
 	}
+	if before != nil {
+		middleware.AuditChange(c, models.AuditChange{Entity: "ads", EntityID: c.Param("id"), Before: before.ToResponse()})
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Ad deleted successfully"})
 }
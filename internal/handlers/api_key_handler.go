@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler обрабатывает HTTP-запросы управления API-ключами.
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+// NewAPIKeyHandler создает новый экземпляр APIKeyHandler.
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// GetAllAPIKeys godoc
+// @Summary      Получить список API-ключей (Админ)
+// @Description  Возвращает все выпущенные ключи с областями доступа, сроком действия и временем последнего использования. Сами ключи не возвращаются.
+// @Tags         admin
+// @Produce      json
+// @Success      200 {array} models.APIKey "Список ключей"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/api-keys [get]
+func (h *APIKeyHandler) GetAllAPIKeys(c *gin.Context) {
+	keys, err := h.service.GetAll()
+	if err != nil {
+		logger.Default().WithError(err).Error("GetAllAPIKeys: failed to get keys from service")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список ключей"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey godoc
+// @Summary      Выпустить API-ключ (Админ)
+// @Description  Выпускает новый ключ с указанными областями доступа. Значение ключа возвращается только в этом ответе. Без rate_limit_per_minute действует лимит 60 запросов в минуту, 0 снимает ограничение. Выдать можно только области, которые есть у ключа запроса.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateAPIKeyRequest true "Параметры ключа"
+// @Success      201 {object} models.APIKeyIssuedResponse "Выпущенный ключ"
+// @Failure      400 {object} map[string]string "Ошибка в запросе"
+// @Failure      403 {object} map[string]string "Запрошенные области шире, чем у ключа запроса"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	log := logger.Default()
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Warn("CreateAPIKey: Failed to bind JSON")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	issuer, _ := middleware.APIKeyFromContext(c)
+	plainKey, key, err := h.service.Issue(&req, issuer)
+	if err != nil {
+		log.WithError(err).Error("CreateAPIKey: Failed to issue key")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIKeyIssuedResponse{Key: plainKey, APIKey: *key})
+}
+
+// RotateAPIKey godoc
+// @Summary      Ротация API-ключа (Админ)
+// @Description  Выпускает новый ключ с теми же настройками. Старый ключ продолжает работать в течение grace_period_minutes. Ключ запроса должен иметь все области ротируемого ключа.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID ключа"
+// @Param        request body models.RotateAPIKeyRequest false "Параметры ротации"
+// @Success      201 {object} models.APIKeyIssuedResponse "Новый ключ"
+// @Failure      400 {object} map[string]string "Ошибка в запросе"
+// @Failure      403 {object} map[string]string "У ключа запроса нет всех областей ротируемого ключа"
+// @Failure      404 {object} map[string]string "Ключ не найден"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	var req models.RotateAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+			return
+		}
+	}
+
+	before, err := h.service.GetByID(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	issuer, _ := middleware.APIKeyFromContext(c)
+	plainKey, key, err := h.service.Rotate(uint(id), time.Duration(req.GracePeriodMinutes)*time.Minute, issuer)
+	if err != nil {
+		logger.Default().WithError(err).Error("RotateAPIKey: Failed to rotate key")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	after, err := h.service.GetByID(uint(id))
+	logSnapshotError(c, err)
+	middleware.AuditChange(c, models.AuditChange{
+		Entity:   "api-keys",
+		EntityID: c.Param("id"),
+		Before:   gin.H{"key": before},
+		After:    gin.H{"key": after, "new_key": key},
+	})
+
+	c.JSON(http.StatusCreated, models.APIKeyIssuedResponse{Key: plainKey, APIKey: *key})
+}
+
+// RevokeAPIKey godoc
+// @Summary      Отозвать API-ключ (Админ)
+// @Description  Немедленно отзывает ключ, в том числе ротированный ключ, который еще действует в течение отсрочки. Перезапуск сервера не требуется.
+// @Tags         admin
+// @Produce      json
+// @Param        id path int true "ID ключа"
+// @Success      200 {object} models.APIKey "Отозванный ключ"
+// @Failure      400 {object} map[string]string "Ошибка в запросе"
+// @Failure      404 {object} map[string]string "Ключ не найден"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	before, err := h.service.GetByID(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	key, err := h.service.Revoke(uint(id))
+	if err != nil {
+		logger.Default().WithError(err).Error("RevokeAPIKey: Failed to revoke key")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "api-keys", EntityID: c.Param("id"), Before: before, After: key})
+
+	c.JSON(http.StatusOK, key)
+}
@@ -2,11 +2,12 @@ package handlers
 
 import (
 	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/services"
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -66,12 +67,15 @@ func (h *AppointmentHandler) GetDoctorSchedule(c *gin.Context) {
 // CreateAppointment godoc
 // @Summary      Создать новую запись на прием
 // @Description  Создает новую запись на прием для пациента, связывая ее со слотом в расписании и исходным талоном. Обновляет слот как занятый.
+// @Description  Запись проверяется по правилам специальности врача; при нарушениях возвращается 409 со списком violations. Чтобы записать пациента вопреки правилам, повторите запрос с override_reason.
 // @Tags         registrar
 // @Accept       json
 // @Produce      json
 // @Param        request body models.CreateAppointmentRequest true "Данные для создания записи"
 // @Success      201 {object} models.Appointment "Успешно созданная запись"
 // @Failure      400 {object} map[string]string "Ошибка: неверный формат запроса"
+// @Failure      404 {object} map[string]string "Слот не найден"
+// @Failure      409 {object} map[string]interface{} "Слот недоступен по календарю или нарушены правила записи (поле violations)"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера (например, слот уже занят)"
 // @Security     ApiKeyAuth
 // @Router       /api/registrar/appointments [post]
@@ -85,10 +89,16 @@ func (h *AppointmentHandler) CreateAppointment(c *gin.Context) {
 		return
 	}
 
-	appointment, err := h.service.CreateAppointment(&req)
+	appointment, err := h.service.CreateAppointment(&req, requestActor(c))
 	if err != nil {
+		var ruleErr *services.BookingRuleViolationError
+		if errors.As(err, &ruleErr) {
+			log.WithError(err).Warn("CreateAppointment: Booking rules violated")
+			c.JSON(http.StatusConflict, gin.H{"error": ruleErr.Error(), "violations": ruleErr.Violations})
+			return
+		}
 		log.WithError(err).Error("CreateAppointment: Failed to create appointment in service")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -124,14 +134,15 @@ func (h *AppointmentHandler) GetPatientAppointments(c *gin.Context) {
 }
 
 // DeleteAppointment godoc
-// @Summary      Удалить будущую запись
-// @Description  Удаляет запись на прием и освобождает связанный с ней слот в расписании.
+// @Summary      Отменить будущую запись без указания причины
+// @Description  Отменяет запись на прием и освобождает связанный с ней слот в расписании. Запись сохраняется в истории пациента со статусом 'отменен'. Для указания причины используйте /cancel.
 // @Tags         registrar
 // @Produce      json
 // @Param        id path int true "ID Записи"
-// @Success      200 {object} map[string]string "Запись успешно удалена"
+// @Success      200 {object} map[string]string "Запись успешно отменена"
 // @Failure      400 {object} map[string]string "Ошибка: неверный ID"
 // @Failure      404 {object} map[string]string "Запись не найдена"
+// @Failure      409 {object} map[string]string "Запись уже отменена, перенесена или состоялась"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Security     ApiKeyAuth
 // @Router       /api/registrar/appointments/{id} [delete]
@@ -143,16 +154,121 @@ func (h *AppointmentHandler) DeleteAppointment(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteAppointment(uint(id)); err != nil {
-		if strings.Contains(err.Error(), "не найдена") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	before, err := h.service.GetAppointment(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	if err := h.service.DeleteAppointment(uint(id), requestActor(c)); err != nil {
+		if status := errorStatus(err); status != http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении записи"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при отмене записи"})
+		return
+	}
+	after, err := h.service.GetAppointment(uint(id))
+	logSnapshotError(c, err)
+	middleware.AuditChange(c, models.AuditChange{Entity: "appointments", EntityID: idStr, Before: before, After: after})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Запись успешно отменена"})
+}
+
+// CancelAppointment godoc
+// @Summary      Отменить запись с указанием причины
+// @Description  Отменяет запись на прием, сохраняя причину, автора и время отмены. Слот освобождается и предлагается листу ожидания, запись остается в истории пациента.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID Записи"
+// @Param        request body models.CancelAppointmentRequest true "Причина отмены"
+// @Success      200 {object} models.Appointment "Отмененная запись"
+// @Failure      400 {object} map[string]string "Ошибка: неверный ID или не указана причина"
+// @Failure      404 {object} map[string]string "Запись не найдена"
+// @Failure      409 {object} map[string]string "Запись уже отменена, перенесена или состоялась"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/appointments/{id}/cancel [post]
+func (h *AppointmentHandler) CancelAppointment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	var req models.CancelAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetAppointment(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	appointment, err := h.service.CancelAppointment(uint(id), req.Reason, requestActor(c))
+	if err != nil {
+		logger.Default().WithError(err).Error("CancelAppointment: Failed to cancel appointment")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "appointments", EntityID: c.Param("id"), Before: before, After: appointment})
+	c.JSON(http.StatusOK, appointment)
+}
+
+// RescheduleAppointment godoc
+// @Summary      Перенести запись на другой слот
+// @Description  Атомарно занимает новый слот и освобождает прежний. Прежняя запись получает статус 'перенесен' и ссылку на новую, новая создается в статусе 'записан'.
+// @Description  Новый слот проверяется по правилам записи; при нарушениях возвращается 409 со списком violations. Чтобы перенести запись вопреки правилам, повторите запрос с override_reason.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID Записи"
+// @Param        request body models.RescheduleAppointmentRequest true "Новый слот и причина переноса"
+// @Success      200 {object} models.RescheduleAppointmentResult "Прежняя и новая записи"
+// @Failure      400 {object} map[string]string "Ошибка: неверный формат запроса"
+// @Failure      404 {object} map[string]string "Запись или слот не найдены"
+// @Failure      409 {object} map[string]interface{} "Слот занят, недоступен по календарю, запись нельзя изменить или нарушены правила записи (поле violations)"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/appointments/{id}/reschedule [post]
+func (h *AppointmentHandler) RescheduleAppointment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	var req models.RescheduleAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetAppointment(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	result, err := h.service.RescheduleAppointment(uint(id), &req, requestActor(c))
+	if err != nil {
+		var ruleErr *services.BookingRuleViolationError
+		if errors.As(err, &ruleErr) {
+			logger.Default().WithError(err).Warn("RescheduleAppointment: Booking rules violated")
+			c.JSON(http.StatusConflict, gin.H{"error": ruleErr.Error(), "violations": ruleErr.Violations})
+			return
+		}
+		logger.Default().WithError(err).Error("RescheduleAppointment: Failed to reschedule appointment")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "appointments", EntityID: c.Param("id"), Before: before, After: result.Previous})
+	c.JSON(http.StatusOK, result)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Запись успешно удалена"})
+// requestActor возвращает идентификатор пользователя из JWT для журналирования действий, например "registrar:5".
+// Для запросов с API-ключом возвращается имя ключа, например "api_key:master".
+func requestActor(c *gin.Context) string {
+	actor, _ := middleware.RequestActor(c)
+	return actor
 }
 
 type ConfirmAppointmentRequest struct {
@@ -38,7 +38,7 @@ func (h *AudioHandler) GenerateAnnouncement(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметры 'ticket' и 'window' обязательны"})
 		return
 	}
-	wavBytes, err := utils.GenerateAnnouncementWav(ticketNumber, windowNumber, "assets/audio", h.cfg.AudioBackgroundMusicEnabled)
+	wavBytes, err := utils.GenerateAnnouncementWav(ticketNumber, windowNumber, utils.AudioDir, h.cfg.AudioBackgroundMusicEnabled)
 	if err != nil {
 		log.WithError(err).Error("Audio handler: failed to generate WAV file")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сгенерировать аудиофайл: " + err.Error()})
@@ -49,3 +49,36 @@ func (h *AudioHandler) GenerateAnnouncement(c *gin.Context) {
 	c.Header("Content-Disposition", `inline; filename="announcement.wav"`)
 	c.Data(http.StatusOK, "audio/wav", wavBytes)
 }
+
+// GenerateCabinetAnnouncement создает и отдает WAV файл с приглашением пациента в кабинет врача.
+// @Summary      Сгенерировать приглашение в кабинет
+// @Description  Создает и возвращает WAV файл с озвучкой "Клиент номер ..., пройдите в кабинет номер ...". Ссылку на этот файл содержит событие cabinet_invite табло кабинета.
+// @Tags         audio
+// @Produce      audio/wav
+// @Param        ticket query string true "Номер талона (например, A007 или C21)"
+// @Param        cabinet query string true "Номер кабинета от 1 до 999 (например, 12 или 305)"
+// @Success      200 {file} file "WAV файл оповещения"
+// @Failure      400 {object} map[string]string "Ошибка: неверные параметры"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router       /api/audio/announce/cabinet [get]
+func (h *AudioHandler) GenerateCabinetAnnouncement(c *gin.Context) {
+	log := logger.Default()
+	ticketNumber := c.Query("ticket")
+	cabinetNumber := c.Query("cabinet")
+
+	if ticketNumber == "" || cabinetNumber == "" {
+		log.Warn("Audio handler: ticket or cabinet parameter is missing")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметры 'ticket' и 'cabinet' обязательны"})
+		return
+	}
+	wavBytes, err := utils.GenerateCabinetAnnouncementWav(ticketNumber, cabinetNumber, utils.AudioDir, h.cfg.AudioBackgroundMusicEnabled)
+	if err != nil {
+		log.WithError(err).Error("Audio handler: failed to generate cabinet WAV file")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сгенерировать аудиофайл: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "audio/wav")
+	c.Header("Content-Disposition", `inline; filename="cabinet_announcement.wav"`)
+	c.Data(http.StatusOK, "audio/wav", wavBytes)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler обрабатывает HTTP-запросы к журналу аудита.
+type AuditHandler struct {
+	service *services.AuditService
+}
+
+// NewAuditHandler создает новый экземпляр AuditHandler.
+func NewAuditHandler(service *services.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// GetAuditLogs godoc
+// @Summary      Журнал аудита (Админ)
+// @Description  Возвращает изменяющие запросы (POST, PUT, PATCH, DELETE) с автором, ролью, IP, эндпоинтом, сущностью, состоянием до и после и идентификатором запроса. Последние записи выводятся первыми.
+// @Tags         admin
+// @Produce      json
+// @Param        actor query string false "Автор, например registrar:5 или api_key:master"
+// @Param        role query string false "Роль: administrator, registrar, doctor, api_key"
+// @Param        entity query string false "Сущность, например tickets, processes, ads, patients или имя таблицы"
+// @Param        entity_id query string false "ID сущности"
+// @Param        method query string false "HTTP-метод: POST, PUT, PATCH, DELETE или TASK для изменений фоновых задач"
+// @Param        request_id query string false "Идентификатор запроса (заголовок X-Request-ID)"
+// @Param        from query string false "Начало периода: YYYY-MM-DD или RFC3339"
+// @Param        to query string false "Конец периода (не включая): YYYY-MM-DD (включая этот день) или RFC3339"
+// @Param        page query int false "Номер страницы" default(1)
+// @Param        limit query int false "Размер страницы (до 500)" default(50)
+// @Success      200 {object} models.AuditLogPage "Страница журнала аудита"
+// @Failure      400 {object} map[string]string "Неверные параметры"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/audit-logs [get]
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	filter := models.AuditLogFilter{
+		Actor:     c.Query("actor"),
+		Role:      c.Query("role"),
+		Entity:    c.Query("entity"),
+		EntityID:  c.Query("entity_id"),
+		Method:    strings.ToUpper(c.Query("method")),
+		RequestID: c.Query("request_id"),
+	}
+	filter.Page, _ = strconv.Atoi(c.Query("page"))
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	var err error
+	if filter.From, err = parseAuditTime(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = parseAuditTime(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.Find(filter)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetAuditLogs: Failed to get audit logs")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// parseAuditTime разбирает границу периода. Дата без времени как конец периода включает весь день.
+func parseAuditTime(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат даты '%s', используйте YYYY-MM-DD или RFC3339", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
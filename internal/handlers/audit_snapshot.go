@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// snapshotFailed проверяет ошибку чтения состояния сущности до изменения для журнала аудита.
+// Отсутствие сущности не мешает изменению: создание допустимо, а иначе ошибку вернет сервис.
+// При других ошибках отправляется ответ и изменение не выполняется, чтобы оно не попало в журнал
+// без исходного состояния.
+func snapshotFailed(c *gin.Context, err error) bool {
+	if err == nil || errorStatus(err) == http.StatusNotFound {
+		return false
+	}
+	logger.Default().WithError(err).WithField("path", c.FullPath()).Error("Не удалось получить состояние до изменения для журнала аудита")
+	c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
+	return true
+}
+
+// logSnapshotError журналирует ошибку чтения состояния после изменения: изменение уже выполнено,
+// поэтому запрос не прерывается, а в журнал аудита попадает пустое состояние.
+func logSnapshotError(c *gin.Context, err error) {
+	if err != nil {
+		logger.Default().WithError(err).WithField("path", c.FullPath()).Warn("Не удалось получить состояние после изменения для журнала аудита")
+	}
+}
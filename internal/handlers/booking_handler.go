@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookingHandler обрабатывает публичные запросы самостоятельной записи пациентов.
+type BookingHandler struct {
+	service *services.BookingService
+}
+
+// NewBookingHandler создает новый экземпляр BookingHandler.
+func NewBookingHandler(service *services.BookingService) *BookingHandler {
+	return &BookingHandler{service: service}
+}
+
+// SearchSlots godoc
+// @Summary      Найти свободные слоты по специальности
+// @Description  Возвращает свободные слоты всех врачей указанной специальности за период (по умолчанию 14 дней с сегодняшнего дня, не более 31 дня). Нерабочие дни клиники и отсутствия врачей учитываются.
+// @Tags         booking
+// @Produce      json
+// @Param        specialization query string true "Специальность врача"
+// @Param        from query string false "Дата начала периода (YYYY-MM-DD), по умолчанию сегодня"
+// @Param        to query string false "Дата окончания периода (YYYY-MM-DD)"
+// @Success      200 {array} models.BookingSlot "Свободные слоты"
+// @Failure      400 {object} map[string]string "Неверные параметры поиска"
+// @Failure      429 {object} map[string]string "Превышен лимит запросов"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router       /api/booking/slots [get]
+func (h *BookingHandler) SearchSlots(c *gin.Context) {
+	from := time.Now()
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты from, используйте YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	var to *time.Time
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты to, используйте YYYY-MM-DD"})
+			return
+		}
+		to = &parsed
+	}
+
+	slots, err := h.service.SearchSlots(c.Query("specialization"), from, to)
+	if err != nil {
+		logger.Default().WithError(err).Warn("SearchSlots: Failed to search free slots")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, slots)
+}
+
+// Book godoc
+// @Summary      Записаться на прием
+// @Description  Записывает пациента на свободный слот. Пациент подтверждает личность номером полиса ОМС и датой рождения и получает код подтверждения. Число предстоящих записей пациента ограничено, к одному врачу допускается одна предстоящая запись. Правила записи по специальности (в том числе требование направления) обойти нельзя.
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        request body models.SelfBookingRequest true "Слот и данные пациента"
+// @Success      201 {object} models.SelfBookingResponse "Подтверждение записи"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент или слот не найден"
+// @Failure      409 {object} map[string]interface{} "Слот занят или недоступен, превышен лимит записей или нарушены правила записи (поле violations)"
+// @Failure      429 {object} map[string]string "Превышен лимит запросов"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router       /api/booking/appointments [post]
+func (h *BookingHandler) Book(c *gin.Context) {
+	var req models.SelfBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	result, err := h.service.Book(&req)
+	if err != nil {
+		var ruleErr *services.BookingRuleViolationError
+		if errors.As(err, &ruleErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": ruleErr.Error(), "violations": ruleErr.Violations})
+			return
+		}
+		logger.Default().WithError(err).Warn("Book: Failed to create self-service booking")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
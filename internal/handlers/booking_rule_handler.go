@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookingRuleHandler обрабатывает HTTP-запросы правил записи и направлений пациентов.
+type BookingRuleHandler struct {
+	service *services.BookingRuleService
+}
+
+// NewBookingRuleHandler создает новый экземпляр BookingRuleHandler.
+func NewBookingRuleHandler(service *services.BookingRuleService) *BookingRuleHandler {
+	return &BookingRuleHandler{service: service}
+}
+
+// GetRules godoc
+// @Summary      Получить правила записи (Админ)
+// @Description  Возвращает правила записи по всем специальностям, включая выключенные.
+// @Tags         admin
+// @Produce      json
+// @Success      200 {array} models.BookingRule "Правила записи"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/booking-rules [get]
+func (h *BookingRuleHandler) GetRules(c *gin.Context) {
+	rules, err := h.service.GetRules()
+	if err != nil {
+		logger.Default().WithError(err).Error("GetRules: Failed to get booking rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить правила записи"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateRule godoc
+// @Summary      Создать правила записи для специальности (Админ)
+// @Description  Задает ограничения записи к врачам специальности: число предстоящих записей пациента, минимальный интервал между визитами, возраст и обязательность направления.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.BookingRuleRequest true "Правила записи"
+// @Success      201 {object} models.BookingRule "Созданные правила"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      409 {object} map[string]string "Правила для специальности уже существуют"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/booking-rules [post]
+func (h *BookingRuleHandler) CreateRule(c *gin.Context) {
+	var req models.BookingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(&req)
+	if err != nil {
+		logger.Default().WithError(err).Error("CreateRule: Failed to create booking rule")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "booking-rules", EntityID: strconv.FormatUint(uint64(rule.ID), 10), After: rule})
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule godoc
+// @Summary      Изменить правила записи (Админ)
+// @Description  Полностью заменяет правила записи с указанным ID.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID правил"
+// @Param        request body models.BookingRuleRequest true "Правила записи"
+// @Success      200 {object} models.BookingRule "Обновленные правила"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Правила не найдены"
+// @Failure      409 {object} map[string]string "Правила для специальности уже существуют"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/booking-rules/{id} [put]
+func (h *BookingRuleHandler) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+	var req models.BookingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetRule(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	rule, err := h.service.UpdateRule(uint(id), &req)
+	if err != nil {
+		logger.Default().WithError(err).Error("UpdateRule: Failed to update booking rule")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "booking-rules", EntityID: c.Param("id"), Before: before, After: rule})
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule godoc
+// @Summary      Удалить правила записи (Админ)
+// @Tags         admin
+// @Produce      json
+// @Param        id path int true "ID правил"
+// @Success      200 {object} map[string]string "Правила удалены"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Правила не найдены"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/booking-rules/{id} [delete]
+func (h *BookingRuleHandler) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	before, err := h.service.GetRule(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	if err := h.service.DeleteRule(uint(id)); err != nil {
+		logger.Default().WithError(err).Error("DeleteRule: Failed to delete booking rule")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "booking-rules", EntityID: c.Param("id"), Before: before})
+	c.JSON(http.StatusOK, gin.H{"message": "Правила записи удалены"})
+}
+
+// GetReferrals godoc
+// @Summary      Получить направления пациента
+// @Description  Возвращает все направления пациента, в том числе использованные для записи.
+// @Tags         registrar
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Success      200 {array} models.Referral "Направления пациента"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id}/referrals [get]
+func (h *BookingRuleHandler) GetReferrals(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("patient_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID пациента"})
+		return
+	}
+
+	referrals, err := h.service.GetReferrals(uint(patientID))
+	if err != nil {
+		logger.Default().WithError(err).Error("GetReferrals: Failed to get referrals")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить направления"})
+		return
+	}
+	c.JSON(http.StatusOK, referrals)
+}
+
+// CreateReferral godoc
+// @Summary      Зарегистрировать направление пациента
+// @Description  Сохраняет направление к специальности. Направление используется при записи к специальности, для которой оно обязательно.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Param        request body models.CreateReferralRequest true "Данные направления"
+// @Success      201 {object} models.Referral "Сохраненное направление"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id}/referrals [post]
+func (h *BookingRuleHandler) CreateReferral(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("patient_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID пациента"})
+		return
+	}
+	var req models.CreateReferralRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	referral, err := h.service.CreateReferral(uint(patientID), &req)
+	if err != nil {
+		logger.Default().WithError(err).Error("CreateReferral: Failed to create referral")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, referral)
+}
+
+// DeleteReferral godoc
+// @Summary      Удалить направление
+// @Description  Удаляет ошибочно внесенное направление. Направление, использованное для записи, удалить нельзя.
+// @Tags         registrar
+// @Produce      json
+// @Param        id path int true "ID направления"
+// @Success      200 {object} map[string]string "Направление удалено"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Направление не найдено"
+// @Failure      409 {object} map[string]string "Направление уже использовано"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/referrals/{id} [delete]
+func (h *BookingRuleHandler) DeleteReferral(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	if err := h.service.DeleteReferral(uint(id)); err != nil {
+		logger.Default().WithError(err).Error("DeleteReferral: Failed to delete referral")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Направление удалено"})
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
+	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/services"
 	"net/http"
 
@@ -63,6 +65,10 @@ func (h *BusinessProcessHandler) UpdateProcess(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetByName(processName)
+	if snapshotFailed(c, err) {
+		return
+	}
 	process, err := h.service.UpdateStatus(processName, req.IsEnabled)
 	if err != nil {
 		log.WithError(err).Error("UpdateProcess: service returned an error")
@@ -74,5 +80,6 @@ func (h *BusinessProcessHandler) UpdateProcess(c *gin.Context) {
 		return
 	}
 
+	middleware.AuditChange(c, models.AuditChange{Entity: "processes", EntityID: processName, Before: before, After: process})
 	c.JSON(http.StatusOK, process)
 }
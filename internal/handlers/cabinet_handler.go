@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CabinetHandler struct {
+	service *services.CabinetService
+}
+
+func NewCabinetHandler(service *services.CabinetService) *CabinetHandler {
+	return &CabinetHandler{service: service}
+}
+
+// GetAllCabinets godoc
+// @Summary      Получить справочник кабинетов (Админ)
+// @Description  Возвращает все кабинеты клиники, включая выведенные из эксплуатации.
+// @Tags         admin
+// @Produce      json
+// @Success      200 {array} models.Cabinet "Список кабинетов"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/cabinets [get]
+func (h *CabinetHandler) GetAllCabinets(c *gin.Context) {
+	cabinets, err := h.service.GetAll(false)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetAllCabinets: Failed to get cabinets")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список кабинетов"})
+		return
+	}
+	c.JSON(http.StatusOK, cabinets)
+}
+
+// CreateCabinet godoc
+// @Summary      Создать кабинет (Админ)
+// @Description  Добавляет кабинет в справочник: номер, название, этаж, крыло, как пройти и оборудование.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateCabinetRequest true "Параметры кабинета"
+// @Success      201 {object} models.Cabinet "Созданный кабинет"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      409 {object} map[string]string "Кабинет с таким номером уже существует"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/cabinets [post]
+func (h *CabinetHandler) CreateCabinet(c *gin.Context) {
+	var req models.CreateCabinetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	cabinet, err := h.service.Create(&req)
+	if err != nil {
+		logger.Default().WithError(err).Error("CreateCabinet: Failed to create cabinet")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "cabinets", EntityID: strconv.Itoa(cabinet.Number), After: cabinet})
+	c.JSON(http.StatusCreated, cabinet)
+}
+
+// UpdateCabinet godoc
+// @Summary      Изменить кабинет (Админ)
+// @Description  Частично обновляет сведения о кабинете. Деактивированный кабинет нельзя назначить в новые слоты и шаблоны.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        number path int true "Номер кабинета"
+// @Param        request body models.UpdateCabinetRequest true "Изменяемые поля"
+// @Success      200 {object} models.Cabinet "Обновленный кабинет"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Кабинет не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/cabinets/{number} [patch]
+func (h *CabinetHandler) UpdateCabinet(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный номер кабинета"})
+		return
+	}
+
+	var req models.UpdateCabinetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetByNumber(number)
+	if snapshotFailed(c, err) {
+		return
+	}
+	cabinet, err := h.service.Update(number, &req)
+	if err != nil {
+		logger.Default().WithError(err).Error("UpdateCabinet: Failed to update cabinet")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "cabinets", EntityID: c.Param("number"), Before: before, After: cabinet})
+	c.JSON(http.StatusOK, cabinet)
+}
+
+// DeleteCabinet godoc
+// @Summary      Удалить кабинет (Админ)
+// @Description  Удаляет кабинет из справочника. Кабинет, используемый в расписании или шаблонах, удалить нельзя - его следует деактивировать.
+// @Tags         admin
+// @Produce      json
+// @Param        number path int true "Номер кабинета"
+// @Success      200 {object} map[string]string "Кабинет удален"
+// @Failure      400 {object} map[string]string "Неверный номер кабинета"
+// @Failure      404 {object} map[string]string "Кабинет не найден"
+// @Failure      409 {object} map[string]string "Кабинет используется в расписании"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/cabinets/{number} [delete]
+func (h *CabinetHandler) DeleteCabinet(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный номер кабинета"})
+		return
+	}
+
+	before, err := h.service.GetByNumber(number)
+	if snapshotFailed(c, err) {
+		return
+	}
+	if err := h.service.Delete(number); err != nil {
+		logger.Default().WithError(err).Error("DeleteCabinet: Failed to delete cabinet")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "cabinets", EntityID: c.Param("number"), Before: before})
+	c.JSON(http.StatusOK, gin.H{"message": "Кабинет удален"})
+}
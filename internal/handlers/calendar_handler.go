@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	service *services.CalendarService
+}
+
+func NewCalendarHandler(service *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+// GetClinicDays godoc
+// @Summary      Получить календарь клиники (Админ)
+// @Description  Возвращает нерабочие и сокращенные дни клиники за период.
+// @Tags         admin
+// @Produce      json
+// @Param        from query string true "Начало периода (YYYY-MM-DD)"
+// @Param        to query string true "Конец периода (YYYY-MM-DD)"
+// @Success      200 {array} models.ClinicDay "Особые дни клиники"
+// @Failure      400 {object} map[string]string "Неверный формат даты"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/calendar/days [get]
+func (h *CalendarHandler) GetClinicDays(c *gin.Context) {
+	from, to, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+
+	days, err := h.service.GetClinicDays(from, to)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetClinicDays: Failed to get clinic calendar")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить календарь клиники"})
+		return
+	}
+	c.JSON(http.StatusOK, days)
+}
+
+// SetClinicDay godoc
+// @Summary      Задать особый день клиники (Админ)
+// @Description  Отмечает дату как нерабочую или сокращенную (с часами работы). Повторный вызов заменяет прежние параметры дня. Записи пациентов, попавшие в нерабочее время, помечаются и возвращаются для обзвона.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        date path string true "Дата (YYYY-MM-DD)"
+// @Param        request body models.ClinicDayRequest true "Тип дня и часы работы"
+// @Success      200 {object} models.ClinicDayResult "Сохраненный день и затронутые записи"
+// @Failure      400 {object} map[string]string "Неверный формат даты или параметры дня"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/calendar/days/{date} [put]
+func (h *CalendarHandler) SetClinicDay(c *gin.Context) {
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты, используйте YYYY-MM-DD"})
+		return
+	}
+
+	var req models.ClinicDayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetClinicDay(date)
+	if snapshotFailed(c, err) {
+		return
+	}
+	result, err := h.service.SetClinicDay(date, &req)
+	if err != nil {
+		logger.Default().WithError(err).Error("SetClinicDay: Failed to save clinic day")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "days", EntityID: c.Param("date"), Before: before, After: result.Day})
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteClinicDay godoc
+// @Summary      Удалить особый день клиники (Админ)
+// @Description  Возвращает дате обычный режим работы и снимает связанные с ней пометки с записей пациентов.
+// @Tags         admin
+// @Produce      json
+// @Param        date path string true "Дата (YYYY-MM-DD)"
+// @Success      200 {object} map[string]string "День удален из календаря"
+// @Failure      400 {object} map[string]string "Неверный формат даты"
+// @Failure      404 {object} map[string]string "День не найден в календаре"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/calendar/days/{date} [delete]
+func (h *CalendarHandler) DeleteClinicDay(c *gin.Context) {
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты, используйте YYYY-MM-DD"})
+		return
+	}
+
+	before, err := h.service.GetClinicDay(date)
+	if snapshotFailed(c, err) {
+		return
+	}
+	if err := h.service.DeleteClinicDay(date); err != nil {
+		logger.Default().WithError(err).Error("DeleteClinicDay: Failed to delete clinic day")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "days", EntityID: c.Param("date"), Before: before})
+	c.JSON(http.StatusOK, gin.H{"message": "День удален из календаря клиники"})
+}
+
+// GetAbsences godoc
+// @Summary      Получить отсутствия врачей (Админ)
+// @Description  Возвращает отпуска, больничные и другие отсутствия врачей, пересекающиеся с периодом.
+// @Tags         admin
+// @Produce      json
+// @Param        from query string true "Начало периода (YYYY-MM-DD)"
+// @Param        to query string true "Конец периода (YYYY-MM-DD)"
+// @Param        doctor_id query int false "ID врача"
+// @Success      200 {array} models.DoctorAbsence "Отсутствия врачей"
+// @Failure      400 {object} map[string]string "Неверный формат параметров"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/calendar/absences [get]
+func (h *CalendarHandler) GetAbsences(c *gin.Context) {
+	from, to, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+
+	var doctorID *uint
+	if raw := c.Query("doctor_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID врача"})
+			return
+		}
+		value := uint(id)
+		doctorID = &value
+	}
+
+	absences, err := h.service.GetAbsences(doctorID, from, to)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetAbsences: Failed to get doctor absences")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить отсутствия врачей"})
+		return
+	}
+	c.JSON(http.StatusOK, absences)
+}
+
+// CreateAbsence godoc
+// @Summary      Зарегистрировать отсутствие врача (Админ)
+// @Description  Регистрирует отпуск, больничный или командировку врача за период; start_time и end_time ограничивают отсутствие частью каждого дня. Слоты периода становятся недоступны для записи, а существующие записи пациентов помечаются и возвращаются для обзвона.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.DoctorAbsenceRequest true "Параметры отсутствия"
+// @Success      201 {object} models.DoctorAbsenceResult "Созданное отсутствие и затронутые записи"
+// @Failure      400 {object} map[string]string "Неверный формат запроса или период"
+// @Failure      404 {object} map[string]string "Врач не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/calendar/absences [post]
+func (h *CalendarHandler) CreateAbsence(c *gin.Context) {
+	var req models.DoctorAbsenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	result, err := h.service.CreateAbsence(&req)
+	if err != nil {
+		logger.Default().WithError(err).Error("CreateAbsence: Failed to create doctor absence")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "absences", EntityID: strconv.FormatUint(uint64(result.Absence.ID), 10), After: result.Absence})
+	c.JSON(http.StatusCreated, result)
+}
+
+// DeleteAbsence godoc
+// @Summary      Удалить отсутствие врача (Админ)
+// @Description  Удаляет отсутствие врача и снимает связанные с ним пометки с записей пациентов.
+// @Tags         admin
+// @Produce      json
+// @Param        id path int true "ID отсутствия"
+// @Success      200 {object} map[string]string "Отсутствие удалено"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Отсутствие не найдено"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/calendar/absences/{id} [delete]
+func (h *CalendarHandler) DeleteAbsence(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	before, err := h.service.GetAbsence(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	if err := h.service.DeleteAbsence(uint(id)); err != nil {
+		logger.Default().WithError(err).Error("DeleteAbsence: Failed to delete doctor absence")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "absences", EntityID: c.Param("id"), Before: before})
+	c.JSON(http.StatusOK, gin.H{"message": "Отсутствие врача удалено"})
+}
+
+// parsePeriodQuery читает обязательные параметры from и to в формате YYYY-MM-DD.
+// При ошибке ответ 400 уже отправлен.
+func parsePeriodQuery(c *gin.Context) (time.Time, time.Time, bool) {
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты from, используйте YYYY-MM-DD"})
+		return time.Time{}, time.Time{}, false
+	}
+	to, err := time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты to, используйте YYYY-MM-DD"})
+		return time.Time{}, time.Time{}, false
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Дата окончания периода раньше даты начала"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"ElectronicQueue/internal/export"
 	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/services"
 	"net/http"
@@ -19,16 +21,18 @@ func NewDatabaseHandler(service *services.DatabaseService) *DatabaseHandler {
 
 // GetData обрабатывает запрос на получение данных из таблицы.
 // @Summary      Получение данных из таблицы
-// @Description  Позволяет получить данные из указанной таблицы с фильтрацией и пагинацией.
+// @Description  Позволяет получить данные из указанной таблицы с фильтрацией, сортировкой, выбором полей и пагинацией.
+// @Description  Фильтры поддерживают вложенные группы и операторы BETWEEN, ILIKE, NOT IN, IS NULL, IS NOT NULL.
+// @Description  Для keyset-пагинации передайте в cursor значение next_cursor из предыдущего ответа.
 // @Tags         database
 // @Accept       json
 // @Produce      json
 // @Param        table path string true "Имя таблицы для получения данных (e.g., tickets, doctors)"
-// @Param        request body models.GetDataRequest true "Фильтры и параметры пагинации"
+// @Param        request body models.GetDataRequest true "Фильтры, сортировка, поля и параметры пагинации"
 // @Success      200 {object} map[string]interface{} "Успешный ответ с данными"
 // @Failure      400 {object} map[string]string "Ошибка в запросе (неверная таблица, поле или оператор)"
 // @Failure      401 {object} map[string]string "Отсутствует ключ API"
-// @Failure      403 {object} map[string]string "Неверный ключ API"
+// @Failure      403 {object} map[string]string "Неверный ключ API или таблица/операция закрыта политикой"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Security     ApiKeyAuth
 // @Router       /api/database/{table}/select [post]
@@ -42,21 +46,55 @@ func (h *DatabaseHandler) GetData(c *gin.Context) {
 		return
 	}
 
-	data, total, err := h.service.GetData(tableName, req)
+	data, total, nextCursor, err := h.service.GetData(tableName, req)
 	if err != nil {
 		logger.Default().WithError(err).Error("Database handler (GetData): service returned an error")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"page":  req.Page,
-		"limit": req.Limit,
-		"total": total,
-		"data":  data,
+		"page":        req.Page,
+		"limit":       req.Limit,
+		"total":       total,
+		"next_cursor": nextCursor,
+		"data":        data,
 	})
 }
 
+// ExportData обрабатывает запрос на выгрузку данных таблицы в файл.
+// @Summary      Выгрузка данных таблицы в CSV или XLSX
+// @Description  Принимает те же фильтры, сортировку и выбор полей, что и select, но выгружает все подходящие записи потоком.
+// @Description  Параметры page, limit и cursor игнорируются.
+// @Tags         database
+// @Accept       json
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        table path string true "Имя таблицы для выгрузки (e.g., tickets, doctors)"
+// @Param        format query string false "Формат файла (csv | xlsx)" default(csv)
+// @Param        request body models.GetDataRequest true "Фильтры, сортировка и поля"
+// @Success      200 {file} file "Файл выгрузки"
+// @Failure      400 {object} map[string]string "Ошибка в запросе"
+// @Failure      401 {object} map[string]string "Отсутствует ключ API"
+// @Failure      403 {object} map[string]string "Неверный ключ API или таблица/операция закрыта политикой"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/database/{table}/export [post]
+func (h *DatabaseHandler) ExportData(c *gin.Context) {
+	tableName := c.Param("table")
+
+	var req models.GetDataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Default().WithError(err).Warn("Database handler (ExportData): failed to bind JSON")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	streamExport(c, tableName, func(w export.Writer) error {
+		return h.service.ExportData(tableName, req, w)
+	}, errorStatus)
+}
+
 // InsertData обрабатывает запрос на вставку данных в таблицу.
 // @Summary      Вставка данных в таблицу
 // @Description  Позволяет вставить одну или несколько записей в указанную таблицу.
@@ -68,7 +106,7 @@ func (h *DatabaseHandler) GetData(c *gin.Context) {
 // @Success      201 {object} map[string]interface{} "Данные успешно вставлены"
 // @Failure      400 {object} map[string]string "Ошибка в запросе"
 // @Failure      401 {object} map[string]string "Отсутствует ключ API"
-// @Failure      403 {object} map[string]string "Неверный ключ API"
+// @Failure      403 {object} map[string]string "Неверный ключ API или таблица/операция закрыта политикой"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Security     ApiKeyAuth
 // @Router       /api/database/{table}/insert [post]
@@ -85,9 +123,10 @@ func (h *DatabaseHandler) InsertData(c *gin.Context) {
 	rowsAffected, err := h.service.InsertData(tableName, req)
 	if err != nil {
 		logger.Default().WithError(err).Error("Database handler (InsertData): service returned an error")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
+	middleware.AuditChange(c, models.AuditChange{Entity: tableName, After: gin.H{"rows_affected": rowsAffected, "data": req.Data}})
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message":       "Data inserted successfully",
@@ -106,7 +145,7 @@ func (h *DatabaseHandler) InsertData(c *gin.Context) {
 // @Success      200 {object} map[string]interface{} "Данные успешно обновлены"
 // @Failure      400 {object} map[string]string "Ошибка в запросе (например, обновление без фильтров)"
 // @Failure      401 {object} map[string]string "Отсутствует ключ API"
-// @Failure      403 {object} map[string]string "Неверный ключ API"
+// @Failure      403 {object} map[string]string "Неверный ключ API или таблица/операция закрыта политикой"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Security     ApiKeyAuth
 // @Router       /api/database/{table}/update [patch]
@@ -120,12 +159,21 @@ func (h *DatabaseHandler) UpdateData(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.AuditRows(tableName, req.Filters)
+	if snapshotFailed(c, err) {
+		return
+	}
 	rowsAffected, err := h.service.UpdateData(tableName, req)
 	if err != nil {
 		logger.Default().WithError(err).Error("Database handler (UpdateData): service returned an error")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
+	middleware.AuditChange(c, models.AuditChange{
+		Entity: tableName,
+		Before: before,
+		After:  gin.H{"rows_affected": rowsAffected, "data": req.Data, "filters": req.Filters},
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Data updated successfully",
@@ -144,7 +192,7 @@ func (h *DatabaseHandler) UpdateData(c *gin.Context) {
 // @Success      200 {object} map[string]interface{} "Данные успешно удалены"
 // @Failure      400 {object} map[string]string "Ошибка в запросе (например, удаление без фильтров)"
 // @Failure      401 {object} map[string]string "Отсутствует ключ API"
-// @Failure      403 {object} map[string]string "Неверный ключ API"
+// @Failure      403 {object} map[string]string "Неверный ключ API или таблица/операция закрыта политикой"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Security     ApiKeyAuth
 // @Router       /api/database/{table}/delete [delete]
@@ -158,15 +206,65 @@ func (h *DatabaseHandler) DeleteData(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.AuditRows(tableName, req.Filters)
+	if snapshotFailed(c, err) {
+		return
+	}
 	rowsAffected, err := h.service.DeleteData(tableName, req)
 	if err != nil {
 		logger.Default().WithError(err).Error("Database handler (DeleteData): service returned an error")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
+	middleware.AuditChange(c, models.AuditChange{
+		Entity: tableName,
+		Before: before,
+		After:  gin.H{"rows_affected": rowsAffected, "filters": req.Filters},
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Data deleted successfully",
 		"rows_affected": rowsAffected,
 	})
 }
+
+// ListTables возвращает список таблиц, доступных текущему ключу.
+// @Summary      Список доступных таблиц
+// @Description  Возвращает таблицы, открытые для внешнего API, и операции, разрешенные текущему ключу.
+// @Tags         database
+// @Produce      json
+// @Success      200 {array} models.TableSummary "Список таблиц"
+// @Failure      401 {object} map[string]string "Отсутствует ключ API"
+// @Failure      403 {object} map[string]string "Неверный ключ API"
+// @Security     ApiKeyAuth
+// @Router       /api/database/tables [get]
+func (h *DatabaseHandler) ListTables(c *gin.Context) {
+	key, _ := middleware.APIKeyFromContext(c)
+	c.JSON(http.StatusOK, h.service.ListTables(key))
+}
+
+// GetTableSchema возвращает описание столбцов таблицы.
+// @Summary      Схема таблицы
+// @Description  Возвращает столбцы таблицы с типом, допустимостью NULL, значением по умолчанию, первичным и внешними ключами.
+// @Tags         database
+// @Produce      json
+// @Param        table path string true "Имя таблицы (e.g., tickets, doctors)"
+// @Success      200 {object} models.TableSchema "Схема таблицы"
+// @Failure      401 {object} map[string]string "Отсутствует ключ API"
+// @Failure      403 {object} map[string]string "Неверный ключ API или таблица закрыта политикой"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/database/tables/{table} [get]
+func (h *DatabaseHandler) GetTableSchema(c *gin.Context) {
+	tableName := c.Param("table")
+	key, _ := middleware.APIKeyFromContext(c)
+
+	schema, err := h.service.GetTableSchema(tableName, key)
+	if err != nil {
+		logger.Default().WithError(err).Error("Database handler (GetTableSchema): service returned an error")
+		c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
@@ -5,9 +5,12 @@ import (
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/pubsub"
 	"ElectronicQueue/internal/services"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,34 +35,24 @@ type StartAppointmentRequest struct {
 	TicketID uint `json:"ticket_id" binding:"required" example:"1"`
 }
 
-// CompleteAppointmentRequest описывает запрос на завершение приема
-// swagger:model CompleteAppointmentRequest
-type CompleteAppointmentRequest struct {
+// CabinetInviteRequest описывает запрос на приглашение пациента в кабинет
+// swagger:model CabinetInviteRequest
+type CabinetInviteRequest struct {
 	TicketID uint `json:"ticket_id" binding:"required" example:"1"`
 }
 
-// StartBreakRequest описывает запрос на начало перерыва
-// swagger:model StartBreakRequest
-type StartBreakRequest struct {
-	DoctorID uint `json:"doctor_id" binding:"required" example:"1"`
-}
-
-// EndBreakRequest описывает запрос на завершение перерыва
-// swagger:model EndBreakRequest
-type EndBreakRequest struct {
-	DoctorID uint `json:"doctor_id" binding:"required" example:"1"`
-}
-
-// SetActiveRequest описывает запрос на установку статуса активный
-// swagger:model SetActiveRequest
-type SetActiveRequest struct {
-	DoctorID uint `json:"doctor_id" binding:"required" example:"1"`
+// CompleteAppointmentRequest описывает запрос на завершение приема и его итог
+// swagger:model CompleteAppointmentRequest
+type CompleteAppointmentRequest struct {
+	TicketID uint `json:"ticket_id" binding:"required" example:"1"`
+	models.UpdateAppointmentRequest
 }
 
-// SetInactiveRequest описывает запрос на установку статуса неактивный
-// swagger:model SetInactiveRequest
-type SetInactiveRequest struct {
-	DoctorID uint `json:"doctor_id" binding:"required" example:"1"`
+// DoctorStatusRequest описывает запрос на смену статуса врача (перерыв, вход, выход).
+// Врач определяется по JWT; doctor_id необязателен и, если указан, должен совпадать с врачом из токена.
+// swagger:model DoctorStatusRequest
+type DoctorStatusRequest struct {
+	DoctorID uint `json:"doctor_id,omitempty" example:"1"`
 }
 
 // DoctorScreenResponse определяет структуру данных для экрана у кабинета врача.
@@ -68,6 +61,7 @@ type DoctorScreenResponse struct {
 	DoctorName      string                             `json:"doctor_name,omitempty"`
 	DoctorSpecialty string                             `json:"doctor_specialty,omitempty"`
 	CabinetNumber   int                                `json:"cabinet_number"`
+	Cabinet         *models.Cabinet                    `json:"cabinet,omitempty"`
 	Queue           []models.DoctorQueueTicketResponse `json:"queue,omitempty"`
 	Message         string                             `json:"message,omitempty"`
 }
@@ -83,7 +77,8 @@ type DoctorScreenResponse struct {
 func (h *DoctorHandler) GetAllActiveDoctors(c *gin.Context) {
 	doctors, err := h.doctorService.GetAllActiveDoctors()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список врачей: " + err.Error()})
+		logger.Default().WithError(err).Error("GetAllActiveDoctors: failed to get doctors")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список врачей"})
 		return
 	}
 	c.JSON(http.StatusOK, doctors)
@@ -91,7 +86,7 @@ func (h *DoctorHandler) GetAllActiveDoctors(c *gin.Context) {
 
 // GetActiveCabinets godoc
 // @Summary      Получить список всех существующих кабинетов
-// @Description  Возвращает список всех уникальных номеров кабинетов, когда-либо существовавших в расписании.
+// @Description  Возвращает номера всех действующих кабинетов из справочника, в том числе без приема на сегодня.
 // @Tags         doctor
 // @Produce      json
 // @Success      200 {array} integer "Массив номеров кабинетов"
@@ -100,7 +95,8 @@ func (h *DoctorHandler) GetAllActiveDoctors(c *gin.Context) {
 func (h *DoctorHandler) GetActiveCabinets(c *gin.Context) {
 	cabinets, err := h.doctorService.GetAllUniqueCabinets()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список кабинетов: " + err.Error()})
+		logger.Default().WithError(err).Error("GetActiveCabinets: failed to get cabinets")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список кабинетов"})
 		return
 	}
 	c.JSON(http.StatusOK, cabinets)
@@ -115,23 +111,16 @@ func (h *DoctorHandler) GetActiveCabinets(c *gin.Context) {
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router       /api/doctor/tickets/registered [get]
 func (h *DoctorHandler) GetRegisteredTickets(c *gin.Context) {
-	// Получаем ID врача из JWT токена
-	doctorID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "ID врача не найден в токене"})
-		return
-	}
-
-	doctorIDUint, ok := doctorID.(uint)
+	doctorID, ok := doctorIDFromToken(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Неверный формат ID врача"})
 		return
 	}
 
 	// Получить только талоны этого врача
-	tickets, err := h.doctorService.GetRegisteredTicketsForDoctor(doctorIDUint)
+	tickets, err := h.doctorService.GetRegisteredTicketsForDoctor(doctorID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Default().WithError(err).WithField("doctor_id", doctorID).Error("GetRegisteredTickets: failed to get tickets")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список талонов"})
 		return
 	}
 	c.JSON(http.StatusOK, tickets)
@@ -146,49 +135,150 @@ func (h *DoctorHandler) GetRegisteredTickets(c *gin.Context) {
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router       /api/doctor/tickets/in-progress [get]
 func (h *DoctorHandler) GetInProgressTickets(c *gin.Context) {
-	// ID врача из JWT токена
-	doctorID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "ID врача не найден в токене"})
+	doctorID, ok := doctorIDFromToken(c)
+	if !ok {
 		return
 	}
 
-	doctorIDUint, ok := doctorID.(uint)
+	// Получить только талоны этого врача
+	tickets, err := h.doctorService.GetInProgressTicketsForDoctor(doctorID)
+	if err != nil {
+		logger.Default().WithError(err).WithField("doctor_id", doctorID).Error("GetInProgressTickets: failed to get tickets")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список талонов"})
+		return
+	}
+	c.JSON(http.StatusOK, tickets)
+}
+
+// GetInvitedTickets возвращает талоны со статусом "приглашен_в_кабинет"
+// @Summary      Получить приглашенных в кабинет пациентов
+// @Description  Возвращает талоны пациентов, которых врач пригласил в кабинет, но прием еще не начат.
+// @Tags         doctor
+// @Produce      json
+// @Success      200 {object} []models.TicketResponse "Список талонов"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/doctor/tickets/invited [get]
+func (h *DoctorHandler) GetInvitedTickets(c *gin.Context) {
+	doctorID, ok := doctorIDFromToken(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Неверный формат ID врача"})
 		return
 	}
 
-	// Получить только талоны этого врача
-	tickets, err := h.doctorService.GetInProgressTicketsForDoctor(doctorIDUint)
+	tickets, err := h.doctorService.GetInvitedTicketsForDoctor(doctorID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Default().WithError(err).WithField("doctor_id", doctorID).Error("GetInvitedTickets: failed to get tickets")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список талонов"})
 		return
 	}
 	c.JSON(http.StatusOK, tickets)
 }
 
+// InviteToCabinet обрабатывает запрос на приглашение пациента в кабинет
+// @Summary      Пригласить пациента в кабинет
+// @Description  Переводит талон из статуса 'зарегистрирован' в 'приглашен_в_кабинет' и отправляет на табло кабинета событие cabinet_invite со ссылкой на озвучку "пройдите в кабинет" (audio_url не передается, если на сервере нет записей для озвучки номера кабинета). Пациент должен быть записан к текущему врачу.
+// @Tags         doctor
+// @Accept       json
+// @Produce      json
+// @Param        request body CabinetInviteRequest true "Талон пациента"
+// @Success      200 {object} map[string]interface{} "Пациент приглашен"
+// @Failure      400 {object} map[string]string "Неверный запрос или номер кабинета вне диапазона озвучки (1-999)"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Пациент записан к другому врачу"
+// @Failure      404 {object} map[string]string "Талон или запись не найдены"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/doctor/invite [post]
+func (h *DoctorHandler) InviteToCabinet(c *gin.Context) {
+	h.callToCabinet(c, false)
+}
+
+// RecallToCabinet обрабатывает запрос на повторный вызов пациента в кабинет
+// @Summary      Повторно вызвать пациента в кабинет
+// @Description  Повторяет приглашение пациента, талон которого уже в статусе 'приглашен_в_кабинет': табло кабинета снова получает событие cabinet_invite с признаком recall.
+// @Tags         doctor
+// @Accept       json
+// @Produce      json
+// @Param        request body CabinetInviteRequest true "Талон пациента"
+// @Success      200 {object} map[string]interface{} "Пациент вызван повторно"
+// @Failure      400 {object} map[string]string "Неверный запрос или номер кабинета вне диапазона озвучки (1-999)"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Пациент записан к другому врачу"
+// @Failure      404 {object} map[string]string "Талон или запись не найдены"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/doctor/recall [post]
+func (h *DoctorHandler) RecallToCabinet(c *gin.Context) {
+	h.callToCabinet(c, true)
+}
+
+// callToCabinet - общая часть приглашения и повторного вызова пациента.
+func (h *DoctorHandler) callToCabinet(c *gin.Context, recall bool) {
+	doctorID, ok := doctorIDFromToken(c)
+	if !ok {
+		return
+	}
+
+	var req CabinetInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ticket_id обязателен"})
+		return
+	}
+
+	var ticket *models.Ticket
+	var err error
+	if recall {
+		ticket, err = h.doctorService.RecallToCabinet(doctorID, req.TicketID)
+	} else {
+		ticket, err = h.doctorService.InviteToCabinet(doctorID, req.TicketID)
+	}
+	if err != nil {
+		logger.Default().WithError(err).WithField("doctor_id", doctorID).WithField("ticket_id", req.TicketID).Warn("callToCabinet: Failed to invite patient")
+		c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
+		return
+	}
+
+	message := "Пациент приглашен в кабинет"
+	if recall {
+		message = "Пациент повторно вызван в кабинет"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"ticket":  ticket.ToResponse(),
+	})
+}
+
 // StartAppointment обрабатывает запрос на начало приема пациента
 // @Summary      Начать прием пациента
-// @Description  Начинает прием пациента по талону. Статус талона должен быть 'зарегистрирован'.
+// @Description  Начинает прием пациента по талону. Статус талона должен быть 'зарегистрирован' или 'приглашен_в_кабинет', пациент должен быть записан к текущему врачу.
 // @Tags         doctor
 // @Accept       json
 // @Produce      json
 // @Param        request body StartAppointmentRequest true "Данные для начала приема"
 // @Success      200 {object} map[string]interface{} "Appointment started successfully"
 // @Failure      400 {object} map[string]string "Неверный запрос или статус талона"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Пациент записан к другому врачу"
+// @Failure      404 {object} map[string]string "Талон не найден"
 // @Security     ApiKeyAuth
 // @Router       /api/doctor/start-appointment [post]
 func (h *DoctorHandler) StartAppointment(c *gin.Context) {
+	doctorID, ok := doctorIDFromToken(c)
+	if !ok {
+		return
+	}
+
 	var req StartAppointmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ticket_id is required"})
 		return
 	}
 
-	ticket, err := h.doctorService.StartAppointment(req.TicketID)
+	ticket, err := h.doctorService.StartAppointment(doctorID, req.TicketID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger.Default().WithError(err).WithField("doctor_id", doctorID).WithField("ticket_id", req.TicketID).Warn("StartAppointment: Failed to start appointment")
+		c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
@@ -200,167 +290,204 @@ func (h *DoctorHandler) StartAppointment(c *gin.Context) {
 
 // CompleteAppointment обрабатывает запрос на завершение приема пациента
 // @Summary      Завершить прием пациента
-// @Description  Завершает прием пациента по талону. Статус талона должен быть 'на_приеме'.
+// @Description  Завершает прием пациента по талону. Статус талона должен быть 'на_приеме', пациент должен быть записан к текущему врачу. Можно указать итог приема: код диагноза по МКБ-10 (обязателен, если указан итог), заметку, необходимость повторного визита и специальность, к которой пациент направлен (направление сохраняется в карточке пациента).
 // @Tags         doctor
 // @Accept       json
 // @Produce      json
 // @Param        request body CompleteAppointmentRequest true "Данные для завершения приема"
 // @Success      200 {object} map[string]interface{} "Appointment completed successfully"
 // @Failure      400 {object} map[string]string "Неверный запрос или статус талона"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Пациент записан к другому врачу"
+// @Failure      404 {object} map[string]string "Талон не найден"
 // @Security     ApiKeyAuth
 // @Router       /api/doctor/complete-appointment [post]
 func (h *DoctorHandler) CompleteAppointment(c *gin.Context) {
+	doctorID, ok := doctorIDFromToken(c)
+	if !ok {
+		return
+	}
+
 	var req CompleteAppointmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ticket_id is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
 		return
 	}
 
-	ticket, err := h.doctorService.CompleteAppointment(req.TicketID)
+	ticket, appointment, err := h.doctorService.CompleteAppointment(doctorID, req.TicketID, &req.UpdateAppointmentRequest, requestActor(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger.Default().WithError(err).WithField("doctor_id", doctorID).WithField("ticket_id", req.TicketID).Warn("CompleteAppointment: Failed to complete appointment")
+		c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Appointment completed successfully",
-		"ticket":  ticket.ToResponse(),
+		"message":            "Appointment completed successfully",
+		"ticket":             ticket.ToResponse(),
+		"appointment_id":     appointment.ID,
+		"diagnosis_code":     appointment.DiagnosisCode,
+		"follow_up_needed":   appointment.FollowUpNeeded,
+		"issued_referral_id": appointment.IssuedReferralID,
 	})
 }
 
 // StartBreak обрабатывает запрос на начало перерыва врача
 // @Summary      Начать перерыв врача
-// @Description  Начинает перерыв врача. Статус врача должен быть 'активен'.
+// @Description  Начинает перерыв врача. Статус врача должен быть 'активен'. Врач определяется по JWT.
 // @Tags         doctor
 // @Accept       json
 // @Produce      json
-// @Param        request body StartBreakRequest true "Данные для начала перерыва"
-// @Success      200 {object} map[string]string "Break started successfully"
+// @Param        request body DoctorStatusRequest false "doctor_id необязателен и должен совпадать с врачом из токена"
+// @Success      200 {object} map[string]string "Перерыв начат успешно"
 // @Failure      400 {object} map[string]string "Неверный запрос или статус врача"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Попытка изменить статус другого врача"
 // @Security     ApiKeyAuth
 // @Router       /api/doctor/start-break [post]
 func (h *DoctorHandler) StartBreak(c *gin.Context) {
 	log := logger.Default().WithField("handler", "StartBreak")
 
-	var req StartBreakRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.WithError(err).Error("Неверный формат запроса")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "doctor_id обязателен"})
+	doctorID, ok := doctorFromStatusRequest(c)
+	if !ok {
 		return
 	}
 
-	log.WithField("doctor_id", req.DoctorID).Info("Начало перерыва для врача")
+	log.WithField("doctor_id", doctorID).Info("Начало перерыва для врача")
 
-	if err := h.doctorService.StartBreak(req.DoctorID); err != nil {
-		log.WithError(err).WithField("doctor_id", req.DoctorID).Error("Ошибка начала перерыва")
+	if err := h.doctorService.StartBreak(doctorID); err != nil {
+		log.WithError(err).WithField("doctor_id", doctorID).Error("Ошибка начала перерыва")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	log.WithField("doctor_id", req.DoctorID).Info("Перерыв начат успешно")
+	log.WithField("doctor_id", doctorID).Info("Перерыв начат успешно")
 	c.JSON(http.StatusOK, gin.H{"message": "Перерыв начат успешно"})
 }
 
 // EndBreak обрабатывает запрос на завершение перерыва врача
 // @Summary      Завершить перерыв врача
-// @Description  Завершает перерыв врача. Статус врача должен быть 'перерыв'.
+// @Description  Завершает перерыв врача. Статус врача должен быть 'перерыв'. Врач определяется по JWT.
 // @Tags         doctor
 // @Accept       json
 // @Produce      json
-// @Param        request body EndBreakRequest true "Данные для завершения перерыва"
-// @Success      200 {object} map[string]string "Break ended successfully"
+// @Param        request body DoctorStatusRequest false "doctor_id необязателен и должен совпадать с врачом из токена"
+// @Success      200 {object} map[string]string "Перерыв завершен успешно"
 // @Failure      400 {object} map[string]string "Неверный запрос или статус врача"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Попытка изменить статус другого врача"
 // @Security     ApiKeyAuth
 // @Router       /api/doctor/end-break [post]
 func (h *DoctorHandler) EndBreak(c *gin.Context) {
 	log := logger.Default().WithField("handler", "EndBreak")
 
-	var req EndBreakRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.WithError(err).Error("Неверный формат запроса")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "doctor_id обязателен"})
+	doctorID, ok := doctorFromStatusRequest(c)
+	if !ok {
 		return
 	}
 
-	log.WithField("doctor_id", req.DoctorID).Info("Завершение перерыва для врача")
+	log.WithField("doctor_id", doctorID).Info("Завершение перерыва для врача")
 
-	if err := h.doctorService.EndBreak(req.DoctorID); err != nil {
-		log.WithError(err).WithField("doctor_id", req.DoctorID).Error("Ошибка завершения перерыва")
+	if err := h.doctorService.EndBreak(doctorID); err != nil {
+		log.WithError(err).WithField("doctor_id", doctorID).Error("Ошибка завершения перерыва")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	log.WithField("doctor_id", req.DoctorID).Info("Перерыв завершен успешно")
+	log.WithField("doctor_id", doctorID).Info("Перерыв завершен успешно")
 	c.JSON(http.StatusOK, gin.H{"message": "Перерыв завершен успешно"})
 }
 
 // SetDoctorActive обрабатывает запрос на установку статуса врача как активный
 // @Summary      Установить статус врача как активный
-// @Description  Устанавливает статус врача как активный (при входе в систему).
+// @Description  Устанавливает статус врача как активный (при входе в систему). Врач определяется по JWT.
 // @Tags         doctor
 // @Accept       json
 // @Produce      json
-// @Param        request body SetActiveRequest true "Данные для установки статуса"
-// @Success      200 {object} map[string]string "Doctor status set to active"
-// @Failure      400 {object} map[string]string "Неверный запрос"
+// @Param        request body DoctorStatusRequest false "doctor_id необязателен и должен совпадать с врачом из токена"
+// @Success      200 {object} map[string]string "Статус активен установлен успешно"
+// @Failure      400 {object} map[string]string "Неверный запрос или статус врача"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Попытка изменить статус другого врача"
+// @Security     ApiKeyAuth
 // @Router       /api/doctor/set-active [post]
 func (h *DoctorHandler) SetDoctorActive(c *gin.Context) {
 	log := logger.Default().WithField("handler", "SetDoctorActive")
 
-	var req SetActiveRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.WithError(err).Error("Неверный формат запроса")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "doctor_id обязателен"})
+	doctorID, ok := doctorFromStatusRequest(c)
+	if !ok {
 		return
 	}
 
-	log.WithField("doctor_id", req.DoctorID).Info("Установка статуса активен для врача")
+	log.WithField("doctor_id", doctorID).Info("Установка статуса активен для врача")
 
-	if err := h.doctorService.SetDoctorActive(req.DoctorID); err != nil {
-		log.WithError(err).WithField("doctor_id", req.DoctorID).Error("Ошибка установки статуса активен")
+	if err := h.doctorService.SetDoctorActive(doctorID); err != nil {
+		log.WithError(err).WithField("doctor_id", doctorID).Error("Ошибка установки статуса активен")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	log.WithField("doctor_id", req.DoctorID).Info("Статус активен установлен успешно")
+	log.WithField("doctor_id", doctorID).Info("Статус активен установлен успешно")
 	c.JSON(http.StatusOK, gin.H{"message": "Статус активен установлен успешно"})
 }
 
 // SetDoctorInactive обрабатывает запрос на установку статуса врача как неактивный
 // @Summary      Установить статус врача как неактивный
-// @Description  Устанавливает статус врача как неактивный (при выходе из системы).
+// @Description  Устанавливает статус врача как неактивный (при выходе из системы). Врач определяется по JWT.
 // @Tags         doctor
 // @Accept       json
 // @Produce      json
-// @Param        request body SetInactiveRequest true "Данные для установки статуса"
-// @Success      200 {object} map[string]string "Doctor status set to inactive"
-// @Failure      400 {object} map[string]string "Неверный запрос"
+// @Param        request body DoctorStatusRequest false "doctor_id необязателен и должен совпадать с врачом из токена"
+// @Success      200 {object} map[string]string "Статус неактивен установлен успешно"
+// @Failure      400 {object} map[string]string "Неверный запрос или статус врача"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Попытка изменить статус другого врача"
+// @Security     ApiKeyAuth
 // @Router       /api/doctor/set-inactive [post]
 func (h *DoctorHandler) SetDoctorInactive(c *gin.Context) {
 	log := logger.Default().WithField("handler", "SetDoctorInactive")
 
-	var req SetInactiveRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.WithError(err).Error("Неверный формат запроса")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "doctor_id обязателен"})
+	doctorID, ok := doctorFromStatusRequest(c)
+	if !ok {
 		return
 	}
 
-	log.WithField("doctor_id", req.DoctorID).Info("Установка статуса неактивен для врача")
+	log.WithField("doctor_id", doctorID).Info("Установка статуса неактивен для врача")
 
-	if err := h.doctorService.SetDoctorInactive(req.DoctorID); err != nil {
-		log.WithError(err).WithField("doctor_id", req.DoctorID).Error("Ошибка установки статуса неактивен")
+	if err := h.doctorService.SetDoctorInactive(doctorID); err != nil {
+		log.WithError(err).WithField("doctor_id", doctorID).Error("Ошибка установки статуса неактивен")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	log.WithField("doctor_id", req.DoctorID).Info("Статус неактивен установлен успешно")
+	log.WithField("doctor_id", doctorID).Info("Статус неактивен установлен успешно")
 	c.JSON(http.StatusOK, gin.H{"message": "Статус неактивен установлен успешно"})
 }
 
+// doctorFromStatusRequest возвращает ID врача из JWT для запросов смены статуса.
+// Тело запроса может быть пустым; doctor_id другого врача отклоняется. При ошибке ответ уже отправлен клиенту.
+func doctorFromStatusRequest(c *gin.Context) (uint, bool) {
+	doctorID, ok := doctorIDFromToken(c)
+	if !ok {
+		return 0, false
+	}
+
+	var req DoctorStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return 0, false
+	}
+	if req.DoctorID != 0 && req.DoctorID != doctorID {
+		logger.Default().WithField("doctor_id", doctorID).WithField("target_doctor_id", req.DoctorID).Warn("Попытка изменить статус другого врача")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Нельзя изменить статус другого врача"})
+		return 0, false
+	}
+	return doctorID, true
+}
+
 // DoctorScreenUpdates - SSE эндпоинт для табло у кабинета врача.
 // @Summary      Получить обновления для табло врача
 // @Description  Отправляет начальное состояние и последующие обновления статуса приема через Server-Sent Events для конкретного кабинета.
+// @Description  Когда врач приглашает пациента в кабинет, дополнительно отправляется событие cabinet_invite (models.CabinetInviteNotification) со ссылкой на озвучку приглашения.
 // @Tags         doctor
 // @Produce      text/event-stream
 // @Param        cabinet_number path int true "Номер кабинета"
@@ -392,6 +519,12 @@ func (h *DoctorHandler) DoctorScreenUpdates(c *gin.Context) {
 			return false
 		}
 
+		cabinet, err := h.doctorService.GetCabinetInfo(cabinetNumber)
+		if err != nil {
+			// Сведения о кабинете не критичны для табло: очередь выводится и без них.
+			log.WithError(err).Warn("Не удалось получить сведения о кабинете")
+		}
+
 		doctorName := ""
 		doctorSpecialty := ""
 		doctorStatus := models.DoctorStatusInactive
@@ -405,6 +538,7 @@ func (h *DoctorHandler) DoctorScreenUpdates(c *gin.Context) {
 			"doctor_name":      doctorName,
 			"doctor_specialty": doctorSpecialty,
 			"cabinet_number":   cabinetNumber,
+			"cabinet":          cabinet,
 			"queue":            queue,
 			"message":          "",
 			"doctor_status":    doctorStatus,
@@ -427,14 +561,32 @@ func (h *DoctorHandler) DoctorScreenUpdates(c *gin.Context) {
 		return
 	}
 
+	inviteMarker := `"event":"` + models.CabinetInviteEvent + `"`
+
 	// Запускаем стрим для отправки обновлений
 	c.Stream(func(w io.Writer) bool {
 		select {
-		case _, ok := <-clientChan:
+		case msg, ok := <-clientChan:
 			if !ok {
 				log.Info("Канал уведомления закрыт для экрана врача.")
 				return false
 			}
+			if strings.Contains(msg, inviteMarker) {
+				// Очередь обновится по уведомлению об изменении талона, здесь передаем только приглашение.
+				var invite models.CabinetInviteNotification
+				if err := json.Unmarshal([]byte(msg), &invite); err != nil {
+					log.WithError(err).Warn("Получено невалидное уведомление о приглашении в кабинет, пропуск.")
+					return true
+				}
+				if invite.Cabinet != cabinetNumber {
+					return true
+				}
+				c.SSEvent(models.CabinetInviteEvent, invite)
+				if f, ok := w.(http.Flusher); ok {
+					f.Flush()
+				}
+				return true
+			}
 			log.Info("Получено уведомление об обновлении талона, обновление состояния экрана врача.")
 			return sendCurrentState()
 
@@ -444,3 +596,18 @@ func (h *DoctorHandler) DoctorScreenUpdates(c *gin.Context) {
 		}
 	})
 }
+
+// doctorIDFromToken возвращает ID врача из JWT. При ошибке ответ уже отправлен клиенту.
+func doctorIDFromToken(c *gin.Context) (uint, bool) {
+	doctorID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "ID врача не найден в токене"})
+		return 0, false
+	}
+	doctorIDUint, ok := doctorID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Неверный формат ID врача"})
+		return 0, false
+	}
+	return doctorIDUint, true
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/services"
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// errorStatus сопоставляет ошибку сервиса с HTTP-статусом по ее виду. Ошибки без вида - внутренние.
+func errorStatus(err error) int {
+	var ruleErr *services.BookingRuleViolationError
+	var conflictErr *services.ScheduleConflictError
+	switch {
+	case errors.Is(err, services.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrConflict) || errors.As(err, &ruleErr) || errors.As(err, &conflictErr):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorMessage возвращает текст ошибки для ответа клиенту. Подробности внутренних ошибок
+// (SQL, сетевые сбои) остаются в логе, а клиент получает общее сообщение.
+func errorMessage(err error) string {
+	if errorStatus(err) == http.StatusInternalServerError {
+		return "Внутренняя ошибка сервера"
+	}
+	return err.Error()
+}
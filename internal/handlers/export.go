@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/export"
+	"ElectronicQueue/internal/logger"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamExport отдает файл выгрузки в формате из query-параметра format (csv | xlsx).
+// Пока write не начал вывод, ошибка возвращается клиенту как JSON; после начала потока
+// ответ прерывается, а ошибка только логируется.
+func streamExport(c *gin.Context, baseName string, write func(w export.Writer) error, errorStatus func(error) int) {
+	format := export.NormalizeFormat(c.DefaultQuery("format", export.FormatCSV))
+
+	writer, err := export.NewWriter(format, c.Writer)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName(baseName, format, time.Now())))
+
+	if err := write(writer); err != nil {
+		logger.Default().WithError(err).WithField("export", baseName).Error("Export failed")
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.JSON(errorStatus(err), gin.H{"error": errorMessage(err)})
+			return
+		}
+		c.Abort()
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		logger.Default().WithError(err).WithField("export", baseName).Error("Export failed on close")
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FollowUpHandler обрабатывает запись пациентов на повторный визит и по направлению из окна врача.
+type FollowUpHandler struct {
+	service *services.FollowUpService
+}
+
+// NewFollowUpHandler создает новый экземпляр FollowUpHandler.
+func NewFollowUpHandler(service *services.FollowUpService) *FollowUpHandler {
+	return &FollowUpHandler{service: service}
+}
+
+// SearchSlots godoc
+// @Summary      Найти свободные слоты для записи с приема
+// @Description  Без specialization возвращает свободные слоты текущего врача, иначе - слоты всех врачей указанной специальности. Период по умолчанию 14 дней с сегодняшнего дня, не более 31 дня.
+// @Tags         doctor
+// @Produce      json
+// @Param        specialization query string false "Специальность врача, по умолчанию собственные слоты врача"
+// @Param        from query string false "Дата начала периода (YYYY-MM-DD), по умолчанию сегодня"
+// @Param        to query string false "Дата окончания периода (YYYY-MM-DD)"
+// @Success      200 {array} models.BookingSlot "Свободные слоты"
+// @Failure      400 {object} map[string]string "Неверные параметры поиска"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/doctor/follow-up/slots [get]
+func (h *FollowUpHandler) SearchSlots(c *gin.Context) {
+	doctorID, ok := doctorIDFromToken(c)
+	if !ok {
+		return
+	}
+
+	from := time.Now()
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты from, используйте YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	var to *time.Time
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты to, используйте YYYY-MM-DD"})
+			return
+		}
+		to = &parsed
+	}
+
+	slots, err := h.service.SearchSlots(doctorID, c.Query("specialization"), from, to)
+	if err != nil {
+		logger.Default().WithError(err).Warn("SearchFollowUpSlots: Failed to search free slots")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, slots)
+}
+
+// Book godoc
+// @Summary      Записать пациента с приема
+// @Description  Записывает пациента, который на приеме у врача или был у него сегодня, на выбранный слот. Запись к врачу той же специальности получает вид follow_up, к другой - referral. В записи сохраняются врач, оформивший ее, и исходный прием.
+// @Description  Правила записи проверяются как в регистратуре; при нарушениях возвращается 409 со списком violations. Чтобы записать пациента вопреки правилам, повторите запрос с override_reason.
+// @Tags         doctor
+// @Accept       json
+// @Produce      json
+// @Param        request body models.DoctorBookingRequest true "Талон текущего пациента и слот"
+// @Success      201 {object} models.Appointment "Созданная запись"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      401 {object} map[string]string "ID врача не найден в токене"
+// @Failure      403 {object} map[string]string "Пациент записан к другому врачу"
+// @Failure      404 {object} map[string]string "Талон, запись или слот не найдены"
+// @Failure      409 {object} map[string]interface{} "Слот занят или недоступен, пациент не на приеме или нарушены правила записи (поле violations)"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/doctor/follow-up/appointments [post]
+func (h *FollowUpHandler) Book(c *gin.Context) {
+	log := logger.Default()
+
+	doctorID, ok := doctorIDFromToken(c)
+	if !ok {
+		return
+	}
+
+	var req models.DoctorBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	appointment, err := h.service.Book(doctorID, &req, requestActor(c))
+	if err != nil {
+		var ruleErr *services.BookingRuleViolationError
+		if errors.As(err, &ruleErr) {
+			log.WithError(err).Warn("BookFollowUp: Booking rules violated")
+			c.JSON(http.StatusConflict, gin.H{"error": ruleErr.Error(), "violations": ruleErr.Violations})
+			return
+		}
+		log.WithError(err).WithField("doctor_id", doctorID).Warn("BookFollowUp: Failed to create appointment")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, appointment)
+}
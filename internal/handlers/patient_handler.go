@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/services"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,45 +21,58 @@ func NewPatientHandler(service *services.PatientService) *PatientHandler {
 }
 
 // SearchPatients godoc
-// @Summary      Поиск пациентов по ФИО, ОМС или паспорту
-// @Description  Ищет пациентов по частичному совпадению в ФИО, номере полиса ОМС или полному номеру паспорта (серия + номер без пробелов). Возвращает до 10 совпадений.
+// @Summary      Поиск пациентов по ФИО, документам и телефону
+// @Description  Ранжированный поиск пациентов с пагинацией. Запрос из цифр ищется по точному номеру полиса ОМС или паспорта (10 цифр: серия и номер) и по части телефона; запрос вида "Фамилия И.О." - по фамилии с учетом опечаток и инициалам; остальные запросы - по ФИО с учетом опечаток. Фильтры по дате рождения и телефону можно использовать без запроса. Удаленные карточки не возвращаются.
 // @Tags         registrar
 // @Produce      json
-// @Param        query query string true "Строка для поиска (минимум 2 символа)"
-// @Success      200 {array} models.Patient "Массив найденных пациентов"
-// @Failure      400 {object} map[string]string "Ошибка: отсутствует или слишком короткий параметр поиска"
+// @Param        query query string false "Строка для поиска (минимум 2 символа)"
+// @Param        birth_date query string false "Дата рождения (YYYY-MM-DD)"
+// @Param        phone query string false "Телефон или его часть"
+// @Param        page query int false "Номер страницы, начиная с 1" default(1)
+// @Param        limit query int false "Размер страницы (не более 100)" default(20)
+// @Success      200 {object} models.PatientSearchResult "Страница найденных пациентов"
+// @Failure      400 {object} map[string]string "Ошибка: не задан ни запрос, ни фильтры, или неверный формат параметров"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Security     ApiKeyAuth
 // @Router       /api/registrar/patients/search [get]
 func (h *PatientHandler) SearchPatients(c *gin.Context) {
 	log := logger.Default()
 	query := c.Query("query")
-	if query == "" {
-		log.Warn("SearchPatients: Query parameter 'query' is missing")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'query' для поиска обязателен"})
+	phone := c.Query("phone")
+
+	var birthDate *time.Time
+	if raw := c.Query("birth_date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты birth_date, используйте YYYY-MM-DD"})
+			return
+		}
+		birthDate = &parsed
+	}
+	if query == "" && phone == "" && birthDate == nil {
+		log.Warn("SearchPatients: neither query nor filters are set")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите строку поиска 'query' или фильтры 'birth_date', 'phone'"})
 		return
 	}
 
-	// Добавим проверку на минимальную длину запроса для снижения нагрузки на БД
-	if len(query) < 2 {
-		c.JSON(http.StatusOK, []models.Patient{})
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат параметра page"})
 		return
 	}
-
-	patients, err := h.service.SearchPatients(query)
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
 	if err != nil {
-		log.WithError(err).Error("SearchPatients: Failed to search patients in service")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось выполнить поиск пациентов"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат параметра limit"})
 		return
 	}
 
-	// Всегда возвращаем JSON-массив, даже если он пустой, это лучшая практика для API.
-	if patients == nil {
-		c.JSON(http.StatusOK, []models.Patient{})
+	result, err := h.service.SearchPatients(query, birthDate, phone, page, limit)
+	if err != nil {
+		log.WithError(err).Error("SearchPatients: Failed to search patients in service")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось выполнить поиск пациентов"})
 		return
 	}
-
-	c.JSON(http.StatusOK, patients)
+	c.JSON(http.StatusOK, result)
 }
 
 // CreatePatient godoc
@@ -90,3 +106,301 @@ func (h *PatientHandler) CreatePatient(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, patient)
 }
+
+// GetPatient godoc
+// @Summary      Получить карточку пациента
+// @Tags         registrar
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Success      200 {object} models.Patient "Карточка пациента"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Пациент не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id} [get]
+func (h *PatientHandler) GetPatient(c *gin.Context) {
+	id, ok := parsePatientID(c)
+	if !ok {
+		return
+	}
+
+	patient, err := h.service.GetPatient(id)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, patient)
+}
+
+// UpdatePatient godoc
+// @Summary      Изменить карточку пациента
+// @Description  Изменяет переданные поля карточки пациента (исправление опечаток в ФИО, телефоне, документах). Незаполненные поля не изменяются.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Param        patient body models.UpdatePatientRequest true "Изменяемые поля"
+// @Success      200 {object} models.Patient "Обновленная карточка"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент не найден"
+// @Failure      409 {object} map[string]string "Пациент с таким паспортом уже существует"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id} [patch]
+func (h *PatientHandler) UpdatePatient(c *gin.Context) {
+	id, ok := parsePatientID(c)
+	if !ok {
+		return
+	}
+	var req models.UpdatePatientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetPatient(id)
+	if snapshotFailed(c, err) {
+		return
+	}
+	patient, err := h.service.UpdatePatient(id, &req, requestActor(c))
+	if err != nil {
+		logger.Default().WithError(err).Error("UpdatePatient: Failed to update patient")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "patients", EntityID: c.Param("patient_id"), Before: before, After: patient})
+	c.JSON(http.StatusOK, patient)
+}
+
+// GetDuplicates godoc
+// @Summary      Найти возможные дубликаты пациента
+// @Description  Возвращает действующие карточки с тем же полисом ОМС или с той же датой рождения и похожим ФИО (до двух опечаток) с указанием причин совпадения.
+// @Tags         registrar
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Success      200 {array} models.PatientDuplicate "Возможные дубликаты"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Пациент не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id}/duplicates [get]
+func (h *PatientHandler) GetDuplicates(c *gin.Context) {
+	id, ok := parsePatientID(c)
+	if !ok {
+		return
+	}
+
+	duplicates, err := h.service.FindDuplicates(id)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetDuplicates: Failed to find duplicates")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, duplicates)
+}
+
+// MergePatients godoc
+// @Summary      Объединить дубликат с карточкой пациента
+// @Description  Переносит записи на прием, заявки листа ожидания и направления дубликата (source_patient_id) в карточку из пути и помечает дубликат удаленным. Операция выполняется в одной транзакции, копия дубликата сохраняется в журнале объединений.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path int true "ID основной карточки"
+// @Param        request body models.MergePatientsRequest true "Дубликат и причина объединения"
+// @Success      200 {object} models.PatientMerge "Запись журнала объединения"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id}/merge [post]
+func (h *PatientHandler) MergePatients(c *gin.Context) {
+	id, ok := parsePatientID(c)
+	if !ok {
+		return
+	}
+	var req models.MergePatientsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetPatient(id)
+	if snapshotFailed(c, err) {
+		return
+	}
+	source, err := h.service.GetPatient(req.SourcePatientID)
+	if snapshotFailed(c, err) {
+		return
+	}
+	merge, err := h.service.MergePatients(id, &req, requestActor(c))
+	if err != nil {
+		logger.Default().WithError(err).Error("MergePatients: Failed to merge patients")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	after, err := h.service.GetPatient(id)
+	logSnapshotError(c, err)
+	middleware.AuditChange(c, models.AuditChange{
+		Entity:   "patients",
+		EntityID: c.Param("patient_id"),
+		Before:   gin.H{"patient": before, "source_patient": source},
+		After:    gin.H{"patient": after, "merge": merge},
+	})
+	c.JSON(http.StatusOK, merge)
+}
+
+// GetMerges godoc
+// @Summary      Получить журнал объединений пациента
+// @Description  Возвращает объединения, в которых карточка была основной или дубликатом.
+// @Tags         registrar
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Success      200 {array} models.PatientMerge "Журнал объединений"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id}/merges [get]
+func (h *PatientHandler) GetMerges(c *gin.Context) {
+	id, ok := parsePatientID(c)
+	if !ok {
+		return
+	}
+
+	merges, err := h.service.GetMerges(id)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetMerges: Failed to get patient merges")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить журнал объединений"})
+		return
+	}
+	c.JSON(http.StatusOK, merges)
+}
+
+// DeletePatient godoc
+// @Summary      Удалить карточку пациента
+// @Description  Помечает карточку удаленной с указанием причины и автора; история записей сохраняется. Карточку с предстоящими записями или активными заявками листа ожидания удалить нельзя.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Param        request body models.DeletePatientRequest true "Причина удаления"
+// @Success      200 {object} map[string]string "Карточка удалена"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент не найден"
+// @Failure      409 {object} map[string]string "У пациента есть предстоящие записи или заявки"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id} [delete]
+func (h *PatientHandler) DeletePatient(c *gin.Context) {
+	id, ok := parsePatientID(c)
+	if !ok {
+		return
+	}
+	var req models.DeletePatientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetPatient(id)
+	if snapshotFailed(c, err) {
+		return
+	}
+	if err := h.service.DeletePatient(id, req.Reason, requestActor(c)); err != nil {
+		logger.Default().WithError(err).Error("DeletePatient: Failed to delete patient")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if before != nil {
+		middleware.AuditChange(c, models.AuditChange{Entity: "patients", EntityID: c.Param("patient_id"), Before: before})
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Карточка пациента удалена"})
+}
+
+// RevealIdentifiers godoc
+// @Summary      Раскрыть документы пациента
+// @Description  Возвращает полные паспортные данные и полис ОМС пациента (в остальных ответах они маскированы). Каждое раскрытие записывается в журнал доступа с указанием сотрудника и причины.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Param        request body models.RevealPatientIdentifiersRequest true "Причина просмотра"
+// @Success      200 {object} models.PatientIdentifiers "Документы пациента"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/patients/{patient_id}/identifiers [post]
+func (h *PatientHandler) RevealIdentifiers(c *gin.Context) {
+	id, ok := parsePatientID(c)
+	if !ok {
+		return
+	}
+	var req models.RevealPatientIdentifiersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	identifiers, err := h.service.RevealIdentifiers(id, requestActor(c), req.Reason, c.ClientIP())
+	if err != nil {
+		logger.Default().WithError(err).Error("RevealIdentifiers: Failed to reveal patient identifiers")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, identifiers)
+}
+
+// GetIdentifierAccess godoc
+// @Summary      Журнал раскрытия документов пациента (Админ)
+// @Description  Возвращает, кто, когда и по какой причине просматривал полные паспортные данные и полис ОМС пациента.
+// @Tags         admin
+// @Produce      json
+// @Param        patient_id path int true "ID пациента"
+// @Success      200 {array} models.PIIAccessLog "Журнал доступа"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/patients/{patient_id}/identifier-access [get]
+func (h *PatientHandler) GetIdentifierAccess(c *gin.Context) {
+	id, ok := parsePatientID(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.service.GetIdentifierAccess(id)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetIdentifierAccess: Failed to get identifier access log")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить журнал доступа"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// ReencryptIdentifiers godoc
+// @Summary      Перешифровать документы пациентов (Админ)
+// @Description  Перешифровывает активным ключом паспорта и полисы ОМС, зашифрованные старыми ключами или хранящиеся в открытом виде. После завершения старые ключи можно удалить из PII_ENCRYPTION_KEYS.
+// @Tags         admin
+// @Produce      json
+// @Success      200 {object} models.PIIReencryptionResult "Итог перешифрования"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/pii/rotate [post]
+func (h *PatientHandler) ReencryptIdentifiers(c *gin.Context) {
+	result, err := h.service.ReencryptIdentifiers()
+	if err != nil {
+		logger.Default().WithError(err).Error("ReencryptIdentifiers: Failed to re-encrypt patient identifiers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// parsePatientID разбирает ID пациента из пути и при ошибке отвечает 400.
+func parsePatientID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("patient_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID пациента"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -2,10 +2,10 @@ package handlers
 
 import (
 	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/services"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -120,12 +120,8 @@ func (h *RegistrarHandler) CallSpecific(c *gin.Context) {
 
 	ticket, err := h.ticketService.CallSpecificTicket(req.TicketID, req.WindowNumber)
 	if err != nil {
-		if strings.Contains(err.Error(), "не найден") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		if strings.Contains(err.Error(), "имеет неверный статус") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if status := errorStatus(err); status != http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": err.Error()})
 			return
 		}
 		logger.Default().WithError(err).Error("CallSpecific: failed to call ticket")
@@ -191,10 +187,12 @@ func (h *RegistrarHandler) UpdateStatus(c *gin.Context) {
 // @Router       /api/admin/tickets/{id} [delete]
 func (h *RegistrarHandler) DeleteTicket(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.ticketService.DeleteTicket(id); err != nil {
+	ticket, err := h.ticketService.DeleteTicket(id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "tickets", EntityID: id, Before: ticket})
 	c.JSON(http.StatusOK, gin.H{"message": "ticket deleted"})
 }
 
@@ -218,3 +216,21 @@ func (h *RegistrarHandler) GetDailyReport(c *gin.Context) {
 
 	c.JSON(http.StatusOK, reportData)
 }
+
+// ExportDailyReport godoc
+// @Summary      Выгрузить отчет по талонам за текущий день
+// @Description  Отдает дневной отчет файлом CSV (UTF-8 с BOM, разделитель ';') или XLSX.
+// @Tags         registrar
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        format query string false "Формат файла (csv | xlsx)" default(csv)
+// @Success      200 {file} file "Файл отчета"
+// @Failure      400 {object} map[string]string "Неподдерживаемый формат"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/reports/daily/export [get]
+func (h *RegistrarHandler) ExportDailyReport(c *gin.Context) {
+	streamExport(c, "daily_report", h.ticketService.ExportDailyReport, func(error) int {
+		return http.StatusInternalServerError
+	})
+}
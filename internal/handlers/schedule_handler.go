@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/middleware"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/pubsub"
 	"ElectronicQueue/internal/services"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -34,6 +36,8 @@ func NewScheduleHandler(service *services.ScheduleService, broker *pubsub.Broker
 // @Failure      400 {object} map[string]string "Ошибка: неверный формат запроса"
 // @Failure      401 {object} map[string]string "Отсутствует ключ API"
 // @Failure      403 {object} map[string]string "Неверный ключ API"
+// @Failure      404 {object} map[string]string "Врач не найден"
+// @Failure      409 {object} map[string]interface{} "Конфликты расписания (поле conflicts)"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Security     ApiKeyAuth
 // @Router       /api/admin/schedules [post]
@@ -49,13 +53,59 @@ func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
 	schedule, err := h.service.CreateSchedule(&req)
 	if err != nil {
 		log.WithError(err).Error("CreateSchedule: Failed to create schedule in service")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeScheduleError(c, err, errorStatus)
 		return
 	}
 
 	c.JSON(http.StatusCreated, schedule)
 }
 
+// UpdateSchedule godoc
+// @Summary      Изменить слот расписания (Админ)
+// @Description  Частично изменяет слот: блокирует или открывает его, меняет кабинет, дату или время. Новое положение проверяется на конфликты и календарь клиники. Занятый слот нельзя освободить, а перенести или сменить кабинет можно только с force=true - затронутая запись возвращается для уведомления пациента.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID слота расписания"
+// @Param        request body models.UpdateScheduleRequest true "Изменяемые поля"
+// @Success      200 {object} models.UpdateScheduleResult "Измененный слот и затронутая запись"
+// @Failure      400 {object} map[string]string "Неверный формат запроса или параметры"
+// @Failure      404 {object} map[string]string "Слот или кабинет не найден"
+// @Failure      409 {object} map[string]interface{} "Конфликт расписания или слот занят записью"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedules/{id} [patch]
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	var req models.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	before, err := h.service.GetSchedule(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
+	result, err := h.service.UpdateSchedule(uint(id), &req)
+	if err != nil {
+		logger.Default().WithError(err).Error("UpdateSchedule: Failed to update schedule")
+		writeScheduleError(c, err, errorStatus)
+		return
+	}
+	change := models.AuditChange{Entity: "schedules", EntityID: c.Param("id"), After: result.Schedule}
+	if before != nil {
+		change.Before = before.ToResponse()
+	}
+	middleware.AuditChange(c, change)
+	c.JSON(http.StatusOK, result)
+}
+
 // DeleteSchedule godoc
 // @Summary      Удалить слот из расписания (Админ)
 // @Description  Удаляет временной слот из расписания по его ID. Требует INTERNAL_API_KEY.
@@ -67,6 +117,7 @@ func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
 // @Failure      401 {object} map[string]string "Отсутствует ключ API"
 // @Failure      403 {object} map[string]string "Неверный ключ API"
 // @Failure      404 {object} map[string]string "Слот не найден"
+// @Failure      409 {object} map[string]string "На слот ссылаются записи пациентов"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Security     ApiKeyAuth
 // @Router       /api/admin/schedules/{id} [delete]
@@ -80,20 +131,132 @@ func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetSchedule(uint(id))
+	if snapshotFailed(c, err) {
+		return
+	}
 	err = h.service.DeleteSchedule(uint(id))
 	if err != nil {
 		log.WithError(err).Error("DeleteSchedule: Failed to delete schedule from service")
-		if err.Error() == "слот расписания с ID "+idStr+" не найден" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	if before != nil {
+		middleware.AuditChange(c, models.AuditChange{Entity: "schedules", EntityID: idStr, Before: before.ToResponse()})
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Слот расписания успешно удален"})
 }
 
+// CancelSchedules godoc
+// @Summary      Отменить прием врача за период (Админ)
+// @Description  Удаляет свободные слоты врача за период (или часть дня), переносит записи к другому врачу той же специализации
+// @Description  (reassign_to_doctor_id или auto_reassign) и возвращает список пациентов, которым нужно позвонить.
+// @Description  С dry_run=true изменения не сохраняются, возвращается только отчет.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CancelSchedulesRequest true "Параметры отмены"
+// @Success      200 {object} models.CancelSchedulesResult "Отчет об отмене"
+// @Failure      400 {object} map[string]string "Неверные параметры"
+// @Failure      404 {object} map[string]string "Врач не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedules/cancel [post]
+func (h *ScheduleHandler) CancelSchedules(c *gin.Context) {
+	var req models.CancelSchedulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	result, err := h.service.CancelSchedules(&req, requestActor(c))
+	if err != nil {
+		logger.Default().WithError(err).Error("CancelSchedules: Failed to cancel schedules")
+		writeScheduleError(c, err, errorStatus)
+		return
+	}
+	if !req.DryRun {
+		middleware.AuditChange(c, models.AuditChange{Entity: "schedules", After: gin.H{"request": req, "result": result}})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CopyWeek godoc
+// @Summary      Скопировать неделю расписания вперед (Админ)
+// @Description  Копирует слоты недели (пн-вс) на одну или несколько следующих недель. Существующие слоты пропускаются.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CopyWeekRequest true "Исходная и целевая недели"
+// @Success      200 {object} models.CopyWeekResult "Итоги копирования"
+// @Failure      400 {object} map[string]string "Неверные параметры"
+// @Failure      409 {object} map[string]interface{} "Конфликты расписания (поле conflicts), если skip_conflicts=false"
+// @Failure      404 {object} map[string]string "Врач не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedules/copy-week [post]
+func (h *ScheduleHandler) CopyWeek(c *gin.Context) {
+	var req models.CopyWeekRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	result, err := h.service.CopyWeek(&req)
+	if err != nil {
+		logger.Default().WithError(err).Error("CopyWeek: Failed to copy schedule week")
+		writeScheduleError(c, err, errorStatus)
+		return
+	}
+	middleware.AuditChange(c, models.AuditChange{Entity: "schedules", After: gin.H{"request": req, "result": result}})
+	c.JSON(http.StatusOK, result)
+}
+
+// ShiftSchedules godoc
+// @Summary      Сдвинуть слоты врача (Админ)
+// @Description  Сдвигает слоты врача за день (или интервал дня) на shift_minutes минут и возвращает пациентов, у которых изменилось время.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.ShiftSchedulesRequest true "Параметры сдвига"
+// @Success      200 {object} models.ShiftSchedulesResult "Итоги сдвига"
+// @Failure      400 {object} map[string]string "Неверные параметры"
+// @Failure      404 {object} map[string]string "Врач не найден"
+// @Failure      409 {object} map[string]interface{} "Конфликты расписания после сдвига (поле conflicts)"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedules/shift [post]
+func (h *ScheduleHandler) ShiftSchedules(c *gin.Context) {
+	var req models.ShiftSchedulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	result, err := h.service.ShiftSchedules(&req)
+	if err != nil {
+		logger.Default().WithError(err).Error("ShiftSchedules: Failed to shift schedules")
+		writeScheduleError(c, err, errorStatus)
+		return
+	}
+	if !req.DryRun {
+		middleware.AuditChange(c, models.AuditChange{Entity: "schedules", After: gin.H{"request": req, "result": result}})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// writeScheduleError отвечает 409 со списком конфликтов для ScheduleConflictError,
+// для остальных ошибок статус определяет status.
+func writeScheduleError(c *gin.Context, err error, status func(error) int) {
+	var conflictErr *services.ScheduleConflictError
+	if errors.As(err, &conflictErr) {
+		c.JSON(http.StatusConflict, gin.H{"error": conflictErr.Error(), "conflicts": conflictErr.Conflicts})
+		return
+	}
+	c.JSON(status(err), gin.H{"error": err.Error()})
+}
+
 // GetTodayScheduleUpdates godoc
 // @Summary      Получить обновления расписания на сегодня
 // @Description  Отправляет начальное состояние расписания (`event: schedule_initial`) и последующие изменения (`event: schedule_update`) через Server-Sent Events.
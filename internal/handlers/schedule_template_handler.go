@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleTemplateHandler struct {
+	service *services.ScheduleTemplateService
+}
+
+func NewScheduleTemplateHandler(service *services.ScheduleTemplateService) *ScheduleTemplateHandler {
+	return &ScheduleTemplateHandler{service: service}
+}
+
+// GetAllTemplates godoc
+// @Summary      Получить шаблоны расписания (Админ)
+// @Description  Возвращает все недельные шаблоны расписания врачей.
+// @Tags         admin
+// @Produce      json
+// @Success      200 {array} models.ScheduleTemplate "Список шаблонов"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedule-templates [get]
+func (h *ScheduleTemplateHandler) GetAllTemplates(c *gin.Context) {
+	templates, err := h.service.GetAll()
+	if err != nil {
+		logger.Default().WithError(err).Error("GetAllTemplates: Failed to get schedule templates")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить шаблоны расписания"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// CreateTemplate godoc
+// @Summary      Создать шаблон расписания (Админ)
+// @Description  Создает недельный шаблон: дни недели (1 - пн, 7 - вс), рабочие часы, длительность слота, кабинет и перерывы.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.ScheduleTemplateRequest true "Параметры шаблона"
+// @Success      201 {object} models.ScheduleTemplate "Созданный шаблон"
+// @Failure      400 {object} map[string]string "Неверный формат запроса или параметры шаблона"
+// @Failure      404 {object} map[string]string "Врач не найден"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedule-templates [post]
+func (h *ScheduleTemplateHandler) CreateTemplate(c *gin.Context) {
+	var req models.ScheduleTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	template, err := h.service.Create(&req)
+	if err != nil {
+		logger.Default().WithError(err).Error("CreateTemplate: Failed to create schedule template")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+// UpdateTemplate godoc
+// @Summary      Изменить шаблон расписания (Админ)
+// @Description  Полностью заменяет параметры шаблона. Уже созданные слоты не изменяются.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID шаблона"
+// @Param        request body models.ScheduleTemplateRequest true "Параметры шаблона"
+// @Success      200 {object} models.ScheduleTemplate "Обновленный шаблон"
+// @Failure      400 {object} map[string]string "Неверный формат запроса или параметры шаблона"
+// @Failure      404 {object} map[string]string "Шаблон или врач не найден"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedule-templates/{id} [put]
+func (h *ScheduleTemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	var req models.ScheduleTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	template, err := h.service.Update(uint(id), &req)
+	if err != nil {
+		logger.Default().WithError(err).Error("UpdateTemplate: Failed to update schedule template")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate godoc
+// @Summary      Удалить шаблон расписания (Админ)
+// @Description  Удаляет шаблон. Уже созданные по нему слоты сохраняются.
+// @Tags         admin
+// @Produce      json
+// @Param        id path int true "ID шаблона"
+// @Success      200 {object} map[string]string "Шаблон удален"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Шаблон не найден"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedule-templates/{id} [delete]
+func (h *ScheduleTemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	if err := h.service.Delete(uint(id)); err != nil {
+		logger.Default().WithError(err).Error("DeleteTemplate: Failed to delete schedule template")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Шаблон расписания удален"})
+}
+
+// GenerateSchedules godoc
+// @Summary      Сгенерировать слоты по шаблонам (Админ)
+// @Description  Создает слоты расписания по активным шаблонам за период (включительно). Существующие слоты пропускаются, поэтому запуск можно повторять.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request body models.GenerateSchedulesRequest true "Период и, опционально, шаблон или врач"
+// @Success      200 {object} models.GenerateSchedulesResult "Итоги генерации"
+// @Failure      400 {object} map[string]string "Неверный период"
+// @Failure      404 {object} map[string]string "Шаблон не найден"
+// @Failure      409 {object} map[string]interface{} "Конфликты расписания (поле conflicts), если skip_conflicts=false"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/schedules/generate [post]
+func (h *ScheduleTemplateHandler) GenerateSchedules(c *gin.Context) {
+	var req models.GenerateSchedulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	result, err := h.service.Generate(&req)
+	if err != nil {
+		logger.Default().WithError(err).Error("GenerateSchedules: Failed to generate schedules")
+		writeScheduleError(c, err, errorStatus)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
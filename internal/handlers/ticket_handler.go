@@ -11,7 +11,6 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -51,10 +50,6 @@ type ConfirmationResponse struct {
 	Timeout      int    `json:"timeout" example:"10"`
 }
 
-type CheckInByPhoneRequest struct {
-	Phone string `json:"phone" binding:"required"`
-}
-
 // StartPage godoc
 // @Summary      Получить стартовую информацию
 // @Description  Возвращает стартовую информацию для клиента (например, текст кнопки)
@@ -208,40 +203,114 @@ func (h *TicketHandler) Confirmation(c *gin.Context) {
 
 // CheckInByPhone godoc
 // @Summary      Регистрация на прием по номеру телефона
-// @Description  Проверяет наличие записи по номеру телефона и выдает приоритетный талон, если прием скоро
+// @Description  Регистрирует явку на прием по номеру телефона в несколько шагов. Сначала терминал отправляет телефон и для любого номера получает запрос даты рождения (status=confirm_birth_date); если телефон и дата рождения не совпадают, ответ одинаковый независимо от того, известен ли телефон. Если под телефон и дату рождения подходят несколько пациентов, возвращается список для выбора (status=select_patient), выбранный пациент передается в patient_id. Если у пациента несколько записей на сегодня, возвращается список записей (status=select_appointments), выбранные записи передаются в appointment_ids и регистрируются одновременно. Регистрация доступна только в окне вокруг времени начала приема.
 // @Tags         tickets
 // @Accept       json
 // @Produce      json
-// @Param        request body CheckInByPhoneRequest true "Номер телефона пациента"
-// @Success      200 {object} ConfirmationResponse "Ответ с данными талона"
-// @Failure      400 {object} map[string]string "Ошибка: не передан номер телефона"
-// @Failure      404 {object} map[string]string "Запись не найдена или еще не время"
+// @Param        request body models.CheckInByPhoneRequest true "Телефон и данные для подтверждения"
+// @Success      200 {object} models.CheckInResult "Запрос уточнения или выданные талоны"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент или запись не найдены"
+// @Failure      409 {object} map[string]string "Регистрация по записи сейчас недоступна"
 // @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router       /api/tickets/appointment/phone [post]
 func (h *TicketHandler) CheckInByPhone(c *gin.Context) {
-	var req CheckInByPhoneRequest
+	var req models.CheckInByPhoneRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "phone is required"})
 		return
 	}
 
-	ticket, err := h.service.CheckInByPhone(req.Phone)
+	result, err := h.service.CheckInByPhone(&req)
 	if err != nil {
-		if strings.Contains(err.Error(), "не найден") || strings.Contains(err.Error(), "нет предстоящих записей") || strings.Contains(err.Error(), "ваша запись на") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, result)
+}
 
-	resp := ConfirmationResponse{
-		ServiceName:  h.service.MapServiceIDToName(*ticket.ServiceType),
-		TicketNumber: ticket.TicketNumber,
-		Message:      "Ваш электронный талон",
-		Timeout:      10,
+// CheckInByOMS godoc
+// @Summary      Регистрация на прием по полису ОМС
+// @Description  Регистрирует явку на прием по номеру полиса ОМС. Если у пациента несколько записей на сегодня, возвращается список записей (status=select_appointments), выбранные записи передаются в appointment_ids. Пациент в ответе указывается фамилией с инициалами.
+// @Tags         tickets
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CheckInByOMSRequest true "Номер полиса ОМС и выбранные записи"
+// @Success      200 {object} models.CheckInResult "Запрос уточнения или выданные талоны"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент или запись не найдены"
+// @Failure      409 {object} map[string]string "Регистрация по записи сейчас недоступна"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router       /api/tickets/appointment/oms [post]
+func (h *TicketHandler) CheckInByOMS(c *gin.Context) {
+	var req models.CheckInByOMSRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
 	}
-	c.JSON(http.StatusOK, resp)
+
+	result, err := h.service.CheckInByOMS(&req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CheckInByPassport godoc
+// @Summary      Регистрация на прием по паспорту
+// @Description  Регистрирует явку на прием по серии и номеру паспорта. Если у пациента несколько записей на сегодня, возвращается список записей (status=select_appointments), выбранные записи передаются в appointment_ids. Пациент в ответе указывается фамилией с инициалами.
+// @Tags         tickets
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CheckInByPassportRequest true "Паспорт и выбранные записи"
+// @Success      200 {object} models.CheckInResult "Запрос уточнения или выданные талоны"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Пациент или запись не найдены"
+// @Failure      409 {object} map[string]string "Регистрация по записи сейчас недоступна"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router       /api/tickets/appointment/passport [post]
+func (h *TicketHandler) CheckInByPassport(c *gin.Context) {
+	var req models.CheckInByPassportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	result, err := h.service.CheckInByPassport(&req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CheckInByCode godoc
+// @Summary      Регистрация на прием по коду подтверждения
+// @Description  Регистрирует явку по коду подтверждения, полученному при самостоятельной записи. Регистрация доступна только в день приема и в окне вокруг времени начала приема.
+// @Tags         tickets
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CheckInByCodeRequest true "Код подтверждения"
+// @Success      200 {object} models.CheckInResult "Выданный талон"
+// @Failure      400 {object} map[string]string "Неверный формат запроса"
+// @Failure      404 {object} map[string]string "Запись не найдена"
+// @Failure      409 {object} map[string]string "Регистрация по записи сейчас недоступна или уже выполнена"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router       /api/tickets/appointment/code [post]
+func (h *TicketHandler) CheckInByCode(c *gin.Context) {
+	var req models.CheckInByCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	result, err := h.service.CheckInByCode(&req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
 }
 
 // DownloadTicket godoc
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/pubsub"
+	"ElectronicQueue/internal/services"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WaitlistHandler обрабатывает HTTP-запросы листа ожидания.
+type WaitlistHandler struct {
+	service *services.WaitlistService
+	broker  *pubsub.Broker
+}
+
+// NewWaitlistHandler создает новый экземпляр WaitlistHandler.
+func NewWaitlistHandler(service *services.WaitlistService, broker *pubsub.Broker) *WaitlistHandler {
+	return &WaitlistHandler{service: service, broker: broker}
+}
+
+// GetEntries godoc
+// @Summary      Получить лист ожидания
+// @Description  Возвращает заявки листа ожидания в порядке очереди. Можно отфильтровать по статусу и врачу.
+// @Tags         registrar
+// @Produce      json
+// @Param        status query string false "Статус заявки (ожидает, предложено, записан, отменено)"
+// @Param        doctor_id query int false "ID врача"
+// @Success      200 {array} models.WaitlistEntry "Заявки листа ожидания"
+// @Failure      400 {object} map[string]string "Неверный ID врача"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/waitlist [get]
+func (h *WaitlistHandler) GetEntries(c *gin.Context) {
+	var doctorID *uint
+	if raw := c.Query("doctor_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID врача"})
+			return
+		}
+		v := uint(id)
+		doctorID = &v
+	}
+
+	entries, err := h.service.GetEntries(c.Query("status"), doctorID)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetEntries: Failed to get waitlist")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить лист ожидания"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// CreateEntry godoc
+// @Summary      Поставить пациента в лист ожидания
+// @Description  Добавляет заявку на более раннюю запись к врачу или к любому врачу специальности в желаемый период. Освободившийся подходящий слот будет удержан за пациентом и предложен регистратуре.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateWaitlistEntryRequest true "Параметры заявки"
+// @Success      201 {object} models.WaitlistEntry "Созданная заявка"
+// @Failure      400 {object} map[string]string "Неверный формат запроса или период"
+// @Failure      404 {object} map[string]string "Пациент или врач не найден"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/waitlist [post]
+func (h *WaitlistHandler) CreateEntry(c *gin.Context) {
+	var req models.CreateWaitlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	entry, err := h.service.CreateEntry(&req, requestActor(c))
+	if err != nil {
+		logger.Default().WithError(err).Error("CreateEntry: Failed to create waitlist entry")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// CancelEntry godoc
+// @Summary      Снять пациента с листа ожидания
+// @Description  Отменяет заявку. Если по ней действует предложение слота, оно отклоняется и слот предлагается следующему пациенту.
+// @Tags         registrar
+// @Produce      json
+// @Param        id path int true "ID заявки"
+// @Success      200 {object} map[string]string "Заявка отменена"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Заявка не найдена"
+// @Failure      409 {object} map[string]string "Заявка уже закрыта"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/waitlist/{id} [delete]
+func (h *WaitlistHandler) CancelEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	if err := h.service.CancelEntry(uint(id)); err != nil {
+		logger.Default().WithError(err).Error("CancelEntry: Failed to cancel waitlist entry")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Заявка снята с листа ожидания"})
+}
+
+// GetOffers godoc
+// @Summary      Получить предложения освободившихся слотов
+// @Description  Возвращает предложения слотов пациентам из листа ожидания, начиная с новых. По умолчанию только действующие.
+// @Tags         registrar
+// @Produce      json
+// @Param        status query string false "Статус предложения (предложено, принято, отклонено, истекло); all - все" default(предложено)
+// @Success      200 {array} models.WaitlistOffer "Предложения слотов"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/waitlist/offers [get]
+func (h *WaitlistHandler) GetOffers(c *gin.Context) {
+	status := c.DefaultQuery("status", string(models.OfferPending))
+	if status == "all" {
+		status = ""
+	}
+
+	offers, err := h.service.GetOffers(status)
+	if err != nil {
+		logger.Default().WithError(err).Error("GetOffers: Failed to get waitlist offers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить предложения"})
+		return
+	}
+	c.JSON(http.StatusOK, offers)
+}
+
+// AcceptOffer godoc
+// @Summary      Принять предложение слота
+// @Description  Записывает пациента на удерживаемый за ним слот. Заявка листа ожидания закрывается.
+// @Description  Запись проверяется по правилам специальности врача; при нарушениях возвращается 409 со списком violations. Чтобы записать пациента вопреки правилам, повторите запрос с override_reason.
+// @Tags         registrar
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID предложения"
+// @Param        request body models.AcceptOfferRequest false "Причина записи вопреки правилам"
+// @Success      200 {object} models.Appointment "Созданная запись"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Предложение не найдено"
+// @Failure      409 {object} map[string]interface{} "Предложение закрыто, срок удержания истек или нарушены правила записи (поле violations)"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/waitlist/offers/{id}/accept [post]
+func (h *WaitlistHandler) AcceptOffer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	var req models.AcceptOfferRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса: " + err.Error()})
+		return
+	}
+
+	appointment, err := h.service.AcceptOffer(uint(id), &req, requestActor(c))
+	if err != nil {
+		var ruleErr *services.BookingRuleViolationError
+		if errors.As(err, &ruleErr) {
+			logger.Default().WithError(err).Warn("AcceptOffer: Booking rules violated")
+			c.JSON(http.StatusConflict, gin.H{"error": ruleErr.Error(), "violations": ruleErr.Violations})
+			return
+		}
+		logger.Default().WithError(err).Error("AcceptOffer: Failed to accept waitlist offer")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, appointment)
+}
+
+// DeclineOffer godoc
+// @Summary      Отклонить предложение слота
+// @Description  Фиксирует отказ пациента. Заявка возвращается в очередь, а слот предлагается следующему пациенту или освобождается.
+// @Tags         registrar
+// @Produce      json
+// @Param        id path int true "ID предложения"
+// @Success      200 {object} models.WaitlistOffer "Отклоненное предложение"
+// @Failure      400 {object} map[string]string "Неверный ID"
+// @Failure      404 {object} map[string]string "Предложение не найдено"
+// @Failure      409 {object} map[string]string "Предложение уже закрыто"
+// @Failure      500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/waitlist/offers/{id}/decline [post]
+func (h *WaitlistHandler) DeclineOffer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат ID"})
+		return
+	}
+
+	offer, err := h.service.DeclineOffer(uint(id))
+	if err != nil {
+		logger.Default().WithError(err).Error("DeclineOffer: Failed to decline waitlist offer")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, offer)
+}
+
+// OfferUpdates godoc
+// @Summary      Поток предложений листа ожидания
+// @Description  Отправляет через Server-Sent Events события `waitlist_offer` о новых, принятых, отклоненных и истекших предложениях слотов.
+// @Tags         registrar
+// @Produce      text/event-stream
+// @Success      200 {object} models.WaitlistOfferNotification "Поток событий о предложениях"
+// @Security     ApiKeyAuth
+// @Router       /api/registrar/waitlist/offers/updates [get]
+func (h *WaitlistHandler) OfferUpdates(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	log := logger.Default().WithField("module", "SSE_WAITLIST")
+
+	clientChan := h.broker.Subscribe()
+	defer h.broker.Unsubscribe(clientChan)
+
+	marker := `"event":"` + models.WaitlistOfferEvent + `"`
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case msg, ok := <-clientChan:
+			if !ok {
+				log.Info("Канал уведомлений закрыт для листа ожидания.")
+				return false
+			}
+			if !strings.Contains(msg, marker) {
+				return true
+			}
+
+			var rawData json.RawMessage
+			if err := json.Unmarshal([]byte(msg), &rawData); err != nil {
+				log.WithError(err).Warn("Получено невалидное уведомление листа ожидания, пропуск.")
+				return true
+			}
+			c.SSEvent(models.WaitlistOfferEvent, rawData)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			return true
+
+		case <-c.Request.Context().Done():
+			log.Info("Клиент отключился от потока листа ожидания.")
+			return false
+		}
+	})
+}
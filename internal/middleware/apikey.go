@@ -1,23 +1,94 @@
 package middleware
 
 import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"crypto/subtle"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
+// apiKeyContextKey - ключ, под которым аутентифицированный API-ключ сохраняется в контексте запроса.
+const apiKeyContextKey = "api_key"
+
 // RequireAPIKey проверяет наличие и правильность API-ключа в заголовке.
-func RequireAPIKey(apiKey string) gin.HandlerFunc {
+// Ключ ищется среди выпущенных ключей в БД; masterKey из окружения (если задан)
+// продолжает работать как ключ с полным доступом для обратной совместимости.
+func RequireAPIKey(keyService *services.APIKeyService, masterKey string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		providedKey := c.GetHeader("X-API-KEY")
 		if providedKey == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key is missing"})
 			return
 		}
-		if providedKey != apiKey {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid API key"})
+
+		if masterKey != "" && subtle.ConstantTimeCompare([]byte(providedKey), []byte(masterKey)) == 1 {
+			c.Set(apiKeyContextKey, &models.APIKey{Name: "master", Scopes: models.StringList{models.ScopeWildcard}})
+			c.Next()
+			return
+		}
+
+		key, err := keyService.Authenticate(providedKey)
+		if err != nil {
+			if errors.Is(err, services.ErrAPIKeyInvalid) || errors.Is(err, services.ErrAPIKeyInactive) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			logger.Default().WithError(err).Error("RequireAPIKey: failed to authenticate API key")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+			return
+		}
+
+		if !keyService.AllowRequest(key) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded for this API key"})
 			return
 		}
+
+		c.Set(apiKeyContextKey, key)
+		c.Set("api_key_id", key.ID)
 		c.Next()
 	}
 }
+
+// RequireScope проверяет, что API-ключ запроса имеет указанную область доступа.
+// Должен использоваться после RequireAPIKey.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checkScope(c, scope)
+	}
+}
+
+// RequireTableScope проверяет область доступа "database:<таблица>:<операция>",
+// где имя таблицы берется из параметра пути :table.
+func RequireTableScope(operation string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checkScope(c, "database:"+c.Param("table")+":"+operation)
+	}
+}
+
+// APIKeyFromContext возвращает API-ключ, сохраненный RequireAPIKey.
+func APIKeyFromContext(c *gin.Context) (*models.APIKey, bool) {
+	value, exists := c.Get(apiKeyContextKey)
+	if !exists {
+		return nil, false
+	}
+	key, ok := value.(*models.APIKey)
+	return key, ok
+}
+
+func checkScope(c *gin.Context, scope string) {
+	key, ok := APIKeyFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key is missing"})
+		return
+	}
+	if !key.HasScope(scope) {
+		logger.Default().WithField("api_key", key.Name).WithField("scope", scope).Warn("API key scope denied")
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key has no access to scope '" + scope + "'"})
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/services"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader - заголовок с идентификатором запроса. Принимается от клиента или создается сервером.
+	RequestIDHeader = "X-Request-ID"
+	// requestIDContextKey - ключ, под которым идентификатор запроса сохраняется в контексте.
+	requestIDContextKey = "request_id"
+	// auditChangeContextKey - ключ, под которым обработчик сохраняет описание изменения сущности.
+	auditChangeContextKey = "audit_change"
+)
+
+// validRequestID ограничивает идентификаторы запросов, принимаемые от клиента.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Audit присваивает каждому запросу идентификатор и записывает изменяющие запросы (POST, PUT, PATCH, DELETE)
+// в журнал аудита: кто, с какого IP, какой эндпоинт, с каким результатом и, если обработчик сообщил
+// через AuditChange, состояние сущности до и после. Ошибка записи журнала не влияет на ответ клиенту.
+func Audit(service *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		c.Set(requestIDContextKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		c.Next()
+
+		actor, role := RequestActor(c)
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = c.Request.URL.Path
+		}
+		entry := &models.AuditLog{
+			RequestID:  requestID,
+			Actor:      actor,
+			Role:       role,
+			ClientIP:   c.ClientIP(),
+			Method:     c.Request.Method,
+			Endpoint:   endpoint,
+			Path:       c.Request.URL.Path,
+			StatusCode: c.Writer.Status(),
+		}
+		entry.Entity, entry.EntityID = routeEntity(c)
+
+		var change *models.AuditChange
+		if value, exists := c.Get(auditChangeContextKey); exists {
+			change, _ = value.(*models.AuditChange)
+		}
+		if err := service.Record(entry, change); err != nil {
+			logger.Default().WithError(err).WithField("request_id", requestID).WithField("endpoint", endpoint).
+				Error("Failed to write audit log")
+		}
+	}
+}
+
+// AuditChange сообщает журналу аудита об изменении сущности: ее тип, идентификатор и состояния до и после.
+// Вызывается обработчиком после успешного изменения.
+func AuditChange(c *gin.Context, change models.AuditChange) {
+	c.Set(auditChangeContextKey, &change)
+}
+
+// RequestID возвращает идентификатор текущего запроса.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// RequestActor возвращает автора запроса и его роль: пользователя из JWT (например, "registrar:5", "registrar")
+// или API-ключ (например, "api_key:master", "api_key"). Для анонимных запросов возвращается "unknown".
+func RequestActor(c *gin.Context) (string, string) {
+	if userID, exists := c.Get("user_id"); exists {
+		role := c.GetString("role")
+		return fmt.Sprintf("%s:%v", role, userID), role
+	}
+	if key, ok := APIKeyFromContext(c); ok {
+		return "api_key:" + key.Name, "api_key"
+	}
+	return "unknown", ""
+}
+
+// routeEntity определяет сущность по шаблону маршрута: последний неизменяемый сегмент перед первым параметром
+// (или последний сегмент, если параметров нет) и значение этого параметра.
+// Например, "/api/admin/tickets/:id" дает ("tickets", "42").
+func routeEntity(c *gin.Context) (string, string) {
+	var entity string
+	for _, segment := range strings.Split(strings.Trim(c.FullPath(), "/"), "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			return entity, c.Param(segment[1:])
+		}
+		if segment != "api" {
+			entity = segment
+		}
+	}
+	return entity, ""
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"ElectronicQueue/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitByIP ограничивает число запросов с одного IP-адреса в минуту.
+// Используется для публичных эндпоинтов, не защищенных ни JWT, ни API-ключом. Лимит <= 0 отключает проверку.
+func RateLimitByIP(limitPerMinute int) gin.HandlerFunc {
+	limiter := utils.NewRateLimiter(time.Minute)
+	return func(c *gin.Context) {
+		if !limiter.Allow(c.ClientIP(), limitPerMinute) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Слишком много запросов, повторите попытку позже"})
+			return
+		}
+		c.Next()
+	}
+}
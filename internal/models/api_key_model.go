@@ -0,0 +1,94 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ScopeWildcard обозначает любое значение в соответствующей части области доступа.
+const ScopeWildcard = "*"
+
+// APIKey представляет ключ доступа интегратора к внешнему API и админ-панели.
+// Сам ключ в базе не хранится, только его SHA-256 хэш.
+type APIKey struct {
+	ID                 uint       `gorm:"primaryKey;autoIncrement;column:api_key_id" json:"id"`
+	Name               string     `gorm:"type:varchar(100);not null;column:name" json:"name"`
+	KeyPrefix          string     `gorm:"type:varchar(16);not null;column:key_prefix" json:"key_prefix"`
+	KeyHash            string     `gorm:"type:varchar(64);not null;unique;column:key_hash" json:"-"`
+	Scopes             StringList `gorm:"type:jsonb;not null;column:scopes" json:"scopes"`
+	RateLimitPerMinute int        `gorm:"not null;column:rate_limit_per_minute" json:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time `gorm:"column:expires_at" json:"expires_at,omitempty"`
+	LastUsedAt         *time.Time `gorm:"column:last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt          *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt          time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+// CreateAPIKeyRequest - DTO для выпуска нового ключа.
+// Области доступа задаются в формате "database:<таблица>:<операция>" или "admin:<раздел>",
+// где любая часть может быть заменена на "*".
+// Если RateLimitPerMinute не указан, действует лимит по умолчанию; 0 снимает ограничение.
+type CreateAPIKeyRequest struct {
+	Name               string     `json:"name" binding:"required" example:"1С интеграция"`
+	Scopes             []string   `json:"scopes" binding:"required,min=1" example:"database:tickets:select"`
+	RateLimitPerMinute *int       `json:"rate_limit_per_minute,omitempty" binding:"omitempty,gte=0" example:"120"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty" example:"2026-12-31T23:59:59Z"`
+}
+
+// RotateAPIKeyRequest - DTO для ротации ключа.
+// GracePeriodMinutes задает, сколько минут старый ключ продолжит работать.
+type RotateAPIKeyRequest struct {
+	GracePeriodMinutes int `json:"grace_period_minutes" binding:"omitempty,gte=0" example:"1440"`
+}
+
+// APIKeyIssuedResponse возвращается один раз при выпуске ключа и содержит сам ключ.
+type APIKeyIssuedResponse struct {
+	Key    string `json:"key"`
+	APIKey APIKey `json:"api_key"`
+}
+
+// IsActive проверяет, что ключ не отозван и не истек на момент now.
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil && !k.RevokedAt.After(now) {
+		return false
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return false
+	}
+	return true
+}
+
+// HasScope проверяет, покрывает ли хотя бы одна из областей ключа требуемую.
+func (k *APIKey) HasScope(required string) bool {
+	for _, scope := range k.Scopes {
+		if ScopeMatches(scope, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeMatches сравнивает выданную область доступа с требуемой по частям, разделенным ":".
+// "*" в выданной области совпадает с любым значением, а последняя "*" - с любым остатком.
+func ScopeMatches(granted, required string) bool {
+	if granted == ScopeWildcard {
+		return true
+	}
+	grantedParts := strings.Split(granted, ":")
+	requiredParts := strings.Split(required, ":")
+
+	for i, part := range grantedParts {
+		if i >= len(requiredParts) {
+			return false
+		}
+		if part == ScopeWildcard {
+			if i == len(grantedParts)-1 {
+				return true
+			}
+			continue
+		}
+		if part != requiredParts[i] {
+			return false
+		}
+	}
+	return len(grantedParts) == len(requiredParts)
+}
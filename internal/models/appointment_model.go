@@ -4,23 +4,91 @@ import (
 	"time"
 )
 
+// AppointmentStatus определяет статус записи на прием.
+type AppointmentStatus string
+
+// Статусы записи на прием.
+const (
+	AppointmentBooked      AppointmentStatus = "записан"
+	AppointmentCancelled   AppointmentStatus = "отменен"
+	AppointmentRescheduled AppointmentStatus = "перенесен"
+	AppointmentAttended    AppointmentStatus = "явился"
+	AppointmentNoShow      AppointmentStatus = "не_явился"
+)
+
+// Виды записей, оформленных врачом с приема.
+const (
+	// BookingKindFollowUp - повторный визит к врачу той же специальности.
+	BookingKindFollowUp = "follow_up"
+	// BookingKindReferral - запись к врачу другой специальности по направлению.
+	BookingKindReferral = "referral"
+)
+
+// OccupyingAppointmentStatuses - статусы записей, которые занимают слот расписания.
+// Отмененные и перенесенные записи остаются в истории, но слот освобождают.
+var OccupyingAppointmentStatuses = []AppointmentStatus{AppointmentBooked, AppointmentAttended, AppointmentNoShow}
+
 // Appointment представляет собой модель записи на прием (связь между пациентом, расписанием и талоном).
 type Appointment struct {
-	ID         uint      `gorm:"primaryKey;autoIncrement;column:appointment_id" json:"id"`
-	ScheduleID uint      `gorm:"not null;column:schedule_id" json:"schedule_id"`
-	PatientID  uint      `gorm:"not null;column:patient_id" json:"patient_id"`
-	TicketID   *uint     `gorm:"column:ticket_id" json:"ticket_id,omitempty"`
-	CreatedAt  time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
-	Patient    Patient   `gorm:"foreignKey:PatientID" json:"patient,omitempty"`
-	Schedule   Schedule  `gorm:"foreignKey:ScheduleID" json:"schedule,omitempty"`
-	Ticket     Ticket    `gorm:"foreignKey:TicketID" json:"ticket,omitempty"`
+	ID               uint              `gorm:"primaryKey;autoIncrement;column:appointment_id" json:"id"`
+	ScheduleID       uint              `gorm:"not null;column:schedule_id" json:"schedule_id"`
+	PatientID        uint              `gorm:"not null;column:patient_id" json:"patient_id"`
+	TicketID         *uint             `gorm:"column:ticket_id" json:"ticket_id,omitempty"`
+	CreatedAt        time.Time         `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	FlagReason       *string           `gorm:"column:flag_reason" json:"flag_reason,omitempty"`
+	Status           AppointmentStatus `gorm:"type:varchar(20);not null;default:записан;column:status" json:"status"`
+	CancelReason     *string           `gorm:"column:cancel_reason" json:"cancel_reason,omitempty"`
+	CancelledBy      *string           `gorm:"column:cancelled_by" json:"cancelled_by,omitempty"`
+	CancelledAt      *time.Time        `gorm:"column:cancelled_at" json:"cancelled_at,omitempty"`
+	RescheduledToID  *uint             `gorm:"column:rescheduled_to_id" json:"rescheduled_to_id,omitempty"`
+	ConfirmationCode *string           `gorm:"column:confirmation_code" json:"confirmation_code,omitempty"`
+	OverrideReason   *string           `gorm:"column:override_reason" json:"override_reason,omitempty"`
+	OverriddenBy     *string           `gorm:"column:overridden_by" json:"overridden_by,omitempty"`
+	OverriddenRules  StringList        `gorm:"type:jsonb;not null;column:overridden_rules" json:"overridden_rules,omitempty"`
+	BookedBy         *string           `gorm:"column:booked_by" json:"booked_by,omitempty" example:"doctor:3"`
+
+	// Заполняются для записей, оформленных врачом с приема: вид записи и прием, с которого она оформлена.
+	BookingKind         *string `gorm:"column:booking_kind" json:"booking_kind,omitempty" example:"follow_up"`
+	SourceAppointmentID *uint   `gorm:"column:source_appointment_id" json:"source_appointment_id,omitempty"`
+
+	// Итог приема, который врач указывает при завершении.
+	DiagnosisCode          *string    `gorm:"column:diagnosis_code" json:"diagnosis_code,omitempty" example:"J06.9"`
+	VisitNote              *string    `gorm:"column:visit_note" json:"visit_note,omitempty"`
+	FollowUpNeeded         bool       `gorm:"not null;default:false;column:follow_up_needed" json:"follow_up_needed"`
+	ReferralSpecialization *string    `gorm:"column:referral_specialization" json:"referral_specialization,omitempty" example:"Кардиолог"`
+	IssuedReferralID       *uint      `gorm:"column:issued_referral_id" json:"issued_referral_id,omitempty"`
+	OutcomeRecordedAt      *time.Time `gorm:"column:outcome_recorded_at" json:"outcome_recorded_at,omitempty"`
+	OutcomeRecordedBy      *string    `gorm:"column:outcome_recorded_by" json:"outcome_recorded_by,omitempty"`
+
+	Patient  Patient  `gorm:"foreignKey:PatientID" json:"patient,omitempty"`
+	Schedule Schedule `gorm:"foreignKey:ScheduleID" json:"schedule,omitempty"`
+	Ticket   Ticket   `gorm:"foreignKey:TicketID" json:"ticket,omitempty"`
 }
 
 // CreateAppointmentRequest определяет структуру для создания новой записи на прием.
+// OverrideReason позволяет регистратору записать пациента вопреки правилам записи; причина сохраняется в записи.
 type CreateAppointmentRequest struct {
-	ScheduleID uint  `json:"schedule_id" binding:"required"`
-	PatientID  uint  `json:"patient_id" binding:"required"`
-	TicketID   *uint `json:"ticket_id"`
+	ScheduleID     uint   `json:"schedule_id" binding:"required"`
+	PatientID      uint   `json:"patient_id" binding:"required"`
+	TicketID       *uint  `json:"ticket_id"`
+	OverrideReason string `json:"override_reason" example:"Направление будет предоставлено на приеме"`
+
+	// Заполняются сервисом, если запись создается вопреки правилам записи.
+	OverriddenBy    string   `json:"-"`
+	OverriddenRules []string `json:"-"`
+
+	// Заполняются сервисом: кто оформил запись и, для записей врача с приема, вид записи и исходный прием.
+	BookedBy            string `json:"-"`
+	BookingKind         string `json:"-"`
+	SourceAppointmentID *uint  `json:"-"`
+}
+
+// DoctorBookingRequest определяет запрос врача на запись текущего пациента к себе повторно
+// или к врачу другой специальности. Пациент определяется по талону приема.
+type DoctorBookingRequest struct {
+	TicketID       uint   `json:"ticket_id" binding:"required" example:"15"`
+	ScheduleID     uint   `json:"schedule_id" binding:"required" example:"42"`
+	OverrideReason string `json:"override_reason" example:"Срочная консультация"`
 }
 
 // AppointmentResponse определяет данные, возвращаемые API.
@@ -31,8 +99,42 @@ type AppointmentResponse struct {
 	Schedule  ScheduleResponse `json:"schedule"`
 }
 
+// CancelAppointmentRequest определяет структуру для отмены записи на прием.
+type CancelAppointmentRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Пациент заболел"`
+}
+
+// RescheduleAppointmentRequest определяет структуру для переноса записи на другой слот.
+// OverrideReason позволяет перенести запись вопреки правилам записи, как при создании записи.
+type RescheduleAppointmentRequest struct {
+	ScheduleID     uint   `json:"schedule_id" binding:"required" example:"42"`
+	Reason         string `json:"reason" example:"Перенос по просьбе пациента"`
+	OverrideReason string `json:"override_reason" example:"Согласовано с заведующим"`
+
+	// Заполняются сервисом, если запись переносится вопреки правилам записи.
+	OverriddenBy    string   `json:"-"`
+	OverriddenRules []string `json:"-"`
+}
+
+// RescheduleAppointmentResult содержит прежнюю (перенесенную) и новую записи.
+type RescheduleAppointmentResult struct {
+	Previous Appointment `json:"previous"`
+	Current  Appointment `json:"current"`
+}
+
 // UpdateAppointmentRequest определяет структуру для добавления результатов приема.
+// Если указан любой из результатов, код диагноза по МКБ-10 обязателен.
+// ReferralSpecialization оформляет направление пациента к врачу другой специальности.
 type UpdateAppointmentRequest struct {
+	DiagnosisCode          string `json:"diagnosis_code" example:"J06.9"`
+	Note                   string `json:"note" binding:"max=2000" example:"ОРВИ, назначено симптоматическое лечение"`
+	FollowUpNeeded         bool   `json:"follow_up_needed" example:"true"`
+	ReferralSpecialization string `json:"referral_specialization" example:"Кардиолог"`
+}
+
+// IsEmpty сообщает, что врач не указал результатов приема.
+func (r *UpdateAppointmentRequest) IsEmpty() bool {
+	return r.DiagnosisCode == "" && r.Note == "" && !r.FollowUpNeeded && r.ReferralSpecialization == ""
 }
 
 // ScheduleWithAppointmentInfo объединяет информацию о слоте расписания и записи на прием.
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// AuditLog - запись журнала аудита об изменяющем запросе (POST, PUT, PATCH, DELETE).
+// Журнал только дополняется: изменение и удаление записей запрещены триггером в БД.
+type AuditLog struct {
+	ID         uint         `gorm:"primaryKey;autoIncrement;column:audit_id" json:"id"`
+	RequestID  string       `gorm:"type:varchar(64);not null;column:request_id" json:"request_id"`
+	Actor      string       `gorm:"type:varchar(100);not null;column:actor" json:"actor" example:"registrar:5"`
+	Role       string       `gorm:"type:varchar(50);column:role" json:"role,omitempty" example:"registrar"`
+	ClientIP   string       `gorm:"type:varchar(45);column:client_ip" json:"client_ip,omitempty"`
+	Method     string       `gorm:"type:varchar(10);not null;column:method" json:"method" example:"DELETE"`
+	Endpoint   string       `gorm:"type:varchar(255);not null;column:endpoint" json:"endpoint" example:"/api/admin/tickets/:id"`
+	Path       string       `gorm:"type:varchar(255);not null;column:path" json:"path" example:"/api/admin/tickets/42"`
+	StatusCode int          `gorm:"not null;column:status_code" json:"status_code" example:"200"`
+	Entity     string       `gorm:"type:varchar(100);column:entity" json:"entity,omitempty" example:"tickets"`
+	EntityID   string       `gorm:"type:varchar(100);column:entity_id" json:"entity_id,omitempty" example:"42"`
+	Before     JSONDocument `gorm:"type:jsonb;column:before" json:"before,omitempty" swaggertype:"object"`
+	After      JSONDocument `gorm:"type:jsonb;column:after" json:"after,omitempty" swaggertype:"object"`
+	Diff       JSONDocument `gorm:"type:jsonb;column:diff" json:"diff,omitempty" swaggertype:"object"`
+	CreatedAt  time.Time    `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// AuditChange описывает изменение сущности, о котором обработчик сообщает журналу аудита.
+// Before и After сериализуются в JSON; для созданной сущности Before пуст, для удаленной - After.
+type AuditChange struct {
+	Entity   string
+	EntityID string
+	Before   interface{}
+	After    interface{}
+}
+
+// AuditLogFilter - условия выборки журнала аудита. Пустые поля не ограничивают выборку.
+type AuditLogFilter struct {
+	Actor     string
+	Role      string
+	Entity    string
+	EntityID  string
+	Method    string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+	Page      int
+	Limit     int
+}
+
+// AuditLogPage - страница журнала аудита.
+type AuditLogPage struct {
+	Page  int        `json:"page" example:"1"`
+	Limit int        `json:"limit" example:"50"`
+	Total int64      `json:"total" example:"1250"`
+	Data  []AuditLog `json:"data"`
+}
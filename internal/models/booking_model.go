@@ -0,0 +1,41 @@
+package models
+
+// BookingSlot - свободный слот, доступный пациенту для самостоятельной записи.
+// Не содержит сведений о других пациентах.
+type BookingSlot struct {
+	ScheduleID     uint   `json:"schedule_id" example:"42"`
+	DoctorID       uint   `json:"doctor_id" example:"3"`
+	DoctorName     string `json:"doctor_name" example:"Иванов Иван Иванович"`
+	Specialization string `json:"specialization" example:"Терапевт"`
+	Date           string `json:"date" example:"2025-03-03"`
+	StartTime      string `json:"start_time" example:"10:30:00"`
+	EndTime        string `json:"end_time" example:"10:45:00"`
+	Cabinet        *int   `json:"cabinet,omitempty" example:"101"`
+}
+
+// BookingLimits - ограничения самостоятельной записи для одного пациента.
+// MaxActive - сколько предстоящих записей может быть у пациента одновременно (0 - без ограничений);
+// OnePerDoctor запрещает вторую предстоящую запись к тому же врачу.
+type BookingLimits struct {
+	MaxActive    int
+	OnePerDoctor bool
+}
+
+// SelfBookingRequest определяет структуру для самостоятельной записи пациента.
+// Пациент подтверждает личность номером полиса ОМС и датой рождения.
+type SelfBookingRequest struct {
+	ScheduleID uint   `json:"schedule_id" binding:"required" example:"42"`
+	OmsNumber  string `json:"oms_number" binding:"required" example:"1234567890123456"`
+	BirthDate  string `json:"birth_date" binding:"required" example:"1985-04-12"`
+}
+
+// SelfBookingResponse содержит подтверждение самостоятельной записи.
+type SelfBookingResponse struct {
+	AppointmentID    uint   `json:"appointment_id" example:"128"`
+	ConfirmationCode string `json:"confirmation_code" example:"K7M2QX9D"`
+	DoctorName       string `json:"doctor_name" example:"Иванов Иван Иванович"`
+	Specialization   string `json:"specialization" example:"Терапевт"`
+	Date             string `json:"date" example:"2025-03-03"`
+	StartTime        string `json:"start_time" example:"10:30:00"`
+	Cabinet          *int   `json:"cabinet,omitempty" example:"101"`
+}
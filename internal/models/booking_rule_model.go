@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Типы нарушений правил записи.
+const (
+	RuleViolationMaxActive = "max_active"
+	RuleViolationInterval  = "min_interval"
+	RuleViolationAge       = "age"
+	RuleViolationReferral  = "referral_required"
+)
+
+// BookingRule - правила записи к врачам одной специальности.
+// Пустые (nil) ограничения не проверяются.
+type BookingRule struct {
+	ID                    uint      `gorm:"primaryKey;autoIncrement;column:rule_id" json:"rule_id"`
+	Specialization        string    `gorm:"type:varchar(100);not null;column:specialization" json:"specialization" example:"Кардиолог"`
+	MaxActiveAppointments *int      `gorm:"column:max_active_appointments" json:"max_active_appointments,omitempty" example:"1"`
+	MinIntervalDays       *int      `gorm:"column:min_interval_days" json:"min_interval_days,omitempty" example:"14"`
+	MinAge                *int      `gorm:"column:min_age" json:"min_age,omitempty" example:"18"`
+	MaxAge                *int      `gorm:"column:max_age" json:"max_age,omitempty"`
+	ReferralRequired      bool      `gorm:"not null;default:false;column:referral_required" json:"referral_required"`
+	IsActive              bool      `gorm:"not null;default:true;column:is_active" json:"is_active"`
+	Description           string    `gorm:"column:description" json:"description,omitempty"`
+	CreatedAt             time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt             time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// BookingRuleRequest определяет структуру для создания или замены правил записи по специальности.
+type BookingRuleRequest struct {
+	Specialization        string `json:"specialization" binding:"required" example:"Кардиолог"`
+	MaxActiveAppointments *int   `json:"max_active_appointments" binding:"omitempty,gt=0" example:"1"`
+	MinIntervalDays       *int   `json:"min_interval_days" binding:"omitempty,gt=0" example:"14"`
+	MinAge                *int   `json:"min_age" binding:"omitempty,gte=0" example:"18"`
+	MaxAge                *int   `json:"max_age" binding:"omitempty,gte=0"`
+	ReferralRequired      bool   `json:"referral_required" example:"true"`
+	IsActive              *bool  `json:"is_active" example:"true"`
+	Description           string `json:"description" example:"Прием только по направлению терапевта"`
+}
+
+// BookingRuleViolation описывает нарушение правила записи.
+type BookingRuleViolation struct {
+	Type           string `json:"type" example:"referral_required"`
+	Message        string `json:"message"`
+	Specialization string `json:"specialization" example:"Кардиолог"`
+}
+
+// Referral - направление пациента к врачу определенной специальности.
+// Направление действует до ValidUntil включительно и используется для одной записи.
+type Referral struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement;column:referral_id" json:"referral_id"`
+	PatientID      uint      `gorm:"not null;column:patient_id" json:"patient_id"`
+	Specialization string    `gorm:"type:varchar(100);not null;column:specialization" json:"specialization" example:"Кардиолог"`
+	ReferralNumber string    `gorm:"type:varchar(50);column:referral_number" json:"referral_number,omitempty" example:"НП-2025-0042"`
+	IssuedBy       string    `gorm:"type:varchar(150);column:issued_by" json:"issued_by,omitempty" example:"Терапевт Петрова А.С."`
+	IssuedAt       time.Time `gorm:"type:date;not null;column:issued_at" json:"issued_at"`
+	ValidUntil     time.Time `gorm:"type:date;not null;column:valid_until" json:"valid_until"`
+	AppointmentID  *uint     `gorm:"column:appointment_id" json:"appointment_id,omitempty"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// CreateReferralRequest определяет структуру для регистрации направления пациента.
+type CreateReferralRequest struct {
+	Specialization string    `json:"specialization" binding:"required" example:"Кардиолог"`
+	ReferralNumber string    `json:"referral_number" example:"НП-2025-0042"`
+	IssuedBy       string    `json:"issued_by" example:"Терапевт Петрова А.С."`
+	IssuedAt       time.Time `json:"issued_at" binding:"required" example:"2025-03-01T00:00:00Z"`
+	ValidUntil     time.Time `json:"valid_until" binding:"required" example:"2025-04-01T00:00:00Z"`
+}
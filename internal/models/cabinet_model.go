@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Cabinet - кабинет клиники. Номер кабинета является первичным ключом и используется в расписании.
+type Cabinet struct {
+	Number     int        `gorm:"primaryKey;autoIncrement:false;column:cabinet_number" json:"cabinet_number" example:"101"`
+	Name       string     `gorm:"type:varchar(100);not null;column:name" json:"name" example:"Кабинет терапевта"`
+	Floor      *int       `gorm:"column:floor" json:"floor,omitempty" example:"1"`
+	Wing       string     `gorm:"type:varchar(50);column:wing" json:"wing,omitempty" example:"Левое крыло"`
+	Directions string     `gorm:"column:directions" json:"directions,omitempty" example:"От регистратуры налево, третья дверь"`
+	Equipment  StringList `gorm:"type:jsonb;not null;column:equipment" json:"equipment"`
+	IsActive   bool       `gorm:"not null;default:true;column:is_active" json:"is_active"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// CreateCabinetRequest определяет структуру для создания кабинета.
+type CreateCabinetRequest struct {
+	Number     int      `json:"cabinet_number" binding:"required,gt=0,lte=999" example:"101"`
+	Name       string   `json:"name" binding:"required" example:"Кабинет терапевта"`
+	Floor      *int     `json:"floor" example:"1"`
+	Wing       string   `json:"wing" example:"Левое крыло"`
+	Directions string   `json:"directions" example:"От регистратуры налево, третья дверь"`
+	Equipment  []string `json:"equipment" example:"ЭКГ,кушетка"`
+	IsActive   *bool    `json:"is_active" example:"true"`
+}
+
+// UpdateCabinetRequest определяет структуру для частичного обновления кабинета.
+type UpdateCabinetRequest struct {
+	Name       *string   `json:"name,omitempty" binding:"omitempty,min=1"`
+	Floor      *int      `json:"floor,omitempty"`
+	Wing       *string   `json:"wing,omitempty"`
+	Directions *string   `json:"directions,omitempty"`
+	Equipment  *[]string `json:"equipment,omitempty"`
+	IsActive   *bool     `json:"is_active,omitempty"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// ClinicDayType определяет тип особого дня в календаре клиники.
+type ClinicDayType string
+
+// Типы особых дней клиники.
+const (
+	ClinicDayHoliday ClinicDayType = "выходной"
+	ClinicDayShort   ClinicDayType = "сокращенный"
+)
+
+// Причины отсутствия врача.
+const (
+	AbsenceVacation     = "отпуск"
+	AbsenceSickLeave    = "больничный"
+	AbsenceBusinessTrip = "командировка"
+	AbsenceOther        = "другое"
+)
+
+// ClinicDay - особый день календаря клиники: нерабочий или сокращенный.
+// Для сокращенного дня WorkStart и WorkEnd задают часы работы клиники.
+type ClinicDay struct {
+	Date        time.Time     `gorm:"type:date;primaryKey;column:date" json:"date"`
+	DayType     ClinicDayType `gorm:"type:varchar(20);not null;column:day_type" json:"day_type" example:"выходной"`
+	WorkStart   *string       `gorm:"type:time;column:work_start" json:"work_start,omitempty" example:"09:00:00"`
+	WorkEnd     *string       `gorm:"type:time;column:work_end" json:"work_end,omitempty" example:"14:00:00"`
+	Description string        `gorm:"column:description" json:"description,omitempty" example:"Новогодние праздники"`
+}
+
+// TableName задает имя таблицы календаря клиники.
+func (ClinicDay) TableName() string {
+	return "clinic_calendar"
+}
+
+// DoctorAbsence - период отсутствия врача (отпуск, больничный и т.п.).
+// Если StartTime и EndTime заданы, врач отсутствует только в эти часы каждого дня периода.
+type DoctorAbsence struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement;column:absence_id" json:"absence_id"`
+	DoctorID  uint      `gorm:"not null;column:doctor_id" json:"doctor_id"`
+	DateFrom  time.Time `gorm:"type:date;not null;column:date_from" json:"date_from"`
+	DateTo    time.Time `gorm:"type:date;not null;column:date_to" json:"date_to"`
+	StartTime *string   `gorm:"type:time;column:start_time" json:"start_time,omitempty" example:"09:00:00"`
+	EndTime   *string   `gorm:"type:time;column:end_time" json:"end_time,omitempty" example:"12:00:00"`
+	Reason    string    `gorm:"type:varchar(30);not null;column:reason" json:"reason" example:"отпуск"`
+	Comment   string    `gorm:"column:comment" json:"comment,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// AppointmentFlag - пометка записи от одного источника (день календаря или отсутствие врача).
+// Сводка всех пометок записи хранится в Appointment.FlagReason.
+type AppointmentFlag struct {
+	AppointmentID uint      `gorm:"primaryKey;column:appointment_id" json:"appointment_id"`
+	Source        string    `gorm:"primaryKey;type:varchar(50);column:source" json:"source" example:"absence:3"`
+	Reason        string    `gorm:"not null;column:reason" json:"reason"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName задает имя таблицы пометок записей.
+func (AppointmentFlag) TableName() string {
+	return "appointment_flags"
+}
+
+// ClinicDayRequest определяет структуру для добавления или изменения особого дня клиники.
+type ClinicDayRequest struct {
+	DayType     ClinicDayType `json:"day_type" binding:"required,oneof=выходной сокращенный" example:"сокращенный"`
+	WorkStart   *string       `json:"work_start" example:"09:00"`
+	WorkEnd     *string       `json:"work_end" example:"14:00"`
+	Description string        `json:"description" example:"Предпраздничный день"`
+}
+
+// DoctorAbsenceRequest определяет структуру для регистрации отсутствия врача.
+type DoctorAbsenceRequest struct {
+	DoctorID  uint      `json:"doctor_id" binding:"required" example:"1"`
+	DateFrom  time.Time `json:"date_from" binding:"required" example:"2025-09-01T00:00:00Z"`
+	DateTo    time.Time `json:"date_to" binding:"required" example:"2025-09-14T00:00:00Z"`
+	StartTime *string   `json:"start_time" example:"09:00"`
+	EndTime   *string   `json:"end_time" example:"12:00"`
+	Reason    string    `json:"reason" binding:"required,oneof=отпуск больничный командировка другое" example:"отпуск"`
+	Comment   string    `json:"comment"`
+}
+
+// ClinicDayResult содержит сохраненный особый день и записи пациентов, попавшие в нерабочее время.
+type ClinicDayResult struct {
+	Day          ClinicDay             `json:"day"`
+	Appointments []AffectedAppointment `json:"appointments"`
+}
+
+// DoctorAbsenceResult содержит созданное отсутствие и записи пациентов, попавшие в этот период.
+type DoctorAbsenceResult struct {
+	Absence      DoctorAbsence         `json:"absence"`
+	Appointments []AffectedAppointment `json:"appointments"`
+}
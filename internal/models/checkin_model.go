@@ -0,0 +1,82 @@
+package models
+
+// CheckInStatus определяет результат шага регистрации явки на терминале.
+type CheckInStatus string
+
+// Результаты шага регистрации явки.
+const (
+	// CheckInConfirmBirthDate - пациент найден, терминал должен запросить дату рождения.
+	CheckInConfirmBirthDate CheckInStatus = "confirm_birth_date"
+	// CheckInSelectPatient - под данные подходят несколько пациентов, терминал должен предложить выбор.
+	CheckInSelectPatient CheckInStatus = "select_patient"
+	// CheckInSelectAppointments - у пациента несколько записей, доступных для регистрации, терминал должен предложить выбор.
+	CheckInSelectAppointments CheckInStatus = "select_appointments"
+	// CheckInCompleted - явка зарегистрирована, талоны выданы.
+	CheckInCompleted CheckInStatus = "checked_in"
+)
+
+// CheckInByPhoneRequest определяет данные шага регистрации явки по номеру телефона.
+// Дата рождения, пациент и записи передаются на последующих шагах, если их запросил терминал.
+type CheckInByPhoneRequest struct {
+	Phone          string `json:"phone" binding:"required" example:"+7 (900) 123-45-67"`
+	BirthDate      string `json:"birth_date" example:"1985-04-12"`
+	PatientID      *uint  `json:"patient_id" example:"12"`
+	AppointmentIDs []uint `json:"appointment_ids"`
+}
+
+// CheckInByOMSRequest определяет данные регистрации явки по номеру полиса ОМС.
+type CheckInByOMSRequest struct {
+	OmsNumber      string `json:"oms_number" binding:"required" example:"1234567890123456"`
+	AppointmentIDs []uint `json:"appointment_ids"`
+}
+
+// CheckInByPassportRequest определяет данные регистрации явки по паспорту.
+type CheckInByPassportRequest struct {
+	PassportSeries string `json:"passport_series" binding:"required,len=4" example:"4510"`
+	PassportNumber string `json:"passport_number" binding:"required,len=6" example:"123456"`
+	AppointmentIDs []uint `json:"appointment_ids"`
+}
+
+// CheckInByCodeRequest определяет данные регистрации явки по коду подтверждения самостоятельной записи.
+type CheckInByCodeRequest struct {
+	ConfirmationCode string `json:"confirmation_code" binding:"required" example:"K7M2QX9P"`
+}
+
+// CheckInPatientCandidate - пациент, среди которых терминал предлагает выбор. Полное имя не раскрывается.
+type CheckInPatientCandidate struct {
+	ID        uint   `json:"id"`
+	ShortName string `json:"short_name" example:"Иванов И. П."`
+}
+
+// CheckInAppointmentCandidate - запись пациента на сегодня с признаком доступности регистрации.
+type CheckInAppointmentCandidate struct {
+	AppointmentID  uint   `json:"appointment_id"`
+	DoctorName     string `json:"doctor_name"`
+	Specialization string `json:"specialization"`
+	StartTime      string `json:"start_time"`
+	Cabinet        *int   `json:"cabinet,omitempty"`
+	Available      bool   `json:"available"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// CheckInTicket - талон, выданный на одну из зарегистрированных записей.
+type CheckInTicket struct {
+	AppointmentID uint   `json:"appointment_id"`
+	TicketNumber  string `json:"ticket_number" example:"B004"`
+	ServiceName   string `json:"service_name"`
+	DoctorName    string `json:"doctor_name"`
+	StartTime     string `json:"start_time"`
+	Cabinet       *int   `json:"cabinet,omitempty"`
+}
+
+// CheckInResult содержит результат шага регистрации явки: запрос уточнения или выданные талоны.
+// Персональные данные маскируются: пациент указывается только фамилией с инициалами.
+type CheckInResult struct {
+	Status       CheckInStatus                 `json:"status"`
+	Message      string                        `json:"message"`
+	PatientName  string                        `json:"patient_name,omitempty" example:"Иванов И. П."`
+	Patients     []CheckInPatientCandidate     `json:"patients,omitempty"`
+	Appointments []CheckInAppointmentCandidate `json:"appointments,omitempty"`
+	Tickets      []CheckInTicket               `json:"tickets,omitempty"`
+	Timeout      int                           `json:"timeout" example:"10"`
+}
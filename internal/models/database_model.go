@@ -1,10 +1,31 @@
 package models
 
 // GetDataRequest определяет тело запроса для получения данных.
+// Если передан Cursor, используется keyset-пагинация и поле Page игнорируется.
 type GetDataRequest struct {
-	Page    int     `json:"page"`
-	Limit   int     `json:"limit"`
-	Filters Filters `json:"filters"`
+	Page    int         `json:"page"`
+	Limit   int         `json:"limit"`
+	Fields  []string    `json:"fields,omitempty"`
+	Sort    []SortField `json:"sort,omitempty"`
+	Cursor  string      `json:"cursor,omitempty"`
+	Filters Filters     `json:"filters"`
+}
+
+// SortField описывает сортировку по одному столбцу.
+type SortField struct {
+	Field     string `json:"field"`
+	Direction string `json:"direction" example:"asc"` // asc | desc
+}
+
+// DataQuery - провалидированный запрос выборки, передаваемый в репозиторий.
+// After содержит значения ключей сортировки последней строки предыдущей страницы.
+type DataQuery struct {
+	Columns []string
+	Sort    []SortField
+	Filters Filters
+	Page    int
+	Limit   int
+	After   []interface{}
 }
 
 // Поле Data может содержать один объект (map[string]interface{}) или массив объектов.
@@ -21,15 +42,94 @@ type DeleteRequest struct {
 	Filters Filters `json:"filters" binding:"required"`
 }
 
-// Filters содержит логический оператор и список условий для фильтрации.
+// Filters содержит логический оператор, список условий и вложенные группы условий.
+// Группы объединяются с условиями тем же логическим оператором, что позволяет
+// строить выражения вида (a OR b) AND (c OR d).
 type Filters struct {
 	LogicalOperator string            `json:"logical_operator"`
 	Conditions      []FilterCondition `json:"conditions"`
+	Groups          []Filters         `json:"groups,omitempty"`
+}
+
+// IsEmpty сообщает, что фильтр не содержит ни условий, ни непустых групп.
+func (f Filters) IsEmpty() bool {
+	if len(f.Conditions) > 0 {
+		return false
+	}
+	for _, g := range f.Groups {
+		if !g.IsEmpty() {
+			return false
+		}
+	}
+	return true
 }
 
 // FilterCondition описывает одно условие фильтрации.
+// Для BETWEEN значение - массив из двух элементов, для IN и NOT IN - массив,
+// для IS NULL и IS NOT NULL значение не передается.
 type FilterCondition struct {
 	Field    string      `json:"field"`
 	Operator string      `json:"operator"`
 	Value    interface{} `json:"value"`
 }
+
+// Операции внешнего API базы данных.
+const (
+	OperationSelect = "select"
+	OperationInsert = "insert"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// TablePolicy описывает, что разрешено делать с таблицей через внешний API базы данных.
+// Пустой список ReadableColumns означает "все столбцы таблицы", а пустой WritableColumns - что
+// записывать нельзя ни один столбец: разрешенные для записи столбцы всегда перечисляются явно.
+type TablePolicy struct {
+	Operations      []string `json:"operations"`
+	ReadableColumns []string `json:"readable_columns,omitempty"`
+	WritableColumns []string `json:"writable_columns,omitempty"`
+	HiddenColumns   []string `json:"-"`
+	MaskedColumns   []string `json:"masked_columns,omitempty"`
+}
+
+// Allows проверяет, разрешена ли операция для таблицы.
+func (p TablePolicy) Allows(operation string) bool {
+	for _, op := range p.Operations {
+		if op == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// TableSummary - краткое описание таблицы, доступной через внешний API базы данных.
+type TableSummary struct {
+	Name       string   `json:"name" example:"tickets"`
+	Operations []string `json:"operations"`
+}
+
+// TableSchema описывает структуру таблицы для внешних интеграций.
+type TableSchema struct {
+	Name       string         `json:"name" example:"tickets"`
+	Operations []string       `json:"operations"`
+	Columns    []ColumnSchema `json:"columns"`
+}
+
+// ColumnSchema описывает столбец таблицы по данным information_schema
+// и ограничения, наложенные на него политикой API.
+type ColumnSchema struct {
+	Name         string         `json:"name" example:"ticket_id"`
+	DataType     string         `json:"data_type" example:"integer"`
+	IsNullable   bool           `json:"is_nullable"`
+	Default      *string        `json:"default,omitempty"`
+	IsPrimaryKey bool           `json:"is_primary_key"`
+	References   *ForeignKeyRef `json:"references,omitempty"`
+	Writable     bool           `json:"writable"`
+	Masked       bool           `json:"masked"`
+}
+
+// ForeignKeyRef указывает столбец, на который ссылается внешний ключ.
+type ForeignKeyRef struct {
+	Table  string `json:"table" example:"doctors"`
+	Column string `json:"column" example:"doctor_id"`
+}
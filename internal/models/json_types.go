@@ -0,0 +1,145 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringList хранит список строк в JSONB-столбце.
+type StringList []string
+
+// Value сериализует список в JSON для записи в БД.
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return jsonValue([]string(l))
+}
+
+// Scan десериализует список из JSON, прочитанного из БД.
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = StringList{}
+		return nil
+	}
+	return jsonScan(value, (*[]string)(l), "StringList")
+}
+
+// IntList хранит список целых чисел в JSONB-столбце.
+type IntList []int
+
+// Value сериализует список в JSON для записи в БД.
+func (l IntList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return jsonValue([]int(l))
+}
+
+// Scan десериализует список из JSON, прочитанного из БД.
+func (l *IntList) Scan(value interface{}) error {
+	if value == nil {
+		*l = IntList{}
+		return nil
+	}
+	return jsonScan(value, (*[]int)(l), "IntList")
+}
+
+// TimeRange - интервал времени внутри дня в формате "HH:MM".
+type TimeRange struct {
+	Start string `json:"start" example:"13:00"`
+	End   string `json:"end" example:"14:00"`
+}
+
+// TimeRangeList хранит список интервалов времени в JSONB-столбце.
+type TimeRangeList []TimeRange
+
+// Value сериализует список в JSON для записи в БД.
+func (l TimeRangeList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return jsonValue([]TimeRange(l))
+}
+
+// Scan десериализует список из JSON, прочитанного из БД.
+func (l *TimeRangeList) Scan(value interface{}) error {
+	if value == nil {
+		*l = TimeRangeList{}
+		return nil
+	}
+	return jsonScan(value, (*[]TimeRange)(l), "TimeRangeList")
+}
+
+// PatientSnapshot хранит копию карточки пациента в JSONB-столбце. Паспорт и полис ОМС сохраняются маскированными.
+type PatientSnapshot Patient
+
+// Value сериализует карточку в JSON для записи в БД.
+func (p PatientSnapshot) Value() (driver.Value, error) {
+	return jsonValue(Patient(p))
+}
+
+// Scan десериализует карточку из JSON, прочитанного из БД.
+func (p *PatientSnapshot) Scan(value interface{}) error {
+	if value == nil {
+		*p = PatientSnapshot{}
+		return nil
+	}
+	return jsonScan(value, (*Patient)(p), "PatientSnapshot")
+}
+
+// JSONDocument хранит произвольный JSON-документ в JSONB-столбце. Пустой документ сохраняется как NULL.
+type JSONDocument json.RawMessage
+
+// Value возвращает документ для записи в БД.
+func (d JSONDocument) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+// Scan копирует документ, прочитанный из БД.
+func (d *JSONDocument) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = nil
+	case []byte:
+		*d = append(JSONDocument(nil), v...)
+	case string:
+		*d = JSONDocument(v)
+	default:
+		return fmt.Errorf("unsupported type for JSONDocument: %T", value)
+	}
+	return nil
+}
+
+// MarshalJSON выводит документ без изменений.
+func (d JSONDocument) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	return d, nil
+}
+
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func jsonScan(value interface{}, dest interface{}, typeName string) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for %s: %T", typeName, value)
+	}
+	return json.Unmarshal(data, dest)
+}
@@ -1,18 +1,217 @@
 package models
 
 import (
+	"ElectronicQueue/internal/utils"
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Домены слепых индексов персональных данных.
+const (
+	passportIndexDomain = "passport"
+	omsIndexDomain      = "oms"
 )
 
 // Patient представляет собой модель пациента в базе данных.
+// Паспорт и полис ОМС хранятся зашифрованными (столбцы *_enc) вместе со слепыми индексами для поиска;
+// в памяти поля PassportSeries, PassportNumber и OmsNumber содержат расшифрованные значения.
+// При сериализации в JSON эти поля маскируются, полные значения выдаются только через PatientIdentifiers.
 type Patient struct {
-	ID             uint      `gorm:"primaryKey;autoIncrement;column:patient_id" json:"id"`
-	PassportSeries string    `gorm:"type:varchar(4);not null;uniqueIndex:idx_passport;column:passport_series" json:"passport_series"`
-	PassportNumber string    `gorm:"type:varchar(6);not null;uniqueIndex:idx_passport;column:passport_number" json:"passport_number"`
-	FullName       string    `gorm:"type:varchar(100);not null;column:full_name" json:"full_name"`
-	BirthDate      time.Time `gorm:"type:date;column:birth_date" json:"birth_date"`
-	Phone          string    `gorm:"type:varchar(20)" json:"phone"`
-	OmsNumber      string    `gorm:"type:varchar(16);not null;column:oms_number" json:"oms_number"`
+	ID             uint       `gorm:"primaryKey;autoIncrement;column:patient_id" json:"id"`
+	PassportSeries string     `gorm:"-" json:"passport_series"`
+	PassportNumber string     `gorm:"-" json:"passport_number"`
+	FullName       string     `gorm:"type:varchar(100);not null;column:full_name" json:"full_name"`
+	BirthDate      time.Time  `gorm:"type:date;column:birth_date" json:"birth_date"`
+	Phone          string     `gorm:"type:varchar(20)" json:"phone"`
+	OmsNumber      string     `gorm:"-" json:"oms_number"`
+	DeletedAt      *time.Time `gorm:"column:deleted_at" json:"deleted_at,omitempty"`
+	DeletedBy      *string    `gorm:"column:deleted_by" json:"deleted_by,omitempty"`
+	DeleteReason   *string    `gorm:"column:delete_reason" json:"delete_reason,omitempty"`
+	MergedIntoID   *uint      `gorm:"column:merged_into_id" json:"merged_into_id,omitempty"`
+
+	PassportSeriesEnc *string `gorm:"column:passport_series_enc" json:"-"`
+	PassportNumberEnc *string `gorm:"column:passport_number_enc" json:"-"`
+	OmsNumberEnc      *string `gorm:"column:oms_number_enc" json:"-"`
+	PassportIndex     *string `gorm:"column:passport_index" json:"-"`
+	OmsIndex          *string `gorm:"column:oms_index" json:"-"`
+
+	// Открытые значения, сохраненные до включения шифрования. Очищаются при первом сохранении карточки.
+	LegacyPassportSeries *string `gorm:"column:passport_series" json:"-"`
+	LegacyPassportNumber *string `gorm:"column:passport_number" json:"-"`
+	LegacyOmsNumber      *string `gorm:"column:oms_number" json:"-"`
+}
+
+// piiCipher шифрует персональные данные пациентов. Устанавливается при запуске через SetPIICipher.
+var piiCipher *utils.PIICipher
+
+// SetPIICipher задает шифратор персональных данных пациентов.
+func SetPIICipher(c *utils.PIICipher) {
+	piiCipher = c
+}
+
+// PassportBlindIndex вычисляет слепой индекс паспорта для поиска по серии и номеру.
+func PassportBlindIndex(series, number string) (string, error) {
+	if piiCipher == nil {
+		return "", fmt.Errorf("шифрование персональных данных не настроено")
+	}
+	return piiCipher.BlindIndex(passportIndexDomain, series+number), nil
+}
+
+// OMSBlindIndex вычисляет слепой индекс полиса ОМС для поиска по номеру.
+func OMSBlindIndex(omsNumber string) (string, error) {
+	if piiCipher == nil {
+		return "", fmt.Errorf("шифрование персональных данных не настроено")
+	}
+	return piiCipher.BlindIndex(omsIndexDomain, omsNumber), nil
+}
+
+// PIIActiveKeyID возвращает идентификатор активного ключа шифрования персональных данных.
+func PIIActiveKeyID() (string, error) {
+	if piiCipher == nil {
+		return "", fmt.Errorf("шифрование персональных данных не настроено")
+	}
+	return piiCipher.ActiveKeyID(), nil
+}
+
+// BeforeSave шифрует паспорт и полис ОМС активным ключом, обновляет слепые индексы
+// и очищает открытые значения, сохраненные до включения шифрования.
+func (p *Patient) BeforeSave(tx *gorm.DB) error {
+	if p.PassportSeries == "" && p.PassportNumber == "" && p.OmsNumber == "" {
+		return nil
+	}
+	return p.encryptDocuments()
+}
+
+// encryptDocuments шифрует документы активным ключом и очищает открытые столбцы.
+func (p *Patient) encryptDocuments() error {
+	if piiCipher == nil {
+		return fmt.Errorf("шифрование персональных данных не настроено")
+	}
+	for _, field := range []struct {
+		plain string
+		enc   **string
+	}{
+		{p.PassportSeries, &p.PassportSeriesEnc},
+		{p.PassportNumber, &p.PassportNumberEnc},
+		{p.OmsNumber, &p.OmsNumberEnc},
+	} {
+		enc, err := piiCipher.Encrypt(field.plain)
+		if err != nil {
+			return fmt.Errorf("не удалось зашифровать персональные данные: %w", err)
+		}
+		*field.enc = &enc
+	}
+	passportIndex := piiCipher.BlindIndex(passportIndexDomain, p.PassportSeries+p.PassportNumber)
+	omsIndex := piiCipher.BlindIndex(omsIndexDomain, p.OmsNumber)
+	p.PassportIndex, p.OmsIndex = &passportIndex, &omsIndex
+	p.LegacyPassportSeries, p.LegacyPassportNumber, p.LegacyOmsNumber = nil, nil, nil
+	return nil
+}
+
+// EncryptedColumns шифрует документы активным ключом и возвращает значения зашифрованных столбцов,
+// слепых индексов и очищенных открытых столбцов для точечного обновления карточки.
+// Пустые документы тоже шифруются, чтобы карточка больше не считалась ожидающей перешифрования.
+func (p *Patient) EncryptedColumns() (map[string]interface{}, error) {
+	if err := p.encryptDocuments(); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"passport_series_enc": p.PassportSeriesEnc,
+		"passport_number_enc": p.PassportNumberEnc,
+		"oms_number_enc":      p.OmsNumberEnc,
+		"passport_index":      p.PassportIndex,
+		"oms_index":           p.OmsIndex,
+		"passport_series":     p.LegacyPassportSeries,
+		"passport_number":     p.LegacyPassportNumber,
+		"oms_number":          p.LegacyOmsNumber,
+	}, nil
+}
+
+// AfterFind расшифровывает паспорт и полис ОМС. Если карточка еще не зашифрована,
+// используются открытые значения, сохраненные до включения шифрования.
+func (p *Patient) AfterFind(tx *gorm.DB) error {
+	for _, field := range []struct {
+		enc    *string
+		legacy *string
+		plain  *string
+	}{
+		{p.PassportSeriesEnc, p.LegacyPassportSeries, &p.PassportSeries},
+		{p.PassportNumberEnc, p.LegacyPassportNumber, &p.PassportNumber},
+		{p.OmsNumberEnc, p.LegacyOmsNumber, &p.OmsNumber},
+	} {
+		switch {
+		case field.enc != nil:
+			if piiCipher == nil {
+				return fmt.Errorf("шифрование персональных данных не настроено")
+			}
+			plain, err := piiCipher.Decrypt(*field.enc)
+			if err != nil {
+				return fmt.Errorf("не удалось расшифровать персональные данные пациента %d: %w", p.ID, err)
+			}
+			*field.plain = plain
+		case field.legacy != nil:
+			*field.plain = *field.legacy
+		}
+	}
+	return nil
+}
+
+// MarshalJSON сериализует карточку с маскированными паспортом и полисом ОМС.
+func (p Patient) MarshalJSON() ([]byte, error) {
+	type patientJSON Patient
+	masked := patientJSON(p)
+	masked.PassportSeries = utils.MaskString(p.PassportSeries, 0)
+	masked.PassportNumber = utils.MaskString(p.PassportNumber, 2)
+	masked.OmsNumber = utils.MaskString(p.OmsNumber, 4)
+	return json.Marshal(masked)
+}
+
+// Identifiers возвращает полные значения документов пациента.
+func (p *Patient) Identifiers() PatientIdentifiers {
+	return PatientIdentifiers{
+		PatientID:      p.ID,
+		PassportSeries: p.PassportSeries,
+		PassportNumber: p.PassportNumber,
+		OmsNumber:      p.OmsNumber,
+	}
+}
+
+// PatientIdentifiers - полные значения документов пациента. Выдаются только через раскрытие с записью в журнал доступа.
+type PatientIdentifiers struct {
+	PatientID      uint   `json:"patient_id"`
+	PassportSeries string `json:"passport_series"`
+	PassportNumber string `json:"passport_number"`
+	OmsNumber      string `json:"oms_number"`
+}
+
+// PIIAccessLog - запись журнала раскрытия полных персональных данных пациента.
+type PIIAccessLog struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement;column:access_id" json:"id"`
+	PatientID uint       `gorm:"not null;column:patient_id" json:"patient_id"`
+	Actor     string     `gorm:"not null;column:actor" json:"actor"`
+	Fields    StringList `gorm:"type:jsonb;not null;column:fields" json:"fields"`
+	Reason    string     `gorm:"not null;column:reason" json:"reason"`
+	ClientIP  string     `gorm:"column:client_ip" json:"client_ip,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName задает имя таблицы журнала раскрытия персональных данных.
+func (PIIAccessLog) TableName() string {
+	return "pii_access_logs"
+}
+
+// RevealPatientIdentifiersRequest определяет структуру запроса на раскрытие документов пациента.
+type RevealPatientIdentifiersRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Оформление направления на госпитализацию"`
+}
+
+// PIIReencryptionResult - итог перешифрования карточек пациентов активным ключом.
+type PIIReencryptionResult struct {
+	ActiveKeyID string `json:"active_key_id"`
+	Processed   int    `json:"processed"`
 }
 
 // PatientResponse определяет данные, возвращаемые API.
@@ -43,3 +242,82 @@ type UpdatePatientRequest struct {
 	Phone          string     `json:"phone,omitempty"`
 	OmsNumber      string     `json:"oms_number,omitempty" binding:"omitempty,len=16"`
 }
+
+// PatientSearchQuery - разобранный поисковый запрос по пациентам, передаваемый в репозиторий.
+// Из полей Text, Surname и Digits заполняется не больше одного; фильтры применяются всегда.
+type PatientSearchQuery struct {
+	// Text - нормализованное ФИО или его часть, ищется с учетом опечаток.
+	Text string
+	// Surname, FirstInitial и MiddleInitial заполняются для запроса вида "Фамилия И.О.".
+	Surname       string
+	FirstInitial  string
+	MiddleInitial string
+	// Digits - цифры номера полиса ОМС, паспорта или телефона. Полис и паспорт зашифрованы и ищутся
+	// только по точному совпадению, телефон - по вхождению.
+	Digits    string
+	BirthDate *time.Time
+	Phone     string
+	Page      int
+	Limit     int
+}
+
+// PatientSearchHit - найденный пациент с оценкой релевантности от 0 до 1.
+type PatientSearchHit struct {
+	Patient `gorm:"embedded"`
+	Score   float64 `gorm:"column:score" json:"score"`
+}
+
+// MarshalJSON сериализует маскированную карточку вместе с оценкой релевантности.
+// Без него встроенный Patient.MarshalJSON отбросил бы поле score.
+func (h PatientSearchHit) MarshalJSON() ([]byte, error) {
+	patient, err := json.Marshal(h.Patient)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(patient, &fields); err != nil {
+		return nil, err
+	}
+	fields["score"] = h.Score
+	return json.Marshal(fields)
+}
+
+// PatientSearchResult - страница результатов поиска пациентов, упорядоченных по релевантности.
+type PatientSearchResult struct {
+	Page  int                `json:"page"`
+	Limit int                `json:"limit"`
+	Total int64              `json:"total"`
+	Data  []PatientSearchHit `json:"data"`
+}
+
+// DeletePatientRequest определяет структуру для удаления карточки пациента.
+type DeletePatientRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Карточка заведена ошибочно"`
+}
+
+// MergePatientsRequest определяет структуру для объединения дубликата с основной карточкой пациента.
+// Записи, лист ожидания и направления дубликата переносятся в основную карточку, дубликат удаляется.
+type MergePatientsRequest struct {
+	SourcePatientID uint   `json:"source_patient_id" binding:"required" example:"57"`
+	Reason          string `json:"reason" example:"Дубликат с ошибкой в номере паспорта"`
+}
+
+// PatientDuplicate - карточка, похожая на проверяемую, с причинами совпадения.
+type PatientDuplicate struct {
+	Patient Patient  `json:"patient"`
+	Reasons []string `json:"reasons"`
+}
+
+// PatientMerge - журнал объединения карточек пациентов.
+type PatientMerge struct {
+	ID                   uint            `gorm:"primaryKey;autoIncrement;column:merge_id" json:"id"`
+	SourcePatientID      uint            `gorm:"not null;column:source_patient_id" json:"source_patient_id"`
+	TargetPatientID      uint            `gorm:"not null;column:target_patient_id" json:"target_patient_id"`
+	Reason               string          `gorm:"column:reason" json:"reason,omitempty"`
+	MergedBy             string          `gorm:"column:merged_by" json:"merged_by"`
+	MovedAppointments    int             `gorm:"not null;column:moved_appointments" json:"moved_appointments"`
+	MovedWaitlistEntries int             `gorm:"not null;column:moved_waitlist_entries" json:"moved_waitlist_entries"`
+	MovedReferrals       int             `gorm:"not null;column:moved_referrals" json:"moved_referrals"`
+	SourceSnapshot       PatientSnapshot `gorm:"type:jsonb;not null;column:source_snapshot" json:"source_snapshot"`
+	MergedAt             time.Time       `gorm:"column:merged_at;default:CURRENT_TIMESTAMP" json:"merged_at"`
+}
@@ -37,7 +37,129 @@ type CreateScheduleRequest struct {
 	Cabinet     *int      `json:"cabinet" example:"101"`
 }
 
-// UpdateScheduleRequest определяет структуру для обновления статуса слота (например, блокировка).
+// UpdateScheduleRequest определяет структуру для частичного изменения слота: блокировки, смены кабинета или времени.
+// ClearCabinet снимает привязку к кабинету. Перенос слота с записью пациента выполняется только при Force.
 type UpdateScheduleRequest struct {
-	IsAvailable *bool `json:"is_available" binding:"required"`
+	Date         *time.Time `json:"date,omitempty" example:"2025-07-20T00:00:00Z"`
+	StartTime    *string    `json:"start_time,omitempty" example:"09:30"`
+	EndTime      *string    `json:"end_time,omitempty" example:"10:00"`
+	Cabinet      *int       `json:"cabinet,omitempty" example:"102"`
+	ClearCabinet bool       `json:"clear_cabinet,omitempty" example:"false"`
+	IsAvailable  *bool      `json:"is_available,omitempty" example:"false"`
+	Force        bool       `json:"force,omitempty" example:"false"`
+}
+
+// UpdateScheduleResult содержит измененный слот и, если слот был занят, запись пациента, которой коснулось изменение.
+type UpdateScheduleResult struct {
+	Schedule    ScheduleResponse     `json:"schedule"`
+	Appointment *AffectedAppointment `json:"appointment,omitempty"`
+}
+
+// Действия, примененные к записи пациента при массовых операциях с расписанием.
+const (
+	AffectedActionMoved       = "перенесена"
+	AffectedActionNeedsCall   = "требуется_звонок"
+	AffectedActionTimeChanged = "время_изменено"
+)
+
+// CancelSchedulesRequest определяет параметры отмены приема врача за период.
+// StartTime и EndTime (ЧЧ:ММ) ограничивают отмену частью дня; без них отменяется весь день.
+type CancelSchedulesRequest struct {
+	DoctorID           uint      `json:"doctor_id" binding:"required" example:"1"`
+	DateFrom           time.Time `json:"date_from" binding:"required" example:"2025-09-01T00:00:00Z"`
+	DateTo             time.Time `json:"date_to" binding:"required" example:"2025-09-01T00:00:00Z"`
+	StartTime          *string   `json:"start_time" example:"09:00"`
+	EndTime            *string   `json:"end_time" example:"13:00"`
+	ReassignToDoctorID *uint     `json:"reassign_to_doctor_id" example:"2"`
+	AutoReassign       bool      `json:"auto_reassign" example:"false"`
+	DryRun             bool      `json:"dry_run" example:"true"`
+}
+
+// CopyWeekRequest определяет параметры копирования недели расписания вперед.
+// SourceWeekStart - любой день исходной недели; копируется неделя с понедельника по воскресенье.
+type CopyWeekRequest struct {
+	DoctorID        *uint     `json:"doctor_id" example:"1"`
+	SourceWeekStart time.Time `json:"source_week_start" binding:"required" example:"2025-09-01T00:00:00Z"`
+	TargetWeekStart time.Time `json:"target_week_start" binding:"required" example:"2025-09-08T00:00:00Z"`
+	Weeks           int       `json:"weeks" binding:"omitempty,min=1,max=12" example:"1"`
+	SkipConflicts   bool      `json:"skip_conflicts" example:"false"`
+}
+
+// ShiftSchedulesRequest определяет параметры сдвига слотов врача внутри дня.
+type ShiftSchedulesRequest struct {
+	DoctorID     uint      `json:"doctor_id" binding:"required" example:"1"`
+	Date         time.Time `json:"date" binding:"required" example:"2025-09-01T00:00:00Z"`
+	FromTime     *string   `json:"from_time" example:"14:00"`
+	ToTime       *string   `json:"to_time" example:"18:00"`
+	ShiftMinutes int       `json:"shift_minutes" binding:"required" example:"30"`
+	DryRun       bool      `json:"dry_run" example:"false"`
+}
+
+// AffectedAppointment описывает запись пациента, затронутую массовой операцией.
+type AffectedAppointment struct {
+	AppointmentID    uint    `json:"appointment_id"`
+	PatientID        uint    `json:"patient_id"`
+	PatientFullName  string  `json:"patient_full_name"`
+	PatientPhone     string  `json:"patient_phone"`
+	Date             string  `json:"date" example:"2025-09-01"`
+	StartTime        string  `json:"start_time" example:"09:00:00"`
+	Action           string  `json:"action" example:"требуется_звонок"`
+	NewDoctorID      *uint   `json:"new_doctor_id,omitempty"`
+	NewScheduleID    *uint   `json:"new_schedule_id,omitempty"`
+	NewStartTime     *string `json:"new_start_time,omitempty"`
+	NewAppointmentID *uint   `json:"new_appointment_id,omitempty"`
+}
+
+// CancelSchedulesResult содержит итоги отмены приема и список пациентов для обзвона.
+type CancelSchedulesResult struct {
+	DryRun         bool                  `json:"dry_run"`
+	DeletedSlots   int                   `json:"deleted_slots"`
+	BlockedSlots   int                   `json:"blocked_slots"`
+	MovedCount     int                   `json:"moved_count"`
+	NeedsCallCount int                   `json:"needs_call_count"`
+	Appointments   []AffectedAppointment `json:"appointments"`
+}
+
+// CopyWeekResult содержит итоги копирования недели.
+type CopyWeekResult struct {
+	Planned           int                `json:"planned"`
+	Created           int64              `json:"created"`
+	Skipped           int64              `json:"skipped"`
+	SkippedByCalendar int                `json:"skipped_by_calendar"`
+	Conflicts         []ScheduleConflict `json:"conflicts,omitempty"`
+}
+
+// ShiftSchedulesResult содержит итоги сдвига слотов.
+type ShiftSchedulesResult struct {
+	DryRun       bool                  `json:"dry_run"`
+	ShiftedSlots int                   `json:"shifted_slots"`
+	Appointments []AffectedAppointment `json:"appointments"`
+}
+
+// Типы конфликтов расписания.
+const (
+	ConflictInvalidRange  = "invalid_range"
+	ConflictDoctorOverlap = "doctor_overlap"
+	ConflictCabinetBusy   = "cabinet_busy"
+)
+
+// ScheduleConflict описывает конфликт проверяемого слота с правилами или с другим слотом.
+type ScheduleConflict struct {
+	Type            string            `json:"type" example:"doctor_overlap"`
+	Message         string            `json:"message"`
+	Slot            ScheduleResponse  `json:"slot"`
+	ConflictingSlot *ScheduleResponse `json:"conflicting_slot,omitempty"`
+}
+
+// ToResponse преобразует слот в объект ответа ScheduleResponse.
+func (s *Schedule) ToResponse() ScheduleResponse {
+	return ScheduleResponse{
+		ID:          s.ID,
+		DoctorID:    s.DoctorID,
+		Date:        s.Date,
+		StartTime:   s.StartTime,
+		EndTime:     s.EndTime,
+		IsAvailable: s.IsAvailable,
+		Cabinet:     s.Cabinet,
+	}
 }
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// ScheduleTemplate - недельный шаблон расписания врача, по которому генерируются слоты.
+// Weekdays содержит дни недели в формате ISO: 1 - понедельник, 7 - воскресенье.
+type ScheduleTemplate struct {
+	ID          uint          `gorm:"primaryKey;autoIncrement;column:template_id" json:"template_id"`
+	DoctorID    uint          `gorm:"not null;column:doctor_id" json:"doctor_id"`
+	Weekdays    IntList       `gorm:"type:jsonb;not null;column:weekdays" json:"weekdays"`
+	StartTime   string        `gorm:"type:time;not null;column:start_time" json:"start_time"`
+	EndTime     string        `gorm:"type:time;not null;column:end_time" json:"end_time"`
+	SlotMinutes int           `gorm:"not null;column:slot_minutes" json:"slot_minutes"`
+	Cabinet     *int          `gorm:"column:cabinet" json:"cabinet,omitempty"`
+	Breaks      TimeRangeList `gorm:"type:jsonb;not null;column:breaks" json:"breaks"`
+	ValidFrom   time.Time     `gorm:"type:date;not null;column:valid_from" json:"valid_from"`
+	ValidTo     *time.Time    `gorm:"type:date;column:valid_to" json:"valid_to,omitempty"`
+	IsActive    bool          `gorm:"not null;default:true;column:is_active" json:"is_active"`
+	CreatedAt   time.Time     `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time     `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// AppliesTo проверяет, действует ли шаблон в указанный день.
+func (t *ScheduleTemplate) AppliesTo(day time.Time) bool {
+	if !t.IsActive {
+		return false
+	}
+	d := dateOnly(day)
+	if d.Before(dateOnly(t.ValidFrom)) {
+		return false
+	}
+	if t.ValidTo != nil && d.After(dateOnly(*t.ValidTo)) {
+		return false
+	}
+	weekday := int(day.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	for _, wd := range t.Weekdays {
+		if wd == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ScheduleTemplateRequest определяет структуру для создания и изменения шаблона расписания.
+type ScheduleTemplateRequest struct {
+	DoctorID    uint        `json:"doctor_id" binding:"required" example:"1"`
+	Weekdays    []int       `json:"weekdays" binding:"required,min=1,dive,min=1,max=7" example:"1,2,3,4,5"`
+	StartTime   string      `json:"start_time" binding:"required" example:"09:00"`
+	EndTime     string      `json:"end_time" binding:"required" example:"17:00"`
+	SlotMinutes int         `json:"slot_minutes" binding:"required,min=5,max=480" example:"20"`
+	Cabinet     *int        `json:"cabinet" example:"101"`
+	Breaks      []TimeRange `json:"breaks"`
+	ValidFrom   time.Time   `json:"valid_from" binding:"required" example:"2025-09-01T00:00:00Z"`
+	ValidTo     *time.Time  `json:"valid_to" example:"2025-12-31T00:00:00Z"`
+	IsActive    *bool       `json:"is_active" example:"true"`
+}
+
+// GenerateSchedulesRequest определяет период генерации слотов по шаблонам.
+// Если TemplateID или DoctorID не указаны, используются все активные шаблоны.
+// При SkipConflicts конфликтующие слоты пропускаются, иначе генерация отклоняется целиком.
+type GenerateSchedulesRequest struct {
+	From          time.Time `json:"from" binding:"required" example:"2025-09-01T00:00:00Z"`
+	To            time.Time `json:"to" binding:"required" example:"2025-09-14T00:00:00Z"`
+	TemplateID    *uint     `json:"template_id" example:"1"`
+	DoctorID      *uint     `json:"doctor_id" example:"1"`
+	SkipConflicts bool      `json:"skip_conflicts" example:"false"`
+}
+
+// GenerateSchedulesResult содержит итоги генерации слотов.
+type GenerateSchedulesResult struct {
+	From    string `json:"from" example:"2025-09-01"`
+	To      string `json:"to" example:"2025-09-14"`
+	Planned int    `json:"planned"`
+	Created int64  `json:"created"`
+	Skipped int64  `json:"skipped"`
+
+	// SkippedByCalendar - число слотов, не созданных из-за нерабочих дней клиники и отсутствий врачей.
+	SkippedByCalendar int                `json:"skipped_by_calendar"`
+	Conflicts         []ScheduleConflict `json:"conflicts,omitempty"`
+}
@@ -13,8 +13,28 @@ const (
 	StatusInProgress TicketStatus = "на_приеме"
 	StatusCompleted  TicketStatus = "завершен"
 	StatusRegistered TicketStatus = "зарегистрирован"
+	// StatusInvitedToCabinet - врач пригласил пациента в кабинет, прием еще не начат.
+	StatusInvitedToCabinet TicketStatus = "приглашен_в_кабинет"
 )
 
+// CabinetInviteEvent - имя события брокера о приглашении пациента в кабинет врача.
+const CabinetInviteEvent = "cabinet_invite"
+
+// CabinetInviteNotification - уведомление табло кабинета о приглашении пациента.
+// Recall означает повторный вызов уже приглашенного пациента. AudioURL указывает на озвучку приглашения;
+// он не передается, если на сервере нет записей, нужных для озвучки номера кабинета.
+type CabinetInviteNotification struct {
+	Event      string    `json:"event"`
+	TicketID   uint      `json:"ticket_id"`
+	Ticket     string    `json:"ticket"`
+	Cabinet    int       `json:"cabinet"`
+	DoctorID   uint      `json:"doctor_id"`
+	DoctorName string    `json:"doctor_name"`
+	Recall     bool      `json:"recall"`
+	InvitedAt  time.Time `json:"invited_at"`
+	AudioURL   string    `json:"audio_url,omitempty"`
+}
+
 // Ticket представляет собой модель талона электронной очереди.
 type Ticket struct {
 	ID           uint         `gorm:"primaryKey;autoIncrement;column:ticket_id" json:"id"`
@@ -25,6 +45,7 @@ type Ticket struct {
 	QRCode       []byte       `gorm:"column:qr_code" json:"qr_code,omitempty"`
 	CreatedAt    time.Time    `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
 	CalledAt     *time.Time   `gorm:"column:called_at" json:"called_at,omitempty"`
+	InvitedAt    *time.Time   `gorm:"column:invited_at" json:"invited_at,omitempty"`
 	StartedAt    *time.Time   `gorm:"column:started_at" json:"started_at,omitempty"`
 	CompletedAt  *time.Time   `gorm:"column:completed_at" json:"completed_at,omitempty"`
 }
@@ -39,6 +60,7 @@ type TicketResponse struct {
 	QRCode       []byte       `json:"qr_code,omitempty"`
 	CreatedAt    time.Time    `json:"created_at"`
 	CalledAt     *time.Time   `json:"called_at,omitempty"`
+	InvitedAt    *time.Time   `json:"invited_at,omitempty"`
 	StartedAt    *time.Time   `json:"started_at,omitempty"`
 	CompletedAt  *time.Time   `json:"completed_at,omitempty"`
 }
@@ -82,6 +104,7 @@ func (t *Ticket) ToResponse() TicketResponse {
 		QRCode:       t.QRCode,
 		CreatedAt:    t.CreatedAt,
 		CalledAt:     t.CalledAt,
+		InvitedAt:    t.InvitedAt,
 		StartedAt:    t.StartedAt,
 		CompletedAt:  t.CompletedAt,
 	}
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// WaitlistStatus определяет статус заявки в листе ожидания.
+type WaitlistStatus string
+
+// Статусы заявки в листе ожидания.
+const (
+	WaitlistWaiting   WaitlistStatus = "ожидает"
+	WaitlistOffered   WaitlistStatus = "предложено"
+	WaitlistBooked    WaitlistStatus = "записан"
+	WaitlistCancelled WaitlistStatus = "отменено"
+)
+
+// WaitlistOfferStatus определяет статус предложения освободившегося слота.
+type WaitlistOfferStatus string
+
+// Статусы предложения слота пациенту из листа ожидания.
+const (
+	OfferPending  WaitlistOfferStatus = "предложено"
+	OfferAccepted WaitlistOfferStatus = "принято"
+	OfferDeclined WaitlistOfferStatus = "отклонено"
+	OfferExpired  WaitlistOfferStatus = "истекло"
+)
+
+// WaitlistOfferEvent - имя события брокера о предложении слота из листа ожидания.
+const WaitlistOfferEvent = "waitlist_offer"
+
+// WaitlistEntry - заявка пациента на более раннюю запись к врачу или к любому врачу специальности.
+// Должен быть задан DoctorID или Specialization; период [DateFrom, DateTo] задает желаемые даты приема.
+type WaitlistEntry struct {
+	ID             uint           `gorm:"primaryKey;autoIncrement;column:entry_id" json:"entry_id"`
+	PatientID      uint           `gorm:"not null;column:patient_id" json:"patient_id"`
+	DoctorID       *uint          `gorm:"column:doctor_id" json:"doctor_id,omitempty"`
+	Specialization *string        `gorm:"type:varchar(100);column:specialization" json:"specialization,omitempty" example:"Терапевт"`
+	DateFrom       time.Time      `gorm:"type:date;not null;column:date_from" json:"date_from"`
+	DateTo         time.Time      `gorm:"type:date;not null;column:date_to" json:"date_to"`
+	Status         WaitlistStatus `gorm:"type:varchar(20);not null;default:ожидает;column:status" json:"status" example:"ожидает"`
+	Comment        string         `gorm:"column:comment" json:"comment,omitempty"`
+	CreatedBy      string         `gorm:"type:varchar(50);column:created_by" json:"created_by,omitempty"`
+	CreatedAt      time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"column:updated_at" json:"updated_at"`
+	Patient        Patient        `gorm:"foreignKey:PatientID" json:"patient,omitempty"`
+	Doctor         *Doctor        `gorm:"foreignKey:DoctorID" json:"doctor,omitempty"`
+}
+
+// WaitlistOffer - предложение освободившегося слота пациенту из листа ожидания.
+// Пока предложение действует (до HeldUntil), слот удерживается и недоступен для обычной записи.
+type WaitlistOffer struct {
+	ID            uint                `gorm:"primaryKey;autoIncrement;column:offer_id" json:"offer_id"`
+	EntryID       uint                `gorm:"not null;column:entry_id" json:"entry_id"`
+	ScheduleID    uint                `gorm:"not null;column:schedule_id" json:"schedule_id"`
+	Status        WaitlistOfferStatus `gorm:"type:varchar(20);not null;default:предложено;column:status" json:"status" example:"предложено"`
+	HeldUntil     time.Time           `gorm:"not null;column:held_until" json:"held_until"`
+	AppointmentID *uint               `gorm:"column:appointment_id" json:"appointment_id,omitempty"`
+	CreatedAt     time.Time           `gorm:"column:created_at" json:"created_at"`
+	ResolvedAt    *time.Time          `gorm:"column:resolved_at" json:"resolved_at,omitempty"`
+	Entry         WaitlistEntry       `gorm:"foreignKey:EntryID" json:"entry,omitempty"`
+	Schedule      Schedule            `gorm:"foreignKey:ScheduleID" json:"schedule,omitempty"`
+}
+
+// CreateWaitlistEntryRequest определяет структуру для постановки пациента в лист ожидания.
+type CreateWaitlistEntryRequest struct {
+	PatientID      uint      `json:"patient_id" binding:"required" example:"1"`
+	DoctorID       *uint     `json:"doctor_id" example:"3"`
+	Specialization *string   `json:"specialization" example:"Терапевт"`
+	DateFrom       time.Time `json:"date_from" binding:"required" example:"2025-03-01T00:00:00Z"`
+	DateTo         time.Time `json:"date_to" binding:"required" example:"2025-03-14T00:00:00Z"`
+	Comment        string    `json:"comment" example:"Готов прийти в любое время"`
+}
+
+// AcceptOfferRequest определяет необязательные параметры принятия предложения слота.
+// OverrideReason позволяет записать пациента вопреки правилам записи, как при создании записи.
+type AcceptOfferRequest struct {
+	OverrideReason string `json:"override_reason" example:"Срочная консультация"`
+
+	// Заполняются сервисом: кто оформил запись и какие правила нарушены.
+	BookedBy        string   `json:"-"`
+	OverriddenBy    string   `json:"-"`
+	OverriddenRules []string `json:"-"`
+}
+
+// WaitlistOfferNotification - сообщение брокеру о новом или завершенном предложении слота.
+// Рассылается подписчикам потока предложений регистратуры.
+type WaitlistOfferNotification struct {
+	Event       string              `json:"event" example:"waitlist_offer"`
+	OfferID     uint                `json:"offer_id"`
+	EntryID     uint                `json:"entry_id"`
+	Status      WaitlistOfferStatus `json:"status"`
+	PatientID   uint                `json:"patient_id"`
+	PatientName string              `json:"patient_name"`
+	Phone       string              `json:"phone"`
+	ScheduleID  uint                `json:"schedule_id"`
+	DoctorID    uint                `json:"doctor_id"`
+	DoctorName  string              `json:"doctor_name"`
+	Date        string              `json:"date" example:"2025-03-03"`
+	StartTime   string              `json:"start_time" example:"10:30:00"`
+	Cabinet     *int                `json:"cabinet,omitempty"`
+	HeldUntil   time.Time           `json:"held_until"`
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"ElectronicQueue/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepo{db: db}
+}
+
+func (r *apiKeyRepo) Create(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepo) Update(key *models.APIKey) error {
+	return r.db.Save(key).Error
+}
+
+func (r *apiKeyRepo) GetAll() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.db.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepo) GetByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepo) FindByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// TouchLastUsed обновляет время последнего использования ключа без изменения остальных полей.
+func (r *apiKeyRepo) TouchLastUsed(id uint, usedAt time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("api_key_id = ?", id).Update("last_used_at", usedAt).Error
+}
@@ -20,37 +20,81 @@ func NewAppointmentRepository(db *gorm.DB) AppointmentRepository {
 
 // CreateAppointmentInTransaction создает запись и блокирует слот в рамках одной транзакции.
 func (r *appointmentRepo) CreateAppointmentInTransaction(req *models.CreateAppointmentRequest) (*models.Appointment, error) {
-	var appointment models.Appointment
+	appointment := models.Appointment{
+		ScheduleID: req.ScheduleID,
+		PatientID:  req.PatientID,
+		TicketID:   req.TicketID,
+		Status:     models.AppointmentBooked,
+	}
+	if req.OverrideReason != "" {
+		appointment.OverrideReason = &req.OverrideReason
+		appointment.OverriddenBy = &req.OverriddenBy
+		appointment.OverriddenRules = req.OverriddenRules
+	}
+	if req.BookedBy != "" {
+		appointment.BookedBy = &req.BookedBy
+	}
+	if req.BookingKind != "" {
+		appointment.BookingKind = &req.BookingKind
+		appointment.SourceAppointmentID = req.SourceAppointmentID
+	}
 	err := r.db.Transaction(func(tx *gorm.DB) error {
-		var schedule models.Schedule
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, req.ScheduleID).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("указанный слот в расписании не найден")
-			}
-			return err
-		}
+		return reserveSlot(tx, &appointment)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		if !schedule.IsAvailable {
-			return errors.New("выбранное время уже занято")
-		}
+	if err := r.db.Preload("Patient").Preload("Schedule.Doctor").First(&appointment, appointment.ID).Error; err != nil {
+		return nil, err
+	}
 
-		appointment = models.Appointment{
-			ScheduleID: req.ScheduleID,
-			PatientID:  req.PatientID,
-			TicketID:   req.TicketID,
-		}
-		if err := tx.Create(&appointment).Error; err != nil {
+	return &appointment, nil
+}
+
+// CreateSelfBookingInTransaction создает запись, оформленную пациентом самостоятельно, с кодом подтверждения.
+// Строка пациента блокируется, чтобы параллельные запросы не обошли ограничения на число записей.
+func (r *appointmentRepo) CreateSelfBookingInTransaction(req *models.CreateAppointmentRequest, confirmationCode string, limits models.BookingLimits) (*models.Appointment, error) {
+	appointment := models.Appointment{
+		ScheduleID:       req.ScheduleID,
+		PatientID:        req.PatientID,
+		Status:           models.AppointmentBooked,
+		ConfirmationCode: &confirmationCode,
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Patient{}, req.PatientID).Error; err != nil {
 			return err
 		}
 
-		schedule.IsAvailable = false
-		if err := tx.Save(&schedule).Error; err != nil {
-			return err
+		today := time.Now().Format("2006-01-02")
+		active := tx.Model(&models.Appointment{}).
+			Joins("JOIN schedules ON schedules.schedule_id = appointments.schedule_id").
+			Where("appointments.patient_id = ? AND appointments.status = ? AND schedules.date >= ?", req.PatientID, models.AppointmentBooked, today)
+
+		if limits.MaxActive > 0 {
+			var count int64
+			if err := active.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) >= limits.MaxActive {
+				return NewError(ErrConflict, "превышено допустимое число предстоящих записей (%d)", limits.MaxActive)
+			}
+		}
+		if limits.OnePerDoctor {
+			var count int64
+			err := active.Session(&gorm.Session{}).
+				Where("schedules.doctor_id = (SELECT doctor_id FROM schedules WHERE schedule_id = ?)", req.ScheduleID).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return NewError(ErrConflict, "у пациента уже есть предстоящая запись к этому врачу")
+			}
 		}
 
-		return nil
+		return reserveSlot(tx, &appointment)
 	})
-
 	if err != nil {
 		return nil, err
 	}
@@ -58,10 +102,42 @@ func (r *appointmentRepo) CreateAppointmentInTransaction(req *models.CreateAppoi
 	if err := r.db.Preload("Patient").Preload("Schedule.Doctor").First(&appointment, appointment.ID).Error; err != nil {
 		return nil, err
 	}
-
 	return &appointment, nil
 }
 
+// reserveSlot блокирует слот, проверяет, что он свободен, создает запись и помечает слот занятым.
+// Должна вызываться внутри транзакции.
+func reserveSlot(tx *gorm.DB, appointment *models.Appointment) error {
+	// Блокировка карточки не дает одновременно удалить пациента или объединить его с другой карточкой.
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("deleted_at IS NULL").
+		First(&models.Patient{}, appointment.PatientID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewError(ErrNotFound, "пациент с ID %d не найден", appointment.PatientID)
+		}
+		return err
+	}
+
+	var schedule models.Schedule
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, appointment.ScheduleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewError(ErrNotFound, "указанный слот в расписании не найден")
+		}
+		return err
+	}
+
+	if !schedule.IsAvailable {
+		return NewError(ErrConflict, "выбранное время уже занято")
+	}
+
+	if err := tx.Omit("Patient", "Schedule", "Ticket").Create(appointment).Error; err != nil {
+		return err
+	}
+
+	schedule.IsAvailable = false
+	return tx.Save(&schedule).Error
+}
+
 // FindScheduleAndAppointmentsByDoctorAndDate находит расписание и связанные с ним записи.
 func (r *appointmentRepo) FindScheduleAndAppointmentsByDoctorAndDate(doctorID uint, date time.Time) ([]models.ScheduleWithAppointmentInfo, error) {
 	var schedules []models.Schedule
@@ -81,7 +157,9 @@ func (r *appointmentRepo) FindScheduleAndAppointmentsByDoctorAndDate(doctorID ui
 		info := models.ScheduleWithAppointmentInfo{Schedule: s}
 		if !s.IsAvailable {
 			var app models.Appointment
-			err := r.db.Preload("Patient").Preload("Ticket").Where("schedule_id = ?", s.ID).First(&app).Error
+			err := r.db.Preload("Patient").Preload("Ticket").
+				Where("schedule_id = ? AND status IN ?", s.ID, models.OccupyingAppointmentStatuses).
+				First(&app).Error
 			if err == nil {
 				info.Appointment = &app
 				if app.TicketID != nil {
@@ -102,7 +180,20 @@ func (r *appointmentRepo) FindByID(id uint) (*models.Appointment, error) {
 	return &appointment, err
 }
 
-// FindByPatientID находит все записи пациента.
+// FindByScheduleID находит запись, занимающую указанный слот, вместе с пациентом.
+// Отмененные и перенесенные записи не учитываются.
+func (r *appointmentRepo) FindByScheduleID(scheduleID uint) (*models.Appointment, error) {
+	var appointment models.Appointment
+	err := r.db.Preload("Patient").
+		Where("schedule_id = ? AND status IN ?", scheduleID, models.OccupyingAppointmentStatuses).
+		First(&appointment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+// FindByPatientID находит все записи пациента, включая отмененные и перенесенные.
 func (r *appointmentRepo) FindByPatientID(patientID uint) ([]models.Appointment, error) {
 	var appointments []models.Appointment
 	err := r.db.Preload("Schedule.Doctor").Preload("Ticket").
@@ -118,47 +209,248 @@ func (r *appointmentRepo) Update(appointment *models.Appointment) error {
 	return r.db.Save(appointment).Error
 }
 
-// DeleteAppointmentAndFreeSlot удаляет запись и освобождает слот в рамках одной транзакции.
-func (r *appointmentRepo) DeleteAppointmentAndFreeSlot(appointmentID uint) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		var app models.Appointment
-		if err := tx.First(&app, appointmentID).Error; err != nil {
+// CancelAppointment отменяет запись. Запись сохраняется в истории пациента с причиной, автором и временем отмены.
+// Слот остается недоступным: его освобождает или удерживает для листа ожидания WaitlistRepository.OfferSlot,
+// чтобы между отменой и предложением слот не успели занять в обход очереди.
+func (r *appointmentRepo) CancelAppointment(appointmentID uint, reason, actor string) (*models.Appointment, error) {
+	var app models.Appointment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockBookedAppointment(tx, appointmentID, &app); err != nil {
+			return err
+		}
+		now := time.Now()
+		app.Status = models.AppointmentCancelled
+		app.CancelReason = &reason
+		app.CancelledBy = &actor
+		app.CancelledAt = &now
+		return tx.Model(&app).Updates(map[string]interface{}{
+			"status":        app.Status,
+			"cancel_reason": reason,
+			"cancelled_by":  actor,
+			"cancelled_at":  now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// RescheduleAppointment переносит запись на другой слот в рамках одной транзакции:
+// новый слот блокируется, создается новая запись, прежняя получает статус "перенесен"
+// со ссылкой на новую. Прежний слот, как и при отмене, освобождает WaitlistRepository.OfferSlot.
+func (r *appointmentRepo) RescheduleAppointment(appointmentID uint, req *models.RescheduleAppointmentRequest, actor string) (*models.Appointment, *models.Appointment, error) {
+	var previous, current models.Appointment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockBookedAppointment(tx, appointmentID, &previous); err != nil {
+			return err
+		}
+		if previous.ScheduleID == req.ScheduleID {
+			return NewError(ErrConflict, "запись уже находится в выбранном слоте")
+		}
+
+		var slot models.Schedule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&slot, req.ScheduleID).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("запись с ID %d не найдена", appointmentID)
+				return NewError(ErrNotFound, "указанный слот в расписании не найден")
 			}
 			return err
 		}
-		if err := tx.Delete(&app).Error; err != nil {
-			return err
+		if !slot.IsAvailable {
+			return NewError(ErrConflict, "выбранное время уже занято")
 		}
-		if err := tx.Model(&models.Schedule{}).Where("schedule_id = ?", app.ScheduleID).Update("is_available", true).Error; err != nil {
+
+		moved, err := moveAppointment(tx, &previous, &slot, req, actor)
+		if err != nil {
 			return err
 		}
+		current = *moved
 		return nil
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := r.db.Preload("Patient").Preload("Schedule.Doctor").First(&previous, previous.ID).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := r.db.Preload("Patient").Preload("Schedule.Doctor").First(&current, current.ID).Error; err != nil {
+		return nil, nil, err
+	}
+	return &previous, &current, nil
 }
 
-func (r *appointmentRepo) FindUpcomingByPatientID(patientID uint, now time.Time) (*models.Appointment, error) {
-	var appointment models.Appointment
-	today := now.Format("2006-01-02")
+// moveAppointment переносит заблокированную запись previous на заблокированный свободный слот:
+// создается новая запись, слот занимается, прежняя запись получает статус "перенесен" со ссылкой
+// на новую, а использованное направление переходит к новой записи. Источник записи и код подтверждения
+// самозаписи переходят к новой записи, чтобы пациент мог отметиться по прежнему коду; отступление от
+// правил записи берется из req. Прежний слот не освобождается - это решает вызывающий код.
+// Должна вызываться внутри транзакции.
+func moveAppointment(tx *gorm.DB, previous *models.Appointment, slot *models.Schedule, req *models.RescheduleAppointmentRequest, actor string) (*models.Appointment, error) {
+	current := models.Appointment{
+		ScheduleID:          slot.ID,
+		PatientID:           previous.PatientID,
+		Status:              models.AppointmentBooked,
+		ConfirmationCode:    previous.ConfirmationCode,
+		BookedBy:            previous.BookedBy,
+		BookingKind:         previous.BookingKind,
+		SourceAppointmentID: previous.SourceAppointmentID,
+	}
+	if req.OverrideReason != "" {
+		current.OverrideReason = &req.OverrideReason
+		current.OverriddenBy = &req.OverriddenBy
+		current.OverriddenRules = req.OverriddenRules
+	}
+	// Код подтверждения уникален, поэтому снимается с прежней записи до создания новой.
+	if previous.ConfirmationCode != nil {
+		if err := tx.Model(previous).Update("confirmation_code", nil).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Omit("Patient", "Schedule", "Ticket").Create(&current).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Schedule{}).Where("schedule_id = ?", slot.ID).Update("is_available", false).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":            models.AppointmentRescheduled,
+		"cancelled_by":      actor,
+		"cancelled_at":      now,
+		"rescheduled_to_id": current.ID,
+	}
+	if req.Reason != "" {
+		updates["cancel_reason"] = req.Reason
+	}
+	if err := tx.Model(previous).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Referral{}).Where("appointment_id = ?", previous.ID).Update("appointment_id", current.ID).Error; err != nil {
+		return nil, err
+	}
+	return &current, nil
+}
 
+// lockBookedAppointment блокирует запись для изменения и проверяет, что она еще действует.
+func lockBookedAppointment(tx *gorm.DB, appointmentID uint, app *models.Appointment) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(app, appointmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewError(ErrNotFound, "запись с ID %d не найдена", appointmentID)
+		}
+		return err
+	}
+	if app.Status != models.AppointmentBooked {
+		return NewError(ErrConflict, "запись в статусе '%s' нельзя изменить", app.Status)
+	}
+	return nil
+}
+
+// FindForCheckIn возвращает действующие записи пациента на дату, по которым еще не выдан талон.
+func (r *appointmentRepo) FindForCheckIn(patientID uint, date time.Time) ([]models.Appointment, error) {
+	var appointments []models.Appointment
 	err := r.db.Joins("JOIN schedules ON schedules.schedule_id = appointments.schedule_id").
 		Preload("Schedule.Doctor").
-		Where("appointments.patient_id = ? AND appointments.ticket_id IS NULL AND schedules.date = ?", patientID, today).
+		Where("appointments.patient_id = ? AND appointments.ticket_id IS NULL AND appointments.status = ? AND schedules.date = ?",
+			patientID, models.AppointmentBooked, date.Format("2006-01-02")).
 		Order("schedules.start_time asc").
-		First(&appointment).Error
+		Find(&appointments).Error
+	return appointments, err
+}
 
-	return &appointment, err
+// FindByConfirmationCode ищет запись по коду подтверждения самостоятельной записи.
+func (r *appointmentRepo) FindByConfirmationCode(code string) (*models.Appointment, error) {
+	var appointment models.Appointment
+	if err := r.db.Preload("Schedule.Doctor").Where("confirmation_code = ?", code).First(&appointment).Error; err != nil {
+		return nil, err
+	}
+	return &appointment, nil
 }
 
-func (r *appointmentRepo) AssignTicketToAppointment(appointment *models.Appointment, ticket *models.Ticket) error {
+// AssignTicketsToAppointments в одной транзакции создает талоны и отмечает явку по записям.
+// Талон tickets[i] привязывается к записи appointments[i]. Если хотя бы одна запись уже
+// зарегистрирована или изменилась, ни один талон не создается.
+func (r *appointmentRepo) AssignTicketsToAppointments(appointments []models.Appointment, tickets []*models.Ticket) error {
+	if len(appointments) != len(tickets) {
+		return fmt.Errorf("число талонов не совпадает с числом записей")
+	}
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(ticket).Error; err != nil {
-			return err
+		for i := range appointments {
+			if err := tx.Create(tickets[i]).Error; err != nil {
+				return err
+			}
+			res := tx.Model(&models.Appointment{}).
+				Where("appointment_id = ? AND ticket_id IS NULL AND status = ?", appointments[i].ID, models.AppointmentBooked).
+				Updates(map[string]interface{}{
+					"ticket_id": tickets[i].ID,
+					"status":    models.AppointmentAttended,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return NewError(ErrConflict, "явка по записи %d уже зарегистрирована или запись изменилась", appointments[i].ID)
+			}
+		}
+		return nil
+	})
+}
+
+// FindByTicketID находит запись, к которой привязан талон, вместе с пациентом и врачом.
+func (r *appointmentRepo) FindByTicketID(ticketID uint) (*models.Appointment, error) {
+	var appointment models.Appointment
+	err := r.db.Preload("Patient").Preload("Schedule.Doctor").
+		Where("ticket_id = ?", ticketID).
+		First(&appointment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+// SaveOutcome в одной транзакции сохраняет итог приема и, если врач направил пациента к другому специалисту, направление.
+func (r *appointmentRepo) SaveOutcome(appointment *models.Appointment, referral *models.Referral) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if referral != nil {
+			if err := tx.Create(referral).Error; err != nil {
+				return err
+			}
+			appointment.IssuedReferralID = &referral.ID
 		}
-		if err := tx.Model(appointment).Update("ticket_id", ticket.ID).Error; err != nil {
+		return tx.Model(&models.Appointment{}).Where("appointment_id = ?", appointment.ID).Updates(map[string]interface{}{
+			"diagnosis_code":          appointment.DiagnosisCode,
+			"visit_note":              appointment.VisitNote,
+			"follow_up_needed":        appointment.FollowUpNeeded,
+			"referral_specialization": appointment.ReferralSpecialization,
+			"issued_referral_id":      appointment.IssuedReferralID,
+			"outcome_recorded_at":     appointment.OutcomeRecordedAt,
+			"outcome_recorded_by":     appointment.OutcomeRecordedBy,
+		}).Error
+	})
+}
+
+// MarkNoShows переводит в статус "не_явился" действующие записи на даты раньше before,
+// по которым пациент так и не получил талон, и возвращает их ID.
+func (r *appointmentRepo) MarkNoShows(before time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Appointment{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND ticket_id IS NULL", models.AppointmentBooked).
+			Where("schedule_id IN (SELECT schedule_id FROM schedules WHERE date < ?)", before.Format("2006-01-02")).
+			Pluck("appointment_id", &ids).Error; err != nil {
 			return err
 		}
-		return nil
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Appointment{}).
+			Where("appointment_id IN ?", ids).
+			Update("status", models.AppointmentNoShow).Error
 	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
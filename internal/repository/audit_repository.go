@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"ElectronicQueue/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type auditRepo struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepo{db: db}
+}
+
+// Create добавляет запись в журнал аудита.
+func (r *auditRepo) Create(entry *models.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+// Find возвращает страницу журнала аудита по фильтру, начиная с последних записей.
+func (r *auditRepo) Find(filter models.AuditLogFilter) ([]models.AuditLog, int64, error) {
+	tx := r.db.Model(&models.AuditLog{})
+	if filter.Actor != "" {
+		tx = tx.Where("actor = ?", filter.Actor)
+	}
+	if filter.Role != "" {
+		tx = tx.Where("role = ?", filter.Role)
+	}
+	if filter.Entity != "" {
+		tx = tx.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		tx = tx.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Method != "" {
+		tx = tx.Where("method = ?", filter.Method)
+	}
+	if filter.RequestID != "" {
+		tx = tx.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		tx = tx.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		tx = tx.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := tx.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditLog
+	err := tx.Order("created_at DESC, audit_id DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&entries).Error
+	return entries, total, err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"ElectronicQueue/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type bookingRuleRepo struct {
+	db *gorm.DB
+}
+
+func NewBookingRuleRepository(db *gorm.DB) BookingRuleRepository {
+	return &bookingRuleRepo{db: db}
+}
+
+func (r *bookingRuleRepo) Create(rule *models.BookingRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *bookingRuleRepo) Update(rule *models.BookingRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *bookingRuleRepo) Delete(id uint) error {
+	return r.db.Delete(&models.BookingRule{}, id).Error
+}
+
+func (r *bookingRuleRepo) GetByID(id uint) (*models.BookingRule, error) {
+	var rule models.BookingRule
+	if err := r.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *bookingRuleRepo) GetAll() ([]models.BookingRule, error) {
+	var rules []models.BookingRule
+	if err := r.db.Order("specialization asc").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// FindActiveBySpecialization возвращает действующие правила специальности без учета регистра.
+func (r *bookingRuleRepo) FindActiveBySpecialization(specialization string) (*models.BookingRule, error) {
+	var rule models.BookingRule
+	err := r.db.Where("LOWER(specialization) = LOWER(?) AND is_active = ?", specialization, true).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindPatientAppointmentsBySpecialization возвращает предстоящие и состоявшиеся записи пациента
+// к врачам указанной специальности вместе со слотами.
+func (r *bookingRuleRepo) FindPatientAppointmentsBySpecialization(patientID uint, specialization string) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	err := r.db.Preload("Schedule").
+		Joins("JOIN schedules ON schedules.schedule_id = appointments.schedule_id").
+		Joins("JOIN doctors ON doctors.doctor_id = schedules.doctor_id").
+		Where("appointments.patient_id = ? AND appointments.status IN ? AND LOWER(doctors.specialization) = LOWER(?)",
+			patientID, []models.AppointmentStatus{models.AppointmentBooked, models.AppointmentAttended}, specialization).
+		Order("schedules.date asc").
+		Find(&appointments).Error
+	if err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
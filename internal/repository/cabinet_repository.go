@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"ElectronicQueue/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type cabinetRepo struct {
+	db *gorm.DB
+}
+
+func NewCabinetRepository(db *gorm.DB) CabinetRepository {
+	return &cabinetRepo{db: db}
+}
+
+func (r *cabinetRepo) Create(cabinet *models.Cabinet) error {
+	return r.db.Create(cabinet).Error
+}
+
+func (r *cabinetRepo) Update(cabinet *models.Cabinet) error {
+	return r.db.Save(cabinet).Error
+}
+
+func (r *cabinetRepo) Delete(number int) error {
+	return r.db.Delete(&models.Cabinet{}, number).Error
+}
+
+func (r *cabinetRepo) GetByNumber(number int) (*models.Cabinet, error) {
+	var cabinet models.Cabinet
+	if err := r.db.First(&cabinet, number).Error; err != nil {
+		return nil, err
+	}
+	return &cabinet, nil
+}
+
+// GetAll возвращает кабинеты, упорядоченные по номеру. При onlyActive выводятся только действующие.
+func (r *cabinetRepo) GetAll(onlyActive bool) ([]models.Cabinet, error) {
+	var cabinets []models.Cabinet
+	query := r.db.Order("cabinet_number asc")
+	if onlyActive {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Find(&cabinets).Error; err != nil {
+		return nil, err
+	}
+	return cabinets, nil
+}
+
+// CountUsage возвращает число слотов расписания и шаблонов, ссылающихся на кабинет.
+func (r *cabinetRepo) CountUsage(number int) (int64, error) {
+	var slots, templates int64
+	if err := r.db.Model(&models.Schedule{}).Where("cabinet = ?", number).Count(&slots).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.Model(&models.ScheduleTemplate{}).Where("cabinet = ?", number).Count(&templates).Error; err != nil {
+		return 0, err
+	}
+	return slots + templates, nil
+}
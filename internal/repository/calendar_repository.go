@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"ElectronicQueue/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type calendarRepo struct {
+	db *gorm.DB
+}
+
+func NewCalendarRepository(db *gorm.DB) CalendarRepository {
+	return &calendarRepo{db: db}
+}
+
+// SaveClinicDay добавляет особый день или заменяет существующий на ту же дату.
+func (r *calendarRepo) SaveClinicDay(day *models.ClinicDay) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"day_type", "work_start", "work_end", "description"}),
+	}).Create(day).Error
+}
+
+func (r *calendarRepo) DeleteClinicDay(date time.Time) error {
+	return r.db.Where("date = ?", date.Format("2006-01-02")).Delete(&models.ClinicDay{}).Error
+}
+
+func (r *calendarRepo) GetClinicDay(date time.Time) (*models.ClinicDay, error) {
+	var day models.ClinicDay
+	if err := r.db.Where("date = ?", date.Format("2006-01-02")).First(&day).Error; err != nil {
+		return nil, err
+	}
+	return &day, nil
+}
+
+// FindClinicDays возвращает особые дни клиники за период [from, to].
+func (r *calendarRepo) FindClinicDays(from, to time.Time) ([]models.ClinicDay, error) {
+	var days []models.ClinicDay
+	err := r.db.Where("date >= ? AND date <= ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("date asc").
+		Find(&days).Error
+	if err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+func (r *calendarRepo) CreateAbsence(absence *models.DoctorAbsence) error {
+	return r.db.Create(absence).Error
+}
+
+func (r *calendarRepo) DeleteAbsence(id uint) error {
+	return r.db.Delete(&models.DoctorAbsence{}, id).Error
+}
+
+func (r *calendarRepo) GetAbsenceByID(id uint) (*models.DoctorAbsence, error) {
+	var absence models.DoctorAbsence
+	if err := r.db.First(&absence, id).Error; err != nil {
+		return nil, err
+	}
+	return &absence, nil
+}
+
+// FindAbsences возвращает отсутствия врачей, пересекающиеся с периодом [from, to].
+// Если doctorID не указан, возвращаются отсутствия всех врачей.
+func (r *calendarRepo) FindAbsences(doctorID *uint, from, to time.Time) ([]models.DoctorAbsence, error) {
+	var absences []models.DoctorAbsence
+	query := r.db.Where("date_from <= ? AND date_to >= ?", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	if doctorID != nil {
+		query = query.Where("doctor_id = ?", *doctorID)
+	}
+	if err := query.Order("date_from asc, doctor_id asc").Find(&absences).Error; err != nil {
+		return nil, err
+	}
+	return absences, nil
+}
+
+// FindAppointmentsInRange возвращает действующие записи пациентов на слоты за период [from, to] вместе со слотом и пациентом.
+func (r *calendarRepo) FindAppointmentsInRange(doctorID *uint, from, to time.Time) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	query := r.db.Preload("Patient").Preload("Schedule").
+		Joins("JOIN schedules ON schedules.schedule_id = appointments.schedule_id").
+		Where("appointments.status = ? AND schedules.date >= ? AND schedules.date <= ?",
+			models.AppointmentBooked, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if doctorID != nil {
+		query = query.Where("schedules.doctor_id = ?", *doctorID)
+	}
+	if err := query.Order("schedules.date asc, schedules.start_time asc").Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// SetAppointmentFlag помечает записи от источника source причиной, по которой прием не может состояться.
+// Пометки других источников сохраняются, сводка в appointments.flag_reason пересчитывается.
+func (r *calendarRepo) SetAppointmentFlag(appointmentIDs []uint, source, reason string) error {
+	if len(appointmentIDs) == 0 {
+		return nil
+	}
+	flags := make([]models.AppointmentFlag, len(appointmentIDs))
+	for i, id := range appointmentIDs {
+		flags[i] = models.AppointmentFlag{AppointmentID: id, Source: source, Reason: reason}
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "appointment_id"}, {Name: "source"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason"}),
+		}).Create(&flags).Error; err != nil {
+			return err
+		}
+		return refreshFlagReason(tx, appointmentIDs)
+	})
+}
+
+// ClearAppointmentFlag снимает пометки источника source со всех записей.
+// Запись остается помеченной, если у нее есть пометки других источников.
+func (r *calendarRepo) ClearAppointmentFlag(source string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&models.AppointmentFlag{}).Where("source = ?", source).Pluck("appointment_id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Where("source = ?", source).Delete(&models.AppointmentFlag{}).Error; err != nil {
+			return err
+		}
+		return refreshFlagReason(tx, ids)
+	})
+}
+
+// refreshFlagReason пересобирает сводку пометок записей из appointment_flags (NULL, если пометок нет).
+func refreshFlagReason(tx *gorm.DB, appointmentIDs []uint) error {
+	return tx.Model(&models.Appointment{}).
+		Where("appointment_id IN ?", appointmentIDs).
+		Update("flag_reason", gorm.Expr(
+			"(SELECT string_agg(f.reason, '; ' ORDER BY f.created_at, f.source) FROM appointment_flags f WHERE f.appointment_id = appointments.appointment_id)",
+		)).Error
+}
@@ -14,7 +14,8 @@ func NewCleanupRepository(db *gorm.DB) CleanupRepository {
 	return &cleanupRepo{db: db}
 }
 
-// TruncateTickets удаляет завершенные tickets и связанные appointments
+// TruncateTickets удаляет завершенные tickets и отмечает прошедшие записи без явки как "не_явился".
+// Сами записи сохраняются в истории пациентов; ссылка на удаленный талон обнуляется внешним ключом.
 func (r *cleanupRepo) TruncateTickets() error {
 	// Начинаем транзакцию
 	tx := r.db.Begin()
@@ -22,8 +23,8 @@ func (r *cleanupRepo) TruncateTickets() error {
 		return tx.Error
 	}
 
-	// Удаляем appointments с ticket_id = NULL (осиротевшие записи)
-	if err := tx.Exec("DELETE FROM appointments WHERE ticket_id IS NULL").Error; err != nil {
+	// Прошедшие записи, по которым пациент так и не получил талон, считаем неявкой
+	if err := tx.Exec(missedAppointmentsUpdateSQL).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -45,9 +46,20 @@ func (r *cleanupRepo) GetTicketsCount() (int64, error) {
 	return count, err
 }
 
-// GetOrphanedAppointmentsCount возвращает количество appointments с ticket_id = NULL
-func (r *cleanupRepo) GetOrphanedAppointmentsCount() (int64, error) {
+// GetMissedAppointmentsCount возвращает количество прошедших записей без явки
+func (r *cleanupRepo) GetMissedAppointmentsCount() (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Appointment{}).Where("ticket_id IS NULL").Count(&count).Error
+	err := r.db.Model(&models.Appointment{}).
+		Joins("JOIN schedules ON schedules.schedule_id = appointments.schedule_id").
+		Where("appointments.status = ? AND appointments.ticket_id IS NULL AND schedules.date < CURRENT_DATE", models.AppointmentBooked).
+		Count(&count).Error
 	return count, err
 }
+
+const missedAppointmentsUpdateSQL = `
+	UPDATE appointments a SET status = 'не_явился'
+	FROM schedules s
+	WHERE s.schedule_id = a.schedule_id
+	  AND a.status = 'записан'
+	  AND a.ticket_id IS NULL
+	  AND s.date < CURRENT_DATE`
@@ -12,7 +12,10 @@ import (
 // DatabaseRepository определяет методы для работы с данными таблиц.
 type DatabaseRepository interface {
 	GetTableColumns(tableName string) ([]string, error)
-	GetData(tableName string, page, limit int, filters models.Filters) ([]map[string]interface{}, int64, error)
+	GetPrimaryKey(tableName string) ([]string, error)
+	GetColumnSchemas(tableName string) ([]models.ColumnSchema, error)
+	GetData(tableName string, query models.DataQuery) ([]map[string]interface{}, int64, error)
+	StreamData(tableName string, query models.DataQuery, fn func(row map[string]interface{}) error) error
 	InsertData(tableName string, data interface{}) (int64, error)
 	UpdateData(tableName string, data map[string]interface{}, filters models.Filters) (int64, error)
 	DeleteData(tableName string, filters models.Filters) (int64, error)
@@ -27,10 +30,16 @@ func NewDatabaseRepository(db *gorm.DB) DatabaseRepository {
 }
 
 func (r *databaseRepo) applyFilters(tx *gorm.DB, filters models.Filters) (*gorm.DB, error) {
-	if len(filters.Conditions) == 0 {
+	clause, args := buildFilterClause(filters)
+	if clause == "" {
 		return tx, nil
 	}
+	return tx.Where(clause, args...), nil
+}
 
+// buildFilterClause рекурсивно строит SQL-условие для фильтра и его вложенных групп.
+// Имена полей и операторы должны быть провалидированы сервисом заранее.
+func buildFilterClause(filters models.Filters) (string, []interface{}) {
 	var queryParts []string
 	var queryArgs []interface{}
 
@@ -39,27 +48,84 @@ func (r *databaseRepo) applyFilters(tx *gorm.DB, filters models.Filters) (*gorm.
 		op := strings.ToUpper(cond.Operator)
 
 		var queryPart string
-		if op == "IN" {
-			queryPart = fmt.Sprintf("%s IN (?)", cond.Field)
+		switch {
+		case op == "IN" || op == "NOT IN":
+			queryPart = fmt.Sprintf("%s %s (?)", cond.Field, op)
 			queryArgs = append(queryArgs, cond.Value)
-		} else if isNil && (op == "=" || op == "IS") {
+		case op == "BETWEEN":
+			bounds := reflect.ValueOf(cond.Value)
+			queryPart = fmt.Sprintf("%s BETWEEN ? AND ?", cond.Field)
+			queryArgs = append(queryArgs, bounds.Index(0).Interface(), bounds.Index(1).Interface())
+		case op == "IS NULL" || (isNil && (op == "=" || op == "IS")):
 			queryPart = fmt.Sprintf("%s IS NULL", cond.Field)
-		} else if isNil && (op == "<>" || op == "!=" || op == "IS NOT") {
+		case op == "IS NOT NULL" || (isNil && (op == "<>" || op == "!=" || op == "IS NOT")):
 			queryPart = fmt.Sprintf("%s IS NOT NULL", cond.Field)
-		} else {
-			queryPart = fmt.Sprintf("%s %s ?", cond.Field, cond.Operator)
+		default:
+			queryPart = fmt.Sprintf("%s %s ?", cond.Field, op)
 			queryArgs = append(queryArgs, cond.Value)
 		}
 		queryParts = append(queryParts, queryPart)
 	}
 
+	for _, group := range filters.Groups {
+		groupClause, groupArgs := buildFilterClause(group)
+		if groupClause == "" {
+			continue
+		}
+		queryParts = append(queryParts, "("+groupClause+")")
+		queryArgs = append(queryArgs, groupArgs...)
+	}
+
+	if len(queryParts) == 0 {
+		return "", nil
+	}
+
 	logicalOp := " AND "
 	if strings.ToUpper(filters.LogicalOperator) == "OR" {
 		logicalOp = " OR "
 	}
 
-	fullQuery := strings.Join(queryParts, logicalOp)
-	return tx.Where(fullQuery, queryArgs...), nil
+	return strings.Join(queryParts, logicalOp), queryArgs
+}
+
+// buildKeysetClause строит условие "строка идет после after" для заданной сортировки:
+// (a > ?) OR (a = ? AND b > ?) OR ...
+func buildKeysetClause(sort []models.SortField, after []interface{}) (string, []interface{}) {
+	var orParts []string
+	var args []interface{}
+	for i := range sort {
+		var andParts []string
+		for j := 0; j < i; j++ {
+			andParts = append(andParts, fmt.Sprintf("%s = ?", sort[j].Field))
+			args = append(args, after[j])
+		}
+		cmp := ">"
+		if strings.ToLower(sort[i].Direction) == "desc" {
+			cmp = "<"
+		}
+		andParts = append(andParts, fmt.Sprintf("%s %s ?", sort[i].Field, cmp))
+		args = append(args, after[i])
+		orParts = append(orParts, "("+strings.Join(andParts, " AND ")+")")
+	}
+	return strings.Join(orParts, " OR "), args
+}
+
+// GetPrimaryKey возвращает столбцы первичного ключа таблицы.
+func (r *databaseRepo) GetPrimaryKey(tableName string) ([]string, error) {
+	var columns []string
+	err := r.db.Raw(`
+		SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema
+		WHERE tc.table_schema = 'public' AND tc.table_name = ? AND tc.constraint_type = 'PRIMARY KEY'
+		ORDER BY kcu.ordinal_position`,
+		tableName,
+	).Scan(&columns).Error
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить первичный ключ таблицы %s: %w", tableName, err)
+	}
+	return columns, nil
 }
 
 // GetTableColumns получает список столбцов для указанной таблицы из схемы БД.
@@ -77,47 +143,175 @@ func (r *databaseRepo) GetTableColumns(tableName string) ([]string, error) {
 	}
 
 	if len(columns) == 0 {
-		return nil, fmt.Errorf("таблица '%s' не найдена или не имеет столбцов", tableName)
+		return nil, NewError(ErrNotFound, "таблица '%s' не найдена или не имеет столбцов", tableName)
+	}
+
+	return columns, nil
+}
+
+// columnSchemaRow - строка результата запроса к information_schema о столбцах таблицы.
+type columnSchemaRow struct {
+	ColumnName    string
+	DataType      string
+	IsNullable    bool
+	ColumnDefault *string
+	IsPrimaryKey  bool
+	ForeignTable  *string
+	ForeignColumn *string
+}
+
+// GetColumnSchemas возвращает описание столбцов таблицы: тип, допустимость NULL,
+// значение по умолчанию, принадлежность первичному ключу и внешние ключи.
+func (r *databaseRepo) GetColumnSchemas(tableName string) ([]models.ColumnSchema, error) {
+	var rows []columnSchemaRow
+	err := r.db.Raw(`
+		SELECT
+			c.column_name,
+			c.data_type,
+			c.is_nullable = 'YES' AS is_nullable,
+			c.column_default,
+			EXISTS (
+				SELECT 1
+				FROM information_schema.table_constraints tc
+				JOIN information_schema.key_column_usage kcu
+					ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema
+				WHERE tc.constraint_type = 'PRIMARY KEY'
+					AND tc.table_schema = c.table_schema
+					AND tc.table_name = c.table_name
+					AND kcu.column_name = c.column_name
+			) AS is_primary_key,
+			fk.foreign_table,
+			fk.foreign_column
+		FROM information_schema.columns c
+		LEFT JOIN (
+			-- Столбец внешнего ключа сопоставляется со столбцом ссылки по позиции в составном ключе;
+			-- если столбец входит в несколько внешних ключей, берется первый по имени ограничения.
+			SELECT DISTINCT ON (kcu.column_name)
+				kcu.column_name, ref.table_name AS foreign_table, ref.column_name AS foreign_column
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu
+				ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema
+			JOIN information_schema.referential_constraints rc
+				ON tc.constraint_name = rc.constraint_name AND tc.table_schema = rc.constraint_schema
+			JOIN information_schema.key_column_usage ref
+				ON rc.unique_constraint_name = ref.constraint_name
+				AND rc.unique_constraint_schema = ref.table_schema
+				AND ref.ordinal_position = kcu.position_in_unique_constraint
+			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = 'public' AND tc.table_name = ?
+			ORDER BY kcu.column_name, tc.constraint_name
+		) fk ON fk.column_name = c.column_name
+		WHERE c.table_schema = 'public' AND c.table_name = ?
+		ORDER BY c.ordinal_position`,
+		tableName, tableName,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить схему таблицы %s: %w", tableName, err)
+	}
+	if len(rows) == 0 {
+		return nil, NewError(ErrNotFound, "таблица '%s' не найдена или не имеет столбцов", tableName)
 	}
 
+	columns := make([]models.ColumnSchema, 0, len(rows))
+	for _, row := range rows {
+		col := models.ColumnSchema{
+			Name:         row.ColumnName,
+			DataType:     row.DataType,
+			IsNullable:   row.IsNullable,
+			Default:      row.ColumnDefault,
+			IsPrimaryKey: row.IsPrimaryKey,
+		}
+		if row.ForeignTable != nil && row.ForeignColumn != nil {
+			col.References = &models.ForeignKeyRef{Table: *row.ForeignTable, Column: *row.ForeignColumn}
+		}
+		columns = append(columns, col)
+	}
 	return columns, nil
 }
 
-// GetData строит и выполняет динамический запрос к БД.
-func (r *databaseRepo) GetData(tableName string, page, limit int, filters models.Filters) ([]map[string]interface{}, int64, error) {
+// GetData строит и выполняет динамический запрос к БД по провалидированному запросу.
+func (r *databaseRepo) GetData(tableName string, query models.DataQuery) ([]map[string]interface{}, int64, error) {
 	tx := r.db.Table(tableName)
 
 	// Построение WHERE-условия
-	tx, err := r.applyFilters(tx, filters)
+	tx, err := r.applyFilters(tx, query.Filters)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Получение общего количества записей для пагинации
+	// Получение общего количества записей для пагинации (без учета курсора)
 	var total int64
 	if err := tx.Count(&total).Error; err != nil {
-		return nil, 0, err
+		return nil, 0, dataError(err)
 	}
 
-	// Применение пагинации
-	offset := (page - 1) * limit
-	tx = tx.Offset(offset).Limit(limit)
+	// Keyset-пагинация по курсору либо обычная пагинация по страницам
+	if len(query.After) > 0 {
+		keysetClause, keysetArgs := buildKeysetClause(query.Sort, query.After)
+		tx = tx.Where(keysetClause, keysetArgs...)
+	} else {
+		offset := (query.Page - 1) * query.Limit
+		tx = tx.Offset(offset)
+	}
+	tx = tx.Limit(query.Limit)
+
+	if len(query.Columns) > 0 {
+		tx = tx.Select(query.Columns)
+	}
 
-	// Если таблица - tickets, применяем кастомную сортировку
-	if tableName == "tickets" {
-		orderClause := "CASE status WHEN 'ожидает' THEN 1 WHEN 'приглашен' THEN 2 WHEN 'зарегистрирован' THEN 3 WHEN 'на_приеме' THEN 4 WHEN 'завершен' THEN 5 ELSE 6 END, created_at ASC"
+	if len(query.Sort) > 0 {
+		for _, sf := range query.Sort {
+			tx = tx.Order(fmt.Sprintf("%s %s", sf.Field, strings.ToUpper(sf.Direction)))
+		}
+	} else if tableName == "tickets" {
+		// Если таблица - tickets и сортировка не задана, применяем кастомную сортировку
+		orderClause := "CASE status WHEN 'ожидает' THEN 1 WHEN 'приглашен' THEN 2 WHEN 'зарегистрирован' THEN 3 WHEN 'приглашен_в_кабинет' THEN 4 WHEN 'на_приеме' THEN 5 WHEN 'завершен' THEN 6 ELSE 7 END, created_at ASC"
 		tx = tx.Order(orderClause)
 	}
 
 	// Выполнение запроса
 	var results []map[string]interface{}
 	if err := tx.Find(&results).Error; err != nil {
-		return nil, 0, err
+		return nil, 0, dataError(err)
 	}
 
 	return results, total, nil
 }
 
+// StreamData построчно читает все записи, удовлетворяющие фильтрам, без пагинации
+// и передает их в fn, не загружая весь результат в память.
+func (r *databaseRepo) StreamData(tableName string, query models.DataQuery, fn func(row map[string]interface{}) error) error {
+	tx := r.db.Table(tableName)
+
+	tx, err := r.applyFilters(tx, query.Filters)
+	if err != nil {
+		return err
+	}
+
+	if len(query.Columns) > 0 {
+		tx = tx.Select(query.Columns)
+	}
+	for _, sf := range query.Sort {
+		tx = tx.Order(fmt.Sprintf("%s %s", sf.Field, strings.ToUpper(sf.Direction)))
+	}
+
+	rows, err := tx.Rows()
+	if err != nil {
+		return dataError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // InsertData вставляет одну или несколько записей в таблицу.
 func (r *databaseRepo) InsertData(tableName string, data interface{}) (int64, error) {
 	// Начинаем транзакцию
@@ -144,7 +338,7 @@ func (r *databaseRepo) InsertData(tableName string, data interface{}) (int64, er
 			result := tx.Table(tableName).Create(v.Index(i).Interface())
 			if result.Error != nil {
 				tx.Rollback() // Откатываем транзакцию при ошибке
-				return 0, result.Error
+				return 0, dataError(result.Error)
 			}
 			totalRowsAffected += result.RowsAffected
 		}
@@ -153,7 +347,7 @@ func (r *databaseRepo) InsertData(tableName string, data interface{}) (int64, er
 		result := tx.Table(tableName).Create(data)
 		if result.Error != nil {
 			tx.Rollback()
-			return 0, result.Error
+			return 0, dataError(result.Error)
 		}
 		totalRowsAffected = result.RowsAffected
 	default:
@@ -180,7 +374,7 @@ func (r *databaseRepo) UpdateData(tableName string, data map[string]interface{},
 
 	result := tx.Updates(data)
 	if result.Error != nil {
-		return 0, result.Error
+		return 0, dataError(result.Error)
 	}
 	return result.RowsAffected, nil
 }
@@ -196,7 +390,7 @@ func (r *databaseRepo) DeleteData(tableName string, filters models.Filters) (int
 	// Используем пустой map для GORM, чтобы он построил правильный DELETE запрос
 	result := tx.Delete(&map[string]interface{}{})
 	if result.Error != nil {
-		return 0, result.Error
+		return 0, dataError(result.Error)
 	}
 	return result.RowsAffected, nil
 }
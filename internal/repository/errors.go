@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Виды ошибок предметной области. Текст ошибки предназначен клиенту, а вид проверяется через
+// errors.Is и определяет HTTP-статус ответа, поэтому изменение формулировки не меняет поведение API.
+var (
+	ErrNotFound     = errors.New("не найдено")
+	ErrConflict     = errors.New("конфликт с текущим состоянием")
+	ErrInvalidInput = errors.New("неверные данные")
+	ErrForbidden    = errors.New("действие запрещено")
+)
+
+// kindError - ошибка с текстом для клиента и видом из перечисленных выше.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+
+// Unwrap позволяет errors.Is найти и вид ошибки, и обернутую через %w причину.
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// NewError создает ошибку вида kind с сообщением в формате fmt.Errorf (поддерживается %w).
+func NewError(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+// sqlState возвращает код SQLSTATE ошибки PostgreSQL или пустую строку, если это не ошибка СУБД.
+func sqlState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// IsUniqueViolation сообщает, что запись нарушила ограничение уникальности (SQLSTATE 23505).
+func IsUniqueViolation(err error) bool {
+	return sqlState(err) == "23505"
+}
+
+// IsForeignKeyViolation сообщает, что запись ссылается на несуществующую строку или на нее
+// ссылаются другие записи (SQLSTATE 23503).
+func IsForeignKeyViolation(err error) bool {
+	return sqlState(err) == "23503"
+}
+
+// dataError помечает как неверные данные отказы СУБД из-за переданных значений: классы SQLSTATE
+// 22 (неверный формат или диапазон) и 23 (нарушение ограничений). Остальные ошибки остаются внутренними.
+func dataError(err error) error {
+	code := sqlState(err)
+	if strings.HasPrefix(code, "22") || strings.HasPrefix(code, "23") {
+		var pgErr *pgconn.PgError
+		errors.As(err, &pgErr)
+		return NewError(ErrInvalidInput, "%s", pgErr.Message)
+	}
+	return err
+}
@@ -2,10 +2,17 @@ package repository
 
 import (
 	"ElectronicQueue/internal/models"
+	"errors"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// activePatient отбирает карточки, которые не удалены и не объединены с другими.
+const activePatient = "patients.deleted_at IS NULL"
+
 type patientRepo struct {
 	db *gorm.DB
 }
@@ -22,33 +29,363 @@ func (r *patientRepo) Create(patient *models.Patient) (*models.Patient, error) {
 	return patient, nil
 }
 
-// Search ищет по ФИО, номеру ОМС и полному номеру паспорта.
-func (r *patientRepo) Search(query string) ([]models.Patient, error) {
-	var patients []models.Patient
-	searchQuery := "%" + query + "%"
+// patientNameExpr - нормализованное ФИО; совпадает с выражением триграммного индекса idx_patients_full_name_trgm.
+const patientNameExpr = "replace(lower(patients.full_name), 'ё', 'е')"
 
-	err := r.db.Where(
-		"full_name ILIKE ? OR oms_number LIKE ? OR (passport_series || passport_number) LIKE ?",
-		searchQuery,
-		searchQuery,
-		searchQuery,
-	).Limit(10).Find(&patients).Error
+// patientPhoneExpr - цифры телефона; совпадает с выражением индекса idx_patients_phone_digits_trgm.
+const patientPhoneExpr = "regexp_replace(patients.phone, '[^0-9]+', '', 'g')"
 
-	return patients, err
+// Search выполняет ранжированный поиск действующих пациентов с фильтрами и пагинацией.
+// ФИО сравнивается по триграммам (pg_trgm), поэтому находятся и написания с опечатками. Полис ОМС и паспорт
+// ищутся только по точному номеру: после шифрования (миграция 000025) триграммные индексы по ним удалены.
+func (r *patientRepo) Search(q models.PatientSearchQuery) ([]models.PatientSearchHit, int64, error) {
+	tx := r.db.Table("patients").Where(activePatient)
+	score, scoreArgs := "1.0", []interface{}{}
+
+	switch {
+	case q.Digits != "":
+		// Паспорт и полис ОМС зашифрованы, поэтому по ним возможно только точное совпадение через слепой индекс.
+		omsIndex, err := models.OMSBlindIndex(q.Digits)
+		if err != nil {
+			return nil, 0, err
+		}
+		passportIndex := ""
+		if len(q.Digits) == 10 {
+			if passportIndex, err = models.PassportBlindIndex(q.Digits[:4], q.Digits[4:]); err != nil {
+				return nil, 0, err
+			}
+		}
+		tx = tx.Where("patients.oms_index = ? OR patients.passport_index = ? OR "+patientPhoneExpr+" LIKE ?",
+			omsIndex, passportIndex, "%"+q.Digits+"%")
+		score = "CASE WHEN patients.oms_index = ? OR patients.passport_index = ? OR " +
+			patientPhoneExpr + " = ? THEN 1.0 ELSE 0.5 END"
+		scoreArgs = []interface{}{omsIndex, passportIndex, q.Digits}
+	case q.Surname != "":
+		tx = tx.Where("? <% "+patientNameExpr, q.Surname).
+			Where("split_part("+patientNameExpr+", ' ', 2) LIKE ?", q.FirstInitial+"%")
+		if q.MiddleInitial != "" {
+			tx = tx.Where("split_part("+patientNameExpr+", ' ', 3) LIKE ?", q.MiddleInitial+"%")
+		}
+		score = "similarity(split_part(" + patientNameExpr + ", ' ', 1), ?)"
+		scoreArgs = []interface{}{q.Surname}
+	case q.Text != "":
+		tx = tx.Where(patientNameExpr+" % ? OR ? <% "+patientNameExpr+" OR "+patientNameExpr+" LIKE ?",
+			q.Text, q.Text, "%"+q.Text+"%")
+		score = "GREATEST(similarity(" + patientNameExpr + ", ?), word_similarity(?, " + patientNameExpr + "))"
+		scoreArgs = []interface{}{q.Text, q.Text}
+	}
+
+	if q.BirthDate != nil {
+		tx = tx.Where("patients.birth_date = ?", q.BirthDate.Format("2006-01-02"))
+	}
+	if q.Phone != "" {
+		tx = tx.Where(patientPhoneExpr+" LIKE ?", "%"+q.Phone+"%")
+	}
+
+	var total int64
+	if err := tx.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []models.PatientSearchHit
+	err := tx.Select("patients.*, "+score+" AS score", scoreArgs...).
+		Order("score DESC, patients.full_name ASC, patients.patient_id ASC").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Find(&hits).Error
+	return hits, total, err
 }
 
+// FindByPassport ищет действующего пациента по слепому индексу паспорта.
 func (r *patientRepo) FindByPassport(series, number string) (*models.Patient, error) {
+	index, err := models.PassportBlindIndex(series, number)
+	if err != nil {
+		return nil, err
+	}
 	var patient models.Patient
-	if err := r.db.Where("passport_series = ? AND passport_number = ?", series, number).First(&patient).Error; err != nil {
+	if err := r.db.Where(activePatient).Where("passport_index = ?", index).First(&patient).Error; err != nil {
 		return nil, err
 	}
 	return &patient, nil
 }
 
-func (r *patientRepo) FindByPhone(phone string) (*models.Patient, error) {
+// FindAllByPhone возвращает всех пациентов с указанным номером телефона (сравниваются только цифры).
+// Одним номером может пользоваться несколько членов семьи.
+func (r *patientRepo) FindAllByPhone(phone string) ([]models.Patient, error) {
+	var patients []models.Patient
+	err := r.db.Where(activePatient).Where("regexp_replace(phone, '[^0-9]+', '', 'g') = ?", phone).
+		Order("patient_id asc").
+		Find(&patients).Error
+	return patients, err
+}
+
+// FindByOMS ищет действующего пациента по слепому индексу полиса ОМС.
+func (r *patientRepo) FindByOMS(omsNumber string) (*models.Patient, error) {
+	index, err := models.OMSBlindIndex(omsNumber)
+	if err != nil {
+		return nil, err
+	}
+	var patient models.Patient
+	if err := r.db.Where(activePatient).Where("oms_index = ?", index).First(&patient).Error; err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+// GetByID возвращает действующую карточку пациента. Удаленные и объединенные карточки не возвращаются.
+func (r *patientRepo) GetByID(id uint) (*models.Patient, error) {
 	var patient models.Patient
-	if err := r.db.Where("regexp_replace(phone, '[^0-9]+', '', 'g') = ?", phone).First(&patient).Error; err != nil {
+	if err := r.db.Where(activePatient).First(&patient, id).Error; err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+// Update изменяет переданные (непустые) поля действующей карточки пациента. Карточка блокируется и
+// перечитывается перед изменением, а обновляются только переданные столбцы, поэтому параллельное
+// редактирование других полей не перезаписывается. Документы перешифровываются вместе, так как
+// шифруются одним ключом.
+func (r *patientRepo) Update(id uint, req *models.UpdatePatientRequest) (*models.Patient, error) {
+	var patient models.Patient
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(activePatient).First(&patient, id).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.FullName != "" {
+			patient.FullName = req.FullName
+			updates["full_name"] = req.FullName
+		}
+		if req.BirthDate != nil {
+			patient.BirthDate = *req.BirthDate
+			updates["birth_date"] = *req.BirthDate
+		}
+		if req.Phone != "" {
+			patient.Phone = req.Phone
+			updates["phone"] = req.Phone
+		}
+		if req.PassportSeries != "" || req.PassportNumber != "" || req.OmsNumber != "" {
+			if req.PassportSeries != "" {
+				patient.PassportSeries = req.PassportSeries
+			}
+			if req.PassportNumber != "" {
+				patient.PassportNumber = req.PassportNumber
+			}
+			if req.OmsNumber != "" {
+				patient.OmsNumber = req.OmsNumber
+			}
+			columns, err := patient.EncryptedColumns()
+			if err != nil {
+				return err
+			}
+			for column, value := range columns {
+				updates[column] = value
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Session(&gorm.Session{SkipHooks: true}).Model(&models.Patient{}).
+			Where("patient_id = ?", id).
+			Updates(updates).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &patient, nil
 }
+
+// FindDuplicateCandidates возвращает действующие карточки с тем же полисом ОМС или той же датой рождения.
+// Окончательное сравнение ФИО выполняет сервис.
+func (r *patientRepo) FindDuplicateCandidates(patient *models.Patient) ([]models.Patient, error) {
+	omsIndex, err := models.OMSBlindIndex(patient.OmsNumber)
+	if err != nil {
+		return nil, err
+	}
+	var patients []models.Patient
+	err = r.db.Where(activePatient).
+		Where("patient_id <> ?", patient.ID).
+		Where("oms_index = ? OR birth_date = ?", omsIndex, patient.BirthDate.Format("2006-01-02")).
+		Order("patient_id asc").
+		Find(&patients).Error
+	return patients, err
+}
+
+// FindMerges возвращает журнал объединений, в которых карточка была основной или дубликатом.
+func (r *patientRepo) FindMerges(patientID uint) ([]models.PatientMerge, error) {
+	var merges []models.PatientMerge
+	err := r.db.Where("target_patient_id = ? OR source_patient_id = ?", patientID, patientID).
+		Order("merged_at desc").
+		Find(&merges).Error
+	return merges, err
+}
+
+// Merge в одной транзакции переносит записи, заявки листа ожидания и направления дубликата
+// в основную карточку, помечает дубликат удаленным и сохраняет журнал объединения.
+func (r *patientRepo) Merge(sourceID, targetID uint, actor, reason string) (*models.PatientMerge, error) {
+	var merge models.PatientMerge
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Карточки блокируются в порядке ID, чтобы встречные объединения не взаимоблокировались.
+		var locked []models.Patient
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(activePatient).
+			Where("patient_id IN ?", []uint{sourceID, targetID}).
+			Order("patient_id asc").
+			Find(&locked).Error; err != nil {
+			return err
+		}
+		var source *models.Patient
+		found := map[uint]bool{}
+		for i := range locked {
+			found[locked[i].ID] = true
+			if locked[i].ID == sourceID {
+				source = &locked[i]
+			}
+		}
+		for _, id := range []uint{sourceID, targetID} {
+			if !found[id] {
+				return NewError(ErrNotFound, "пациент с ID %d не найден", id)
+			}
+		}
+
+		moved := func(model interface{}) (int, error) {
+			res := tx.Model(model).Where("patient_id = ?", sourceID).Update("patient_id", targetID)
+			return int(res.RowsAffected), res.Error
+		}
+		var err error
+		if merge.MovedAppointments, err = moved(&models.Appointment{}); err != nil {
+			return err
+		}
+		if merge.MovedWaitlistEntries, err = moved(&models.WaitlistEntry{}); err != nil {
+			return err
+		}
+		if merge.MovedReferrals, err = moved(&models.Referral{}); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		deleteReason := fmt.Sprintf("объединен с пациентом %d", targetID)
+		if err := tx.Model(&models.Patient{}).Where("patient_id = ?", sourceID).Updates(map[string]interface{}{
+			"deleted_at":     now,
+			"deleted_by":     actor,
+			"delete_reason":  deleteReason,
+			"merged_into_id": targetID,
+		}).Error; err != nil {
+			return err
+		}
+
+		merge.SourcePatientID = sourceID
+		merge.TargetPatientID = targetID
+		merge.Reason = reason
+		merge.MergedBy = actor
+		merge.SourceSnapshot = models.PatientSnapshot(*source)
+		merge.MergedAt = now
+		return tx.Create(&merge).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// SoftDelete помечает карточку пациента удаленной. Карточку с предстоящими записями
+// или активными заявками листа ожидания удалить нельзя.
+func (r *patientRepo) SoftDelete(id uint, actor, reason string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var patient models.Patient
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(activePatient).First(&patient, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return NewError(ErrNotFound, "пациент с ID %d не найден", id)
+			}
+			return err
+		}
+
+		var upcoming int64
+		if err := tx.Model(&models.Appointment{}).
+			Joins("JOIN schedules ON schedules.schedule_id = appointments.schedule_id").
+			Where("appointments.patient_id = ? AND appointments.status = ? AND schedules.date >= ?",
+				id, models.AppointmentBooked, time.Now().Format("2006-01-02")).
+			Count(&upcoming).Error; err != nil {
+			return err
+		}
+		if upcoming > 0 {
+			return NewError(ErrConflict, "у пациента есть предстоящие записи (%d), отмените их перед удалением", upcoming)
+		}
+
+		var waiting int64
+		if err := tx.Model(&models.WaitlistEntry{}).
+			Where("patient_id = ? AND status IN ?", id, []models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered}).
+			Count(&waiting).Error; err != nil {
+			return err
+		}
+		if waiting > 0 {
+			return NewError(ErrConflict, "пациент стоит в листе ожидания (%d), снимите заявки перед удалением", waiting)
+		}
+
+		return tx.Model(&patient).Updates(map[string]interface{}{
+			"deleted_at":    time.Now(),
+			"deleted_by":    actor,
+			"delete_reason": reason,
+		}).Error
+	})
+}
+
+// ReencryptPatients перешифровывает активным ключом до batch карточек, которые еще хранят
+// документы в открытом виде или зашифрованы другим ключом. Возвращает число обработанных карточек.
+// Каждая карточка блокируется и перечитывается перед изменением, а обновляются только столбцы документов,
+// поэтому параллельное редактирование карточки не перезаписывается.
+func (r *patientRepo) ReencryptPatients(batch int) (int, error) {
+	keyID, err := models.PIIActiveKeyID()
+	if err != nil {
+		return 0, err
+	}
+	stale := func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("(oms_number_enc IS NULL OR split_part(oms_number_enc, ':', 1) <> ? OR "+
+			"split_part(passport_series_enc, ':', 1) <> ? OR split_part(passport_number_enc, ':', 1) <> ?)", keyID, keyID, keyID)
+	}
+
+	var ids []uint
+	if err := stale(r.db.Model(&models.Patient{})).
+		Order("patient_id asc").
+		Limit(batch).
+		Pluck("patient_id", &ids).Error; err != nil {
+		return 0, err
+	}
+	for i, id := range ids {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			var patient models.Patient
+			err := stale(tx.Clauses(clause.Locking{Strength: "UPDATE"})).Where("patient_id = ?", id).First(&patient).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Карточка уже сохранена активным ключом параллельным запросом.
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			columns, err := patient.EncryptedColumns()
+			if err != nil {
+				return err
+			}
+			return tx.Session(&gorm.Session{SkipHooks: true}).Model(&models.Patient{}).
+				Where("patient_id = ?", id).
+				Updates(columns).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("пациент %d: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// LogIdentifierAccess сохраняет запись о раскрытии документов пациента.
+func (r *patientRepo) LogIdentifierAccess(entry *models.PIIAccessLog) error {
+	return r.db.Create(entry).Error
+}
+
+// FindIdentifierAccess возвращает журнал раскрытия документов пациента, начиная с последних записей.
+func (r *patientRepo) FindIdentifierAccess(patientID uint) ([]models.PIIAccessLog, error) {
+	var entries []models.PIIAccessLog
+	err := r.db.Where("patient_id = ?", patientID).Order("created_at desc").Find(&entries).Error
+	return entries, err
+}
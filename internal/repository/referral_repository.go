@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"ElectronicQueue/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type referralRepo struct {
+	db *gorm.DB
+}
+
+func NewReferralRepository(db *gorm.DB) ReferralRepository {
+	return &referralRepo{db: db}
+}
+
+func (r *referralRepo) Create(referral *models.Referral) error {
+	return r.db.Create(referral).Error
+}
+
+func (r *referralRepo) Delete(id uint) error {
+	return r.db.Delete(&models.Referral{}, id).Error
+}
+
+func (r *referralRepo) GetByID(id uint) (*models.Referral, error) {
+	var referral models.Referral
+	if err := r.db.First(&referral, id).Error; err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// FindByPatient возвращает все направления пациента, начиная с новых.
+func (r *referralRepo) FindByPatient(patientID uint) ([]models.Referral, error) {
+	var referrals []models.Referral
+	if err := r.db.Where("patient_id = ?", patientID).Order("issued_at desc, referral_id desc").Find(&referrals).Error; err != nil {
+		return nil, err
+	}
+	return referrals, nil
+}
+
+// FindValid возвращает неиспользованное направление пациента к специальности, действующее на дату приема.
+// Если подходящих направлений несколько, выбирается то, что истекает раньше.
+func (r *referralRepo) FindValid(patientID uint, specialization string, date time.Time) (*models.Referral, error) {
+	var referral models.Referral
+	day := date.Format("2006-01-02")
+	err := r.db.Where("patient_id = ? AND LOWER(specialization) = LOWER(?) AND appointment_id IS NULL", patientID, specialization).
+		Where("issued_at <= ? AND valid_until >= ?", day, day).
+		Order("valid_until asc").
+		First(&referral).Error
+	if err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// AttachToAppointment отмечает направление использованным для записи.
+func (r *referralRepo) AttachToAppointment(referralID, appointmentID uint) error {
+	result := r.db.Model(&models.Referral{}).
+		Where("referral_id = ? AND appointment_id IS NULL", referralID).
+		Update("appointment_id", appointmentID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return NewError(ErrConflict, "направление уже использовано")
+	}
+	return nil
+}
+
+// MoveToAppointment переносит отметку об использовании направления с одной записи на другую.
+// Если toAppointmentID не указан, направление снова становится доступным для записи.
+func (r *referralRepo) MoveToAppointment(fromAppointmentID uint, toAppointmentID *uint) error {
+	return r.db.Model(&models.Referral{}).
+		Where("appointment_id = ?", fromAppointmentID).
+		Update("appointment_id", toAppointmentID).Error
+}
@@ -29,9 +29,19 @@ type DoctorRepository interface {
 // PatientRepository определяет методы для взаимодействия с данными пациентов.
 type PatientRepository interface {
 	Create(patient *models.Patient) (*models.Patient, error)
-	Search(query string) ([]models.Patient, error)
+	Search(query models.PatientSearchQuery) ([]models.PatientSearchHit, int64, error)
 	FindByPassport(series, number string) (*models.Patient, error)
-	FindByPhone(phone string) (*models.Patient, error)
+	FindAllByPhone(phone string) ([]models.Patient, error)
+	FindByOMS(omsNumber string) (*models.Patient, error)
+	GetByID(id uint) (*models.Patient, error)
+	Update(id uint, req *models.UpdatePatientRequest) (*models.Patient, error)
+	FindDuplicateCandidates(patient *models.Patient) ([]models.Patient, error)
+	FindMerges(patientID uint) ([]models.PatientMerge, error)
+	Merge(sourceID, targetID uint, actor, reason string) (*models.PatientMerge, error)
+	SoftDelete(id uint, actor, reason string) error
+	ReencryptPatients(batch int) (int, error)
+	LogIdentifierAccess(entry *models.PIIAccessLog) error
+	FindIdentifierAccess(patientID uint) ([]models.PIIAccessLog, error)
 }
 
 // TicketRepository определяет методы для взаимодействия с талонами.
@@ -55,26 +65,112 @@ type TicketRepository interface {
 type ScheduleRepository interface {
 	Create(schedule *models.Schedule) error
 	Update(schedule *models.Schedule) error
+	UpdateFields(id uint, changes map[string]interface{}, guard func(appointment *models.Appointment, held bool) error) error
 	Delete(id uint) error
 	GetByID(id uint) (*models.Schedule, error)
 	FindByDoctorAndDate(doctorID uint, date time.Time) ([]models.Schedule, error)
 	FindByCabinetAndCurrentTime(cabinetNumber int) (*models.Schedule, error)
-	GetAllUniqueCabinets() ([]int, error)
 	FindFirstScheduleForCabinetByDay(cabinetNumber int) (*models.Schedule, error)
 	FindAllSchedulesForDate(date time.Time) ([]models.Schedule, error)
 	FindMinMaxTimesForDate(date time.Time) (time.Time, time.Time, error)
+	CreateBatchSkipExisting(schedules []models.Schedule) (int64, error)
+	FindInRange(doctorID *uint, from, to time.Time) ([]models.Schedule, error)
+	FindFreeBySpecialization(specialization string, from, to time.Time) ([]models.Schedule, error)
+	FindForConflictCheck(dates []string, doctorIDs []uint, cabinets []int) ([]models.Schedule, error)
+	CancelSlots(req *models.CancelSchedulesRequest, targetSlotIDs []uint, actor string) (*models.CancelSchedulesResult, error)
+	ShiftSlots(req *models.ShiftSchedulesRequest, shift time.Duration) (*models.ShiftSchedulesResult, error)
+}
+
+// ScheduleTemplateRepository определяет методы для работы с шаблонами расписания.
+type ScheduleTemplateRepository interface {
+	Create(template *models.ScheduleTemplate) error
+	Update(template *models.ScheduleTemplate) error
+	Delete(id uint) error
+	GetByID(id uint) (*models.ScheduleTemplate, error)
+	GetAll() ([]models.ScheduleTemplate, error)
+	FindActiveForRange(from, to time.Time) ([]models.ScheduleTemplate, error)
+}
+
+// CabinetRepository определяет методы для работы с кабинетами клиники.
+type CabinetRepository interface {
+	Create(cabinet *models.Cabinet) error
+	Update(cabinet *models.Cabinet) error
+	Delete(number int) error
+	GetByNumber(number int) (*models.Cabinet, error)
+	GetAll(onlyActive bool) ([]models.Cabinet, error)
+	CountUsage(number int) (int64, error)
+}
+
+// CalendarRepository определяет методы для работы с календарем клиники и отсутствиями врачей.
+type CalendarRepository interface {
+	SaveClinicDay(day *models.ClinicDay) error
+	DeleteClinicDay(date time.Time) error
+	GetClinicDay(date time.Time) (*models.ClinicDay, error)
+	FindClinicDays(from, to time.Time) ([]models.ClinicDay, error)
+	CreateAbsence(absence *models.DoctorAbsence) error
+	DeleteAbsence(id uint) error
+	GetAbsenceByID(id uint) (*models.DoctorAbsence, error)
+	FindAbsences(doctorID *uint, from, to time.Time) ([]models.DoctorAbsence, error)
+	FindAppointmentsInRange(doctorID *uint, from, to time.Time) ([]models.Appointment, error)
+	SetAppointmentFlag(appointmentIDs []uint, source, reason string) error
+	ClearAppointmentFlag(source string) error
 }
 
 // AppointmentRepository определяет методы для взаимодействия с записями на прием.
 type AppointmentRepository interface {
 	CreateAppointmentInTransaction(req *models.CreateAppointmentRequest) (*models.Appointment, error)
+	CreateSelfBookingInTransaction(req *models.CreateAppointmentRequest, confirmationCode string, limits models.BookingLimits) (*models.Appointment, error)
 	FindScheduleAndAppointmentsByDoctorAndDate(doctorID uint, date time.Time) ([]models.ScheduleWithAppointmentInfo, error)
 	FindByID(id uint) (*models.Appointment, error)
+	FindByScheduleID(scheduleID uint) (*models.Appointment, error)
 	FindByPatientID(patientID uint) ([]models.Appointment, error)
 	Update(appointment *models.Appointment) error
-	DeleteAppointmentAndFreeSlot(appointmentID uint) error
-	FindUpcomingByPatientID(patientID uint, now time.Time) (*models.Appointment, error)
-	AssignTicketToAppointment(appointment *models.Appointment, ticket *models.Ticket) error
+	CancelAppointment(appointmentID uint, reason, actor string) (*models.Appointment, error)
+	RescheduleAppointment(appointmentID uint, req *models.RescheduleAppointmentRequest, actor string) (*models.Appointment, *models.Appointment, error)
+	FindForCheckIn(patientID uint, date time.Time) ([]models.Appointment, error)
+	FindByConfirmationCode(code string) (*models.Appointment, error)
+	AssignTicketsToAppointments(appointments []models.Appointment, tickets []*models.Ticket) error
+	FindByTicketID(ticketID uint) (*models.Appointment, error)
+	SaveOutcome(appointment *models.Appointment, referral *models.Referral) error
+	MarkNoShows(before time.Time) ([]uint, error)
+}
+
+// WaitlistRepository определяет методы для работы с листом ожидания и предложениями освободившихся слотов.
+type WaitlistRepository interface {
+	CreateEntry(entry *models.WaitlistEntry) error
+	GetEntryByID(id uint) (*models.WaitlistEntry, error)
+	FindEntries(status *models.WaitlistStatus, doctorID *uint) ([]models.WaitlistEntry, error)
+	UpdateEntryStatus(id uint, status models.WaitlistStatus) error
+	GetOfferByID(id uint) (*models.WaitlistOffer, error)
+	FindOffers(status *models.WaitlistOfferStatus) ([]models.WaitlistOffer, error)
+	FindPendingOfferByEntry(entryID uint) (*models.WaitlistOffer, error)
+	FindExpiredOffers(now time.Time) ([]models.WaitlistOffer, error)
+	OfferSlot(scheduleID uint, heldUntil time.Time) (*models.WaitlistOffer, error)
+	ReleaseSlot(scheduleID uint) error
+	AcceptOffer(offerID uint, req *models.AcceptOfferRequest) (*models.WaitlistOffer, *models.Appointment, error)
+	ResolveOffer(offerID uint, status models.WaitlistOfferStatus) (*models.WaitlistOffer, error)
+}
+
+// BookingRuleRepository определяет методы для работы с правилами записи по специальностям.
+type BookingRuleRepository interface {
+	Create(rule *models.BookingRule) error
+	Update(rule *models.BookingRule) error
+	Delete(id uint) error
+	GetByID(id uint) (*models.BookingRule, error)
+	GetAll() ([]models.BookingRule, error)
+	FindActiveBySpecialization(specialization string) (*models.BookingRule, error)
+	FindPatientAppointmentsBySpecialization(patientID uint, specialization string) ([]models.Appointment, error)
+}
+
+// ReferralRepository определяет методы для работы с направлениями пациентов.
+type ReferralRepository interface {
+	Create(referral *models.Referral) error
+	Delete(id uint) error
+	GetByID(id uint) (*models.Referral, error)
+	FindByPatient(patientID uint) ([]models.Referral, error)
+	FindValid(patientID uint, specialization string, date time.Time) (*models.Referral, error)
+	AttachToAppointment(referralID, appointmentID uint) error
+	MoveToAppointment(fromAppointmentID uint, toAppointmentID *uint) error
 }
 
 // RegistrarRepository определяет методы для аутентификации регистраторов.
@@ -103,7 +199,7 @@ type ServiceRepository interface {
 type CleanupRepository interface {
 	TruncateTickets() error
 	GetTicketsCount() (int64, error)
-	GetOrphanedAppointmentsCount() (int64, error)
+	GetMissedAppointmentsCount() (int64, error)
 }
 
 // BusinessProcessRepository определяет методы для управления бизнес-процессами.
@@ -123,36 +219,68 @@ type AdRepository interface {
 	Delete(id uint) error
 }
 
+// APIKeyRepository определяет методы для работы с API-ключами интеграторов.
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	Update(key *models.APIKey) error
+	GetAll() ([]models.APIKey, error)
+	GetByID(id uint) (*models.APIKey, error)
+	FindByHash(hash string) (*models.APIKey, error)
+	TouchLastUsed(id uint, usedAt time.Time) error
+}
+
+// AuditRepository определяет методы для работы с журналом аудита.
+type AuditRepository interface {
+	Create(entry *models.AuditLog) error
+	Find(filter models.AuditLogFilter) ([]models.AuditLog, int64, error)
+}
+
 // Repository содержит все репозитории приложения.
 type Repository struct {
-	Doctor          DoctorRepository
-	Patient         PatientRepository
-	Ticket          TicketRepository
-	Schedule        ScheduleRepository
-	Appointment     AppointmentRepository
-	Service         ServiceRepository
-	Registrar       RegistrarRepository
-	Administrator   AdministratorRepository
-	Cleanup         CleanupRepository
-	BusinessProcess BusinessProcessRepository
-	ReceptionLog    ReceptionLogRepository
-	Ad              AdRepository
+	Doctor           DoctorRepository
+	Patient          PatientRepository
+	Ticket           TicketRepository
+	Schedule         ScheduleRepository
+	ScheduleTemplate ScheduleTemplateRepository
+	Calendar         CalendarRepository
+	Cabinet          CabinetRepository
+	Appointment      AppointmentRepository
+	Waitlist         WaitlistRepository
+	BookingRule      BookingRuleRepository
+	Referral         ReferralRepository
+	Service          ServiceRepository
+	Registrar        RegistrarRepository
+	Administrator    AdministratorRepository
+	Cleanup          CleanupRepository
+	BusinessProcess  BusinessProcessRepository
+	ReceptionLog     ReceptionLogRepository
+	Ad               AdRepository
+	APIKey           APIKeyRepository
+	Audit            AuditRepository
 }
 
 // NewRepository создает новый экземпляр главного репозитория.
 func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{
-		Doctor:          NewDoctorRepository(db),
-		Patient:         NewPatientRepository(db),
-		Ticket:          NewTicketRepository(db),
-		Schedule:        NewScheduleRepository(db),
-		Appointment:     NewAppointmentRepository(db),
-		Service:         NewServiceRepository(db),
-		Registrar:       NewRegistrarRepository(db),
-		Administrator:   NewAdministratorRepository(db),
-		Cleanup:         NewCleanupRepository(db),
-		BusinessProcess: NewBusinessProcessRepository(db),
-		ReceptionLog:    NewReceptionLogRepository(db),
-		Ad:              NewAdRepository(db),
+		Doctor:           NewDoctorRepository(db),
+		Patient:          NewPatientRepository(db),
+		Ticket:           NewTicketRepository(db),
+		Schedule:         NewScheduleRepository(db),
+		ScheduleTemplate: NewScheduleTemplateRepository(db),
+		Calendar:         NewCalendarRepository(db),
+		Cabinet:          NewCabinetRepository(db),
+		Appointment:      NewAppointmentRepository(db),
+		Waitlist:         NewWaitlistRepository(db),
+		BookingRule:      NewBookingRuleRepository(db),
+		Referral:         NewReferralRepository(db),
+		Service:          NewServiceRepository(db),
+		Registrar:        NewRegistrarRepository(db),
+		Administrator:    NewAdministratorRepository(db),
+		Cleanup:          NewCleanupRepository(db),
+		BusinessProcess:  NewBusinessProcessRepository(db),
+		ReceptionLog:     NewReceptionLogRepository(db),
+		Ad:               NewAdRepository(db),
+		APIKey:           NewAPIKeyRepository(db),
+		Audit:            NewAuditRepository(db),
 	}
 }
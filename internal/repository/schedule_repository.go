@@ -2,9 +2,11 @@ package repository
 
 import (
 	"ElectronicQueue/internal/models"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type scheduleRepo struct {
@@ -20,7 +22,49 @@ func (r *scheduleRepo) Create(schedule *models.Schedule) error {
 }
 
 func (r *scheduleRepo) Update(schedule *models.Schedule) error {
-	return r.db.Save(schedule).Error
+	return r.db.Omit("Doctor").Save(schedule).Error
+}
+
+// UpdateFields изменяет только переданные столбцы слота в транзакции. Слот блокируется, после чего guard
+// получает действующую запись на слот (или nil) и признак удержания по предложению из листа ожидания
+// и может отклонить изменение.
+func (r *scheduleRepo) UpdateFields(id uint, changes map[string]interface{}, guard func(appointment *models.Appointment, held bool) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var schedule models.Schedule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return NewError(ErrNotFound, "слот расписания с ID %d не найден", id)
+			}
+			return err
+		}
+
+		var appointment *models.Appointment
+		var app models.Appointment
+		err := tx.Preload("Patient").
+			Where("schedule_id = ? AND status IN ?", id, models.OccupyingAppointmentStatuses).
+			First(&app).Error
+		switch {
+		case err == nil:
+			appointment = &app
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		var offers int64
+		if err := tx.Model(&models.WaitlistOffer{}).
+			Where("schedule_id = ? AND status = ?", id, models.OfferPending).
+			Count(&offers).Error; err != nil {
+			return err
+		}
+
+		if err := guard(appointment, offers > 0); err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return tx.Model(&schedule).Updates(changes).Error
+	})
 }
 
 func (r *scheduleRepo) GetByID(id uint) (*models.Schedule, error) {
@@ -77,22 +121,6 @@ func (r *scheduleRepo) FindFirstScheduleForCabinetByDay(cabinetNumber int) (*mod
 	return &schedule, err
 }
 
-// GetAllUniqueCabinets возвращает отсортированный список всех уникальных номеров кабинетов.
-func (r *scheduleRepo) GetAllUniqueCabinets() ([]int, error) {
-	var cabinets []int
-
-	err := r.db.Model(&models.Schedule{}).
-		Distinct().
-		Where("cabinet IS NOT NULL").
-		Order("cabinet asc").
-		Pluck("cabinet", &cabinets).Error
-
-	if err != nil {
-		return nil, err
-	}
-	return cabinets, nil
-}
-
 func (r *scheduleRepo) Delete(id uint) error {
 	return r.db.Delete(&models.Schedule{}, id).Error
 }
@@ -148,3 +176,283 @@ func (r *scheduleRepo) FindMinMaxTimesForDate(date time.Time) (time.Time, time.T
 
 	return minTime, maxTime, nil
 }
+
+// CreateBatchSkipExisting вставляет слоты пачками, пропуская те, что уже существуют
+// (конфликт по уникальному индексу врач + дата + время начала). Возвращает число созданных слотов.
+func (r *scheduleRepo) CreateBatchSkipExisting(schedules []models.Schedule) (int64, error) {
+	if len(schedules) == 0 {
+		return 0, nil
+	}
+	result := r.db.Omit("Doctor").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(&schedules, 200)
+	return result.RowsAffected, result.Error
+}
+
+// cancelSlotsReason - причина переноса записей при отмене приема врача.
+const cancelSlotsReason = "отмена приема врача"
+
+// errDryRun используется для отката транзакции в режиме предварительного просмотра.
+var errDryRun = errors.New("dry run")
+
+// FindInRange возвращает слоты за период [from, to] включительно, при doctorID != nil - только указанного врача.
+func (r *scheduleRepo) FindInRange(doctorID *uint, from, to time.Time) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	query := r.db.Where("date >= ? AND date <= ?", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if doctorID != nil {
+		query = query.Where("doctor_id = ?", *doctorID)
+	}
+	if err := query.Order("date asc, doctor_id asc, start_time asc").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// FindFreeBySpecialization возвращает свободные слоты всех врачей специальности за период [from, to] вместе с врачом.
+func (r *scheduleRepo) FindFreeBySpecialization(specialization string, from, to time.Time) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.db.Preload("Doctor").
+		Joins("JOIN doctors ON doctors.doctor_id = schedules.doctor_id").
+		Where("schedules.is_available = ? AND schedules.date >= ? AND schedules.date <= ?", true, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Where("LOWER(doctors.specialization) = LOWER(?)", specialization).
+		Order("schedules.date asc, schedules.start_time asc, schedules.doctor_id asc").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// CancelSlots отменяет прием врача за период в одной транзакции.
+// Свободные слоты удаляются. Записи пациентов переносятся так же, как при ручном переносе (с записью
+// "перенесен" и автором actor), на свободный слот того же дня и времени из targetSlotIDs; если перенести
+// не удалось, слот остается заблокированным, а пациент попадает в список для обзвона.
+// В режиме DryRun изменения откатываются.
+func (r *scheduleRepo) CancelSlots(req *models.CancelSchedulesRequest, targetSlotIDs []uint, actor string) (*models.CancelSchedulesResult, error) {
+	result := &models.CancelSchedulesResult{DryRun: req.DryRun, Appointments: []models.AffectedAppointment{}}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("doctor_id = ? AND date >= ? AND date <= ?", req.DoctorID, req.DateFrom.Format("2006-01-02"), req.DateTo.Format("2006-01-02"))
+		if req.StartTime != nil {
+			query = query.Where("start_time >= ?", *req.StartTime)
+		}
+		if req.EndTime != nil {
+			query = query.Where("start_time < ?", *req.EndTime)
+		}
+
+		var slots []models.Schedule
+		if err := query.Order("date asc, start_time asc").Find(&slots).Error; err != nil {
+			return err
+		}
+
+		for _, slot := range slots {
+			var app models.Appointment
+			err := tx.Preload("Patient").
+				Where("schedule_id = ? AND status IN ?", slot.ID, models.OccupyingAppointmentStatuses).
+				First(&app).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				deleted, err := removeOrBlockSlot(tx, slot.ID)
+				if err != nil {
+					return err
+				}
+				if deleted {
+					result.DeletedSlots++
+				} else {
+					result.BlockedSlots++
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			affected := models.AffectedAppointment{
+				AppointmentID:   app.ID,
+				PatientID:       app.PatientID,
+				PatientFullName: app.Patient.FullName,
+				PatientPhone:    app.Patient.Phone,
+				Date:            slot.Date.Format("2006-01-02"),
+				StartTime:       slot.StartTime,
+				Action:          models.AffectedActionNeedsCall,
+			}
+
+			if len(targetSlotIDs) > 0 && app.Status == models.AppointmentBooked {
+				var target models.Schedule
+				err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+					Where("schedule_id IN ? AND date = ? AND start_time = ? AND is_available = ?", targetSlotIDs, slot.Date.Format("2006-01-02"), slot.StartTime, true).
+					Order("doctor_id asc").
+					First(&target).Error
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+				if err == nil {
+					if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&app, app.ID).Error; err != nil {
+						return err
+					}
+					moved, err := moveAppointment(tx, &app, &target, &models.RescheduleAppointmentRequest{ScheduleID: target.ID, Reason: cancelSlotsReason}, actor)
+					if err != nil {
+						return err
+					}
+					deleted, err := removeOrBlockSlot(tx, slot.ID)
+					if err != nil {
+						return err
+					}
+					affected.Action = models.AffectedActionMoved
+					affected.NewAppointmentID = &moved.ID
+					affected.NewDoctorID = &target.DoctorID
+					affected.NewScheduleID = &target.ID
+					affected.NewStartTime = &target.StartTime
+					result.MovedCount++
+					if deleted {
+						result.DeletedSlots++
+					} else {
+						result.BlockedSlots++
+					}
+					result.Appointments = append(result.Appointments, affected)
+					continue
+				}
+			}
+
+			if slot.IsAvailable {
+				if err := tx.Model(&models.Schedule{}).Where("schedule_id = ?", slot.ID).Update("is_available", false).Error; err != nil {
+					return err
+				}
+			}
+			result.BlockedSlots++
+			result.NeedsCallCount++
+			result.Appointments = append(result.Appointments, affected)
+		}
+
+		if req.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// removeOrBlockSlot удаляет слот без записей. Слот, на который ссылаются записи из истории
+// (отмененные или перенесенные), удалить нельзя без потери истории, поэтому он блокируется.
+func removeOrBlockSlot(tx *gorm.DB, slotID uint) (bool, error) {
+	var history int64
+	if err := tx.Model(&models.Appointment{}).Where("schedule_id = ?", slotID).Count(&history).Error; err != nil {
+		return false, err
+	}
+	if history > 0 {
+		return false, tx.Model(&models.Schedule{}).Where("schedule_id = ?", slotID).Update("is_available", false).Error
+	}
+	return true, tx.Delete(&models.Schedule{}, slotID).Error
+}
+
+// ShiftSlots сдвигает слоты врача за день на shift в одной транзакции.
+// Слоты обновляются в порядке, при котором они не пересекаются друг с другом по уникальному индексу;
+// совпадение с несдвигаемым слотом приводит к ошибке и откату. В режиме DryRun изменения откатываются.
+func (r *scheduleRepo) ShiftSlots(req *models.ShiftSchedulesRequest, shift time.Duration) (*models.ShiftSchedulesResult, error) {
+	result := &models.ShiftSchedulesResult{DryRun: req.DryRun, Appointments: []models.AffectedAppointment{}}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("doctor_id = ? AND date = ?", req.DoctorID, req.Date.Format("2006-01-02"))
+		if req.FromTime != nil {
+			query = query.Where("start_time >= ?", *req.FromTime)
+		}
+		if req.ToTime != nil {
+			query = query.Where("start_time < ?", *req.ToTime)
+		}
+
+		order := "start_time asc"
+		if shift > 0 {
+			order = "start_time desc"
+		}
+
+		var slots []models.Schedule
+		if err := query.Order(order).Find(&slots).Error; err != nil {
+			return err
+		}
+
+		const layout = "15:04:05"
+		for _, slot := range slots {
+			start, err := time.Parse(layout, slot.StartTime)
+			if err != nil {
+				return err
+			}
+			end, err := time.Parse(layout, slot.EndTime)
+			if err != nil {
+				return err
+			}
+			newStart, newEnd := start.Add(shift), end.Add(shift)
+			if newStart.Day() != start.Day() || newEnd.Day() != end.Day() {
+				return NewError(ErrInvalidInput, "слот %s-%s после сдвига выходит за пределы суток", slot.StartTime, slot.EndTime)
+			}
+
+			newStartStr := newStart.Format(layout)
+			err = tx.Model(&models.Schedule{}).Where("schedule_id = ?", slot.ID).
+				Updates(map[string]interface{}{"start_time": newStartStr, "end_time": newEnd.Format(layout)}).Error
+			if err != nil {
+				if IsUniqueViolation(err) {
+					return NewError(ErrConflict, "слот %s после сдвига совпадает с существующим слотом в %s", slot.StartTime, newStartStr)
+				}
+				return err
+			}
+			result.ShiftedSlots++
+
+			if slot.IsAvailable {
+				continue
+			}
+			var app models.Appointment
+			err = tx.Preload("Patient").
+				Where("schedule_id = ? AND status IN ?", slot.ID, models.OccupyingAppointmentStatuses).
+				First(&app).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			result.Appointments = append(result.Appointments, models.AffectedAppointment{
+				AppointmentID:   app.ID,
+				PatientID:       app.PatientID,
+				PatientFullName: app.Patient.FullName,
+				PatientPhone:    app.Patient.Phone,
+				Date:            slot.Date.Format("2006-01-02"),
+				StartTime:       slot.StartTime,
+				Action:          models.AffectedActionTimeChanged,
+				NewStartTime:    &newStartStr,
+			})
+		}
+
+		if req.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindForConflictCheck возвращает слоты на указанные даты, принадлежащие указанным врачам
+// или занимающие указанные кабинеты.
+func (r *scheduleRepo) FindForConflictCheck(dates []string, doctorIDs []uint, cabinets []int) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	if len(dates) == 0 {
+		return schedules, nil
+	}
+	query := r.db.Where("date IN ?", dates)
+	if len(cabinets) > 0 {
+		query = query.Where("doctor_id IN ? OR cabinet IN ?", doctorIDs, cabinets)
+	} else {
+		query = query.Where("doctor_id IN ?", doctorIDs)
+	}
+	if err := query.Order("date asc, start_time asc").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"ElectronicQueue/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type scheduleTemplateRepo struct {
+	db *gorm.DB
+}
+
+func NewScheduleTemplateRepository(db *gorm.DB) ScheduleTemplateRepository {
+	return &scheduleTemplateRepo{db: db}
+}
+
+func (r *scheduleTemplateRepo) Create(template *models.ScheduleTemplate) error {
+	return r.db.Create(template).Error
+}
+
+func (r *scheduleTemplateRepo) Update(template *models.ScheduleTemplate) error {
+	return r.db.Save(template).Error
+}
+
+func (r *scheduleTemplateRepo) Delete(id uint) error {
+	return r.db.Delete(&models.ScheduleTemplate{}, id).Error
+}
+
+func (r *scheduleTemplateRepo) GetByID(id uint) (*models.ScheduleTemplate, error) {
+	var template models.ScheduleTemplate
+	if err := r.db.First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *scheduleTemplateRepo) GetAll() ([]models.ScheduleTemplate, error) {
+	var templates []models.ScheduleTemplate
+	if err := r.db.Order("doctor_id ASC, template_id ASC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// FindActiveForRange возвращает активные шаблоны, период действия которых пересекается с [from, to].
+func (r *scheduleTemplateRepo) FindActiveForRange(from, to time.Time) ([]models.ScheduleTemplate, error) {
+	var templates []models.ScheduleTemplate
+	err := r.db.
+		Where("is_active = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to >= ?)", true, to.Format("2006-01-02"), from.Format("2006-01-02")).
+		Order("doctor_id ASC, template_id ASC").
+		Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
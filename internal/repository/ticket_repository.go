@@ -144,8 +144,8 @@ func (r *ticketRepo) FindTicketsForCabinetQueue(cabinetNumber int) ([]models.Doc
 		Joins("JOIN schedules ON schedules.schedule_id = appointments.schedule_id").
 		Joins("JOIN patients ON patients.patient_id = appointments.patient_id").
 		Where("schedules.cabinet = ? AND schedules.date = ? AND tickets.status IN ?",
-			cabinetNumber, today, []string{string(models.StatusInProgress), string(models.StatusRegistered)}).
-		Order("CASE WHEN tickets.status = 'на_приеме' THEN 0 ELSE 1 END, schedules.start_time ASC").
+			cabinetNumber, today, []string{string(models.StatusInProgress), string(models.StatusInvitedToCabinet), string(models.StatusRegistered)}).
+		Order("CASE tickets.status WHEN 'на_приеме' THEN 0 WHEN 'приглашен_в_кабинет' THEN 1 ELSE 2 END, schedules.start_time ASC").
 		Find(&results).Error
 
 	if err != nil {
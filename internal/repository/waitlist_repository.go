@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"ElectronicQueue/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type waitlistRepo struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) WaitlistRepository {
+	return &waitlistRepo{db: db}
+}
+
+func (r *waitlistRepo) CreateEntry(entry *models.WaitlistEntry) error {
+	return r.db.Omit("Patient", "Doctor").Create(entry).Error
+}
+
+func (r *waitlistRepo) GetEntryByID(id uint) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	if err := r.db.Preload("Patient").Preload("Doctor").First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindEntries возвращает заявки листа ожидания в порядке очереди с фильтрацией по статусу и врачу.
+func (r *waitlistRepo) FindEntries(status *models.WaitlistStatus, doctorID *uint) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	query := r.db.Preload("Patient").Preload("Doctor")
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if doctorID != nil {
+		query = query.Where("doctor_id = ?", *doctorID)
+	}
+	if err := query.Order("created_at asc, entry_id asc").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *waitlistRepo) UpdateEntryStatus(id uint, status models.WaitlistStatus) error {
+	return r.db.Model(&models.WaitlistEntry{}).Where("entry_id = ?", id).
+		Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
+}
+
+func (r *waitlistRepo) GetOfferByID(id uint) (*models.WaitlistOffer, error) {
+	var offer models.WaitlistOffer
+	if err := r.db.Preload("Entry.Patient").Preload("Schedule.Doctor").First(&offer, id).Error; err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// FindOffers возвращает предложения слотов, начиная с самых новых. Если статус не указан, возвращаются все.
+func (r *waitlistRepo) FindOffers(status *models.WaitlistOfferStatus) ([]models.WaitlistOffer, error) {
+	var offers []models.WaitlistOffer
+	query := r.db.Preload("Entry.Patient").Preload("Schedule.Doctor")
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if err := query.Order("created_at desc, offer_id desc").Find(&offers).Error; err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+// FindPendingOfferByEntry возвращает действующее предложение по заявке, если оно есть.
+func (r *waitlistRepo) FindPendingOfferByEntry(entryID uint) (*models.WaitlistOffer, error) {
+	var offer models.WaitlistOffer
+	if err := r.db.Where("entry_id = ? AND status = ?", entryID, models.OfferPending).First(&offer).Error; err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// FindExpiredOffers возвращает действующие предложения, срок удержания слота по которым истек к моменту now.
+func (r *waitlistRepo) FindExpiredOffers(now time.Time) ([]models.WaitlistOffer, error) {
+	var offers []models.WaitlistOffer
+	if err := r.db.Where("status = ? AND held_until <= ?", models.OfferPending, now).
+		Order("held_until asc").Find(&offers).Error; err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+// OfferSlot удерживает слот для первой подходящей заявки листа ожидания в рамках одной транзакции.
+// Заявка подходит, если ждет запись к врачу слота или к любому врачу его специальности на дату слота
+// и этот слот ей еще не предлагался. Если подходящей заявки нет, слот освобождается для обычной записи
+// и возвращается nil.
+func (r *waitlistRepo) OfferSlot(scheduleID uint, heldUntil time.Time) (*models.WaitlistOffer, error) {
+	var offer *models.WaitlistOffer
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var schedule models.Schedule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Doctor").First(&schedule, scheduleID).Error; err != nil {
+			return err
+		}
+		busy, err := slotBusy(tx, scheduleID)
+		if err != nil || busy {
+			return err
+		}
+
+		var entry models.WaitlistEntry
+		date := schedule.Date.Format("2006-01-02")
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND date_from <= ? AND date_to >= ?", models.WaitlistWaiting, date, date).
+			Where("doctor_id = ? OR (doctor_id IS NULL AND LOWER(specialization) = LOWER(?))", schedule.DoctorID, schedule.Doctor.Specialization).
+			Where("NOT EXISTS (SELECT 1 FROM waitlist_offers o WHERE o.entry_id = waitlist_entries.entry_id AND o.schedule_id = ?)", scheduleID).
+			Order("created_at asc, entry_id asc").
+			First(&entry).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Model(&models.Schedule{}).Where("schedule_id = ?", scheduleID).Update("is_available", true).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		offer = &models.WaitlistOffer{
+			EntryID:    entry.ID,
+			ScheduleID: scheduleID,
+			Status:     models.OfferPending,
+			HeldUntil:  heldUntil,
+		}
+		if err := tx.Omit("Entry", "Schedule").Create(offer).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.WaitlistEntry{}).Where("entry_id = ?", entry.ID).
+			Updates(map[string]interface{}{"status": models.WaitlistOffered, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Schedule{}).Where("schedule_id = ?", scheduleID).Update("is_available", false).Error
+	})
+	if err != nil || offer == nil {
+		return nil, err
+	}
+	return r.GetOfferByID(offer.ID)
+}
+
+// ReleaseSlot снимает удержание со слота, если он не занят записью и не предложен пациенту из листа ожидания.
+func (r *waitlistRepo) ReleaseSlot(scheduleID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var schedule models.Schedule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, scheduleID).Error; err != nil {
+			return err
+		}
+		busy, err := slotBusy(tx, scheduleID)
+		if err != nil || busy {
+			return err
+		}
+		return tx.Model(&schedule).Update("is_available", true).Error
+	})
+}
+
+// AcceptOffer принимает предложение: создает запись пациента на удерживаемый слот и закрывает заявку.
+// Автор записи и отступление от правил записи берутся из req.
+func (r *waitlistRepo) AcceptOffer(offerID uint, req *models.AcceptOfferRequest) (*models.WaitlistOffer, *models.Appointment, error) {
+	var offer models.WaitlistOffer
+	var appointment models.Appointment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingOffer(tx, offerID, &offer); err != nil {
+			return err
+		}
+		if !offer.HeldUntil.After(time.Now()) {
+			return NewError(ErrConflict, "срок удержания слота по предложению истек")
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Schedule{}, offer.ScheduleID).Error; err != nil {
+			return err
+		}
+		var occupied int64
+		if err := tx.Model(&models.Appointment{}).
+			Where("schedule_id = ? AND status IN ?", offer.ScheduleID, models.OccupyingAppointmentStatuses).
+			Count(&occupied).Error; err != nil {
+			return err
+		}
+		if occupied > 0 {
+			return NewError(ErrConflict, "выбранное время уже занято")
+		}
+
+		var entry models.WaitlistEntry
+		if err := tx.First(&entry, offer.EntryID).Error; err != nil {
+			return err
+		}
+		appointment = models.Appointment{
+			ScheduleID: offer.ScheduleID,
+			PatientID:  entry.PatientID,
+			Status:     models.AppointmentBooked,
+		}
+		if req.BookedBy != "" {
+			appointment.BookedBy = &req.BookedBy
+		}
+		if req.OverrideReason != "" {
+			appointment.OverrideReason = &req.OverrideReason
+			appointment.OverriddenBy = &req.OverriddenBy
+			appointment.OverriddenRules = req.OverriddenRules
+		}
+		if err := tx.Omit("Patient", "Schedule", "Ticket").Create(&appointment).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Schedule{}).Where("schedule_id = ?", offer.ScheduleID).Update("is_available", false).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&offer).Updates(map[string]interface{}{
+			"status":         models.OfferAccepted,
+			"appointment_id": appointment.ID,
+			"resolved_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entry).Updates(map[string]interface{}{"status": models.WaitlistBooked, "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := r.db.Preload("Patient").Preload("Schedule.Doctor").First(&appointment, appointment.ID).Error; err != nil {
+		return nil, nil, err
+	}
+	accepted, err := r.GetOfferByID(offer.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return accepted, &appointment, nil
+}
+
+// ResolveOffer закрывает действующее предложение указанным статусом (отклонено или истекло).
+// Заявка, если она не была отменена, возвращается в очередь. Слот остается удержанным до следующего предложения.
+func (r *waitlistRepo) ResolveOffer(offerID uint, status models.WaitlistOfferStatus) (*models.WaitlistOffer, error) {
+	var offer models.WaitlistOffer
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingOffer(tx, offerID, &offer); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Model(&offer).Updates(map[string]interface{}{"status": status, "resolved_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.WaitlistEntry{}).
+			Where("entry_id = ? AND status = ?", offer.EntryID, models.WaitlistOffered).
+			Updates(map[string]interface{}{"status": models.WaitlistWaiting, "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetOfferByID(offer.ID)
+}
+
+// lockPendingOffer блокирует предложение для изменения и проверяет, что оно еще действует.
+func lockPendingOffer(tx *gorm.DB, offerID uint, offer *models.WaitlistOffer) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(offer, offerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewError(ErrNotFound, "предложение с ID %d не найдено", offerID)
+		}
+		return err
+	}
+	if offer.Status != models.OfferPending {
+		return NewError(ErrConflict, "предложение в статусе '%s' уже закрыто", offer.Status)
+	}
+	return nil
+}
+
+// slotBusy проверяет, занят ли слот действующей записью или удерживается по предложению из листа ожидания.
+func slotBusy(tx *gorm.DB, scheduleID uint) (bool, error) {
+	var appointments, offers int64
+	if err := tx.Model(&models.Appointment{}).
+		Where("schedule_id = ? AND status IN ?", scheduleID, models.OccupyingAppointmentStatuses).
+		Count(&appointments).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.WaitlistOffer{}).
+		Where("schedule_id = ? AND status = ?", scheduleID, models.OfferPending).
+		Count(&offers).Error; err != nil {
+		return false, err
+	}
+	return appointments > 0 || offers > 0, nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"ElectronicQueue/internal/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefix            = "eq_"
+	defaultAPIKeyRateLimit  = 60
+	apiKeyTouchInterval     = time.Minute
+	apiKeyRateLimiterWindow = time.Minute
+)
+
+var (
+	// ErrAPIKeyInvalid возвращается, если ключ не найден.
+	ErrAPIKeyInvalid = errors.New("invalid API key")
+	// ErrAPIKeyInactive возвращается, если ключ отозван или истек.
+	ErrAPIKeyInactive = errors.New("API key is revoked or expired")
+)
+
+// APIKeyService управляет выпуском, ротацией, отзывом и проверкой API-ключей.
+type APIKeyService struct {
+	repo        repository.APIKeyRepository
+	log         *logger.AsyncLogger
+	limiter     *utils.RateLimiter
+	touchedAt   map[uint]time.Time
+	touchedLock sync.Mutex
+}
+
+// NewAPIKeyService создает новый экземпляр APIKeyService.
+func NewAPIKeyService(repo repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo:      repo,
+		log:       logger.Default().WithField("module", "APIKey"),
+		limiter:   utils.NewRateLimiter(apiKeyRateLimiterWindow),
+		touchedAt: make(map[uint]time.Time),
+	}
+}
+
+// Issue выпускает новый ключ от имени ключа issuer. Открытое значение ключа возвращается только здесь.
+func (s *APIKeyService) Issue(req *models.CreateAPIKeyRequest, issuer *models.APIKey) (string, *models.APIKey, error) {
+	if err := validateScopes(req.Scopes); err != nil {
+		return "", nil, err
+	}
+	if err := checkIssuerScopes(issuer, req.Scopes); err != nil {
+		return "", nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return "", nil, invalidError("срок действия ключа должен быть в будущем")
+	}
+
+	plainKey, prefix, err := generateAPIKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("не удалось сгенерировать ключ: %w", err)
+	}
+
+	rateLimit := defaultAPIKeyRateLimit
+	if req.RateLimitPerMinute != nil {
+		rateLimit = *req.RateLimitPerMinute
+	}
+
+	key := &models.APIKey{
+		Name:               req.Name,
+		KeyPrefix:          prefix,
+		KeyHash:            hashAPIKey(plainKey),
+		Scopes:             models.StringList(req.Scopes),
+		RateLimitPerMinute: rateLimit,
+		ExpiresAt:          req.ExpiresAt,
+	}
+	if err := s.repo.Create(key); err != nil {
+		return "", nil, fmt.Errorf("не удалось сохранить ключ: %w", err)
+	}
+
+	s.log.WithField("api_key_id", key.ID).WithField("name", key.Name).Info("API key issued")
+	return plainKey, key, nil
+}
+
+// GetAll возвращает все ключи без их открытых значений.
+func (s *APIKeyService) GetAll() ([]models.APIKey, error) {
+	return s.repo.GetAll()
+}
+
+// Revoke немедленно отзывает ключ. Ключ, ротированный с отсрочкой отзыва, отзывается
+// сразу, не дожидаясь окончания отсрочки.
+func (s *APIKeyService) Revoke(id uint) (*models.APIKey, error) {
+	key, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, invalidError("ключ с ID %d уже отозван или истек", id)
+	}
+
+	key.RevokedAt = &now
+	if err := s.repo.Update(key); err != nil {
+		return nil, fmt.Errorf("не удалось отозвать ключ: %w", err)
+	}
+
+	s.log.WithField("api_key_id", key.ID).Info("API key revoked")
+	return key, nil
+}
+
+// Rotate выпускает от имени ключа issuer новый ключ с теми же настройками, а старый продолжает
+// работать в течение gracePeriod, после чего считается отозванным.
+func (s *APIKeyService) Rotate(id uint, gracePeriod time.Duration, issuer *models.APIKey) (string, *models.APIKey, error) {
+	oldKey, err := s.GetByID(id)
+	if err != nil {
+		return "", nil, err
+	}
+	if !oldKey.IsActive(time.Now()) {
+		return "", nil, invalidError("ключ с ID %d уже неактивен", id)
+	}
+
+	plainKey, newKey, err := s.Issue(&models.CreateAPIKeyRequest{
+		Name:               oldKey.Name,
+		Scopes:             oldKey.Scopes,
+		RateLimitPerMinute: &oldKey.RateLimitPerMinute,
+		ExpiresAt:          oldKey.ExpiresAt,
+	}, issuer)
+	if err != nil {
+		return "", nil, err
+	}
+
+	revokeAt := time.Now().Add(gracePeriod)
+	oldKey.RevokedAt = &revokeAt
+	if err := s.repo.Update(oldKey); err != nil {
+		return "", nil, fmt.Errorf("новый ключ выпущен, но не удалось ограничить срок старого: %w", err)
+	}
+
+	s.log.WithField("old_api_key_id", oldKey.ID).WithField("new_api_key_id", newKey.ID).Info("API key rotated")
+	return plainKey, newKey, nil
+}
+
+// Authenticate находит действующий ключ по его открытому значению.
+func (s *APIKeyService) Authenticate(plainKey string) (*models.APIKey, error) {
+	key, err := s.repo.FindByHash(hashAPIKey(plainKey))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, fmt.Errorf("ошибка проверки ключа: %w", err)
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, ErrAPIKeyInactive
+	}
+
+	s.touch(key.ID, now)
+	return key, nil
+}
+
+// AllowRequest учитывает запрос в лимите ключа и возвращает false, если лимит исчерпан.
+func (s *APIKeyService) AllowRequest(key *models.APIKey) bool {
+	return s.limiter.Allow(strconv.FormatUint(uint64(key.ID), 10), key.RateLimitPerMinute)
+}
+
+// touch обновляет last_used_at не чаще раза в apiKeyTouchInterval, чтобы не писать в БД на каждый запрос.
+func (s *APIKeyService) touch(id uint, now time.Time) {
+	s.touchedLock.Lock()
+	last, ok := s.touchedAt[id]
+	if ok && now.Sub(last) < apiKeyTouchInterval {
+		s.touchedLock.Unlock()
+		return
+	}
+	s.touchedAt[id] = now
+	s.touchedLock.Unlock()
+
+	if err := s.repo.TouchLastUsed(id, now); err != nil {
+		s.log.WithError(err).WithField("api_key_id", id).Warn("Failed to update API key last_used_at")
+	}
+}
+
+// GetByID возвращает ключ по ID.
+func (s *APIKeyService) GetByID(id uint) (*models.APIKey, error) {
+	key, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("ключ с ID %d не найден", id)
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		parts := strings.Split(scope, ":")
+		if scope == models.ScopeWildcard {
+			continue
+		}
+		switch parts[0] {
+		case "database":
+			if len(parts) != 3 && !(len(parts) == 2 && parts[1] == models.ScopeWildcard) {
+				return invalidError("неверная область доступа '%s': ожидается database:<таблица>:<операция>", scope)
+			}
+		case "admin":
+			if len(parts) != 2 {
+				return invalidError("неверная область доступа '%s': ожидается admin:<раздел>", scope)
+			}
+		default:
+			return invalidError("неизвестная область доступа '%s'", scope)
+		}
+		for _, part := range parts {
+			if part == "" {
+				return invalidError("неверная область доступа '%s'", scope)
+			}
+		}
+	}
+	return nil
+}
+
+// checkIssuerScopes запрещает выдавать области доступа шире, чем у выпускающего ключа: каждая
+// запрошенная область должна покрываться одной из его областей, поэтому "*" может выдать только ключ с "*".
+func checkIssuerScopes(issuer *models.APIKey, scopes []string) error {
+	if issuer == nil {
+		return forbiddenError("не удалось определить ключ, от имени которого выпускается новый")
+	}
+	for _, scope := range scopes {
+		if !issuer.HasScope(scope) {
+			return forbiddenError("нельзя выдать область доступа '%s', которой нет у текущего ключа", scope)
+		}
+	}
+	return nil
+}
+
+func generateAPIKey() (string, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	secret := hex.EncodeToString(buf)
+	return apiKeyPrefix + secret, apiKeyPrefix + secret[:8], nil
+}
+
+func hashAPIKey(plainKey string) string {
+	sum := sha256.Sum256([]byte(plainKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,15 @@
 package services
 
 import (
+	"ElectronicQueue/internal/logger"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/repository"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // AppointmentDetailsResponse определяет детальную информацию о записи для истории.
@@ -18,17 +23,44 @@ type AppointmentDetailsResponse struct {
 	PatientName   string  `json:"patient_name"`
 	TicketNumber  *string `json:"ticket_number"`
 	IsFuture      bool    `json:"is_future"`
+	FlagReason    *string `json:"flag_reason,omitempty"`
+
+	Status          models.AppointmentStatus `json:"status"`
+	CancelReason    *string                  `json:"cancel_reason,omitempty"`
+	CancelledBy     *string                  `json:"cancelled_by,omitempty"`
+	CancelledAt     *time.Time               `json:"cancelled_at,omitempty"`
+	RescheduledToID *uint                    `json:"rescheduled_to_id,omitempty"`
+
+	ConfirmationCode *string `json:"confirmation_code,omitempty"`
+	OverrideReason   *string `json:"override_reason,omitempty"`
+	BookedBy         *string `json:"booked_by,omitempty"`
+
+	BookingKind         *string `json:"booking_kind,omitempty"`
+	SourceAppointmentID *uint   `json:"source_appointment_id,omitempty"`
+
+	DiagnosisCode          *string    `json:"diagnosis_code,omitempty"`
+	VisitNote              *string    `json:"visit_note,omitempty"`
+	FollowUpNeeded         bool       `json:"follow_up_needed"`
+	ReferralSpecialization *string    `json:"referral_specialization,omitempty"`
+	IssuedReferralID       *uint      `json:"issued_referral_id,omitempty"`
+	OutcomeRecordedAt      *time.Time `json:"outcome_recorded_at,omitempty"`
+	OutcomeRecordedBy      *string    `json:"outcome_recorded_by,omitempty"`
 }
 
 // AppointmentService предоставляет методы для управления записями на прием.
 type AppointmentService struct {
-	repo       repository.AppointmentRepository
-	ticketRepo repository.TicketRepository
+	repo         repository.AppointmentRepository
+	ticketRepo   repository.TicketRepository
+	scheduleRepo repository.ScheduleRepository
+	calendarRepo repository.CalendarRepository
+	waitlist     *WaitlistService
+	rules        *BookingRuleService
 }
 
 // NewAppointmentService создает новый экземпляр AppointmentService.
-func NewAppointmentService(repo repository.AppointmentRepository, ticketRepo repository.TicketRepository) *AppointmentService {
-	return &AppointmentService{repo: repo, ticketRepo: ticketRepo}
+// Слоты, освобожденные при отмене и переносе записей, передаются в лист ожидания.
+func NewAppointmentService(repo repository.AppointmentRepository, ticketRepo repository.TicketRepository, scheduleRepo repository.ScheduleRepository, calendarRepo repository.CalendarRepository, waitlist *WaitlistService, rules *BookingRuleService) *AppointmentService {
+	return &AppointmentService{repo: repo, ticketRepo: ticketRepo, scheduleRepo: scheduleRepo, calendarRepo: calendarRepo, waitlist: waitlist, rules: rules}
 }
 
 // GetDoctorScheduleWithAppointments получает расписание врача вместе с информацией о существующих записях.
@@ -41,20 +73,62 @@ func (s *AppointmentService) GetDoctorScheduleWithAppointments(doctorID uint, da
 }
 
 // CreateAppointment обрабатывает логику создания новой записи.
-// Основная работа (транзакция) выполняется в репозитории.
-func (s *AppointmentService) CreateAppointment(req *models.CreateAppointmentRequest) (*models.Appointment, error) {
+// Запись проверяется по правилам специальности врача; при нарушениях возвращается BookingRuleViolationError,
+// если регистратор не указал причину записи вопреки правилам. Основная работа (транзакция) выполняется в репозитории.
+func (s *AppointmentService) CreateAppointment(req *models.CreateAppointmentRequest, actor string) (*models.Appointment, error) {
 	if req.ScheduleID == 0 || req.PatientID == 0 {
-		return nil, fmt.Errorf("ScheduleID и PatientID являются обязательными полями")
+		return nil, invalidError("ScheduleID и PatientID являются обязательными полями")
+	}
+
+	schedule, err := s.scheduleRepo.GetByID(req.ScheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("указанный слот в расписании не найден")
+		}
+		return nil, fmt.Errorf("ошибка при поиске слота расписания: %w", err)
+	}
+	if err := s.checkSlotCalendar(schedule); err != nil {
+		return nil, err
+	}
+
+	check, err := s.rules.Evaluate(req.PatientID, schedule)
+	if err != nil {
+		return nil, err
+	}
+	req.BookedBy = actor
+	req.OverrideReason = strings.TrimSpace(req.OverrideReason)
+	if len(check.Violations) > 0 {
+		if req.OverrideReason == "" {
+			return nil, &BookingRuleViolationError{Violations: check.Violations}
+		}
+		req.OverriddenBy = actor
+		req.OverriddenRules = check.ViolationTypes()
+	} else {
+		req.OverrideReason = ""
 	}
 
 	appointment, err := s.repo.CreateAppointmentInTransaction(req)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось создать запись на прием: %w", err)
 	}
+	s.rules.attachReferral(check, appointment.ID)
 	return appointment, nil
 }
 
-// GetAppointmentsByPatient получает историю записей и преобразует в DTO.
+// checkSlotCalendar проверяет, что прием в слоте не отменен календарем клиники или отсутствием врача.
+func (s *AppointmentService) checkSlotCalendar(schedule *models.Schedule) error {
+	day := truncateToDate(schedule.Date)
+	cal, err := loadClinicCalendar(s.calendarRepo, day, day)
+	if err != nil {
+		return err
+	}
+	if reason := cal.blockReason(schedule); reason != "" {
+		return conflictError("запись на выбранное время невозможна: %s", reason)
+	}
+	return nil
+}
+
+// GetAppointmentsByPatient получает историю записей, включая отмененные и перенесенные, и преобразует в DTO.
 func (s *AppointmentService) GetAppointmentsByPatient(patientID uint) ([]AppointmentDetailsResponse, error) {
 	appointments, err := s.repo.FindByPatientID(patientID)
 	if err != nil {
@@ -87,15 +161,126 @@ func (s *AppointmentService) GetAppointmentsByPatient(patientID uint) ([]Appoint
 			PatientName:   app.Patient.FullName,
 			TicketNumber:  ticketNum,
 			IsFuture:      isFuture,
+			FlagReason:    app.FlagReason,
+
+			Status:          app.Status,
+			CancelReason:    app.CancelReason,
+			CancelledBy:     app.CancelledBy,
+			CancelledAt:     app.CancelledAt,
+			RescheduledToID: app.RescheduledToID,
+
+			ConfirmationCode: app.ConfirmationCode,
+			OverrideReason:   app.OverrideReason,
+			BookedBy:         app.BookedBy,
+
+			BookingKind:         app.BookingKind,
+			SourceAppointmentID: app.SourceAppointmentID,
+
+			DiagnosisCode:          app.DiagnosisCode,
+			VisitNote:              app.VisitNote,
+			FollowUpNeeded:         app.FollowUpNeeded,
+			ReferralSpecialization: app.ReferralSpecialization,
+			IssuedReferralID:       app.IssuedReferralID,
+			OutcomeRecordedAt:      app.OutcomeRecordedAt,
+			OutcomeRecordedBy:      app.OutcomeRecordedBy,
 		}
 		response = append(response, details)
 	}
 	return response, nil
 }
 
-// DeleteAppointment удаляет запись.
-func (s *AppointmentService) DeleteAppointment(appointmentID uint) error {
-	return s.repo.DeleteAppointmentAndFreeSlot(appointmentID)
+// GetAppointment возвращает запись на прием по ID.
+func (s *AppointmentService) GetAppointment(appointmentID uint) (*models.Appointment, error) {
+	appointment, err := s.repo.FindByID(appointmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("запись с ID %d не найдена", appointmentID)
+		}
+		return nil, fmt.Errorf("ошибка при поиске записи: %w", err)
+	}
+	return appointment, nil
+}
+
+// CancelAppointment отменяет запись с указанием причины и автора; слот освобождается и предлагается листу ожидания,
+// запись остается в истории.
+func (s *AppointmentService) CancelAppointment(appointmentID uint, reason, actor string) (*models.Appointment, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, invalidError("необходимо указать причину отмены")
+	}
+	appointment, err := s.repo.CancelAppointment(appointmentID, reason, actor)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отменить запись: %w", err)
+	}
+	s.rules.moveReferral(appointment.ID, nil)
+	s.waitlist.OnSlotFreed(appointment.ScheduleID)
+	return appointment, nil
+}
+
+// RescheduleAppointment переносит запись на другой слот. Новый слот занимается атомарно, прежний предлагается
+// листу ожидания или освобождается.
+// Новый слот проверяется по правилам записи так же, как при создании записи.
+func (s *AppointmentService) RescheduleAppointment(appointmentID uint, req *models.RescheduleAppointmentRequest, actor string) (*models.RescheduleAppointmentResult, error) {
+	appointment, err := s.repo.FindByID(appointmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("запись с ID %d не найдена", appointmentID)
+		}
+		return nil, fmt.Errorf("ошибка при поиске записи: %w", err)
+	}
+	schedule, err := s.scheduleRepo.GetByID(req.ScheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("указанный слот в расписании не найден")
+		}
+		return nil, fmt.Errorf("ошибка при поиске слота расписания: %w", err)
+	}
+	if err := s.checkSlotCalendar(schedule); err != nil {
+		return nil, err
+	}
+
+	check, err := s.rules.EvaluateReschedule(appointmentID, appointment.PatientID, schedule)
+	if err != nil {
+		return nil, err
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	req.OverrideReason = strings.TrimSpace(req.OverrideReason)
+	if len(check.Violations) > 0 {
+		if req.OverrideReason == "" {
+			return nil, &BookingRuleViolationError{Violations: check.Violations}
+		}
+		req.OverriddenBy = actor
+		req.OverriddenRules = check.ViolationTypes()
+	} else {
+		req.OverrideReason = ""
+	}
+
+	previous, current, err := s.repo.RescheduleAppointment(appointmentID, req, actor)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось перенести запись: %w", err)
+	}
+	s.rules.attachReferral(check, current.ID)
+	s.waitlist.OnSlotFreed(previous.ScheduleID)
+	return &models.RescheduleAppointmentResult{Previous: *previous, Current: *current}, nil
+}
+
+// MarkNoShows отмечает неявку по записям прошедших дней, на которые пациент не пришел (талон не выдан),
+// и возвращает ID отмеченных записей. Вызывается планировщиком задач после окончания рабочего дня.
+func (s *AppointmentService) MarkNoShows(today time.Time) ([]uint, error) {
+	ids, err := s.repo.MarkNoShows(truncateToDate(today))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отметить неявки: %w", err)
+	}
+	if len(ids) > 0 {
+		logger.Default().WithField("module", "appointments").WithField("count", len(ids)).Info("Appointments marked as no-show")
+	}
+	return ids, nil
+}
+
+// DeleteAppointment отменяет запись без указания причины. Запись не удаляется, а сохраняется в истории как отмененная.
+func (s *AppointmentService) DeleteAppointment(appointmentID uint, actor string) error {
+	_, err := s.CancelAppointment(appointmentID, "отменена регистратором", actor)
+	return err
 }
 
 // ConfirmAppointment подтверждает явку по записи.
@@ -106,7 +291,10 @@ func (s *AppointmentService) ConfirmAppointment(appointmentID, ticketID uint) (*
 	}
 
 	if appointment.TicketID != nil {
-		return nil, fmt.Errorf("запись уже подтверждена и привязана к талону")
+		return nil, conflictError("запись уже подтверждена и привязана к талону")
+	}
+	if appointment.Status != models.AppointmentBooked {
+		return nil, conflictError("запись в статусе '%s' нельзя подтвердить", appointment.Status)
 	}
 
 	ticket, err := s.ticketRepo.GetByID(ticketID)
@@ -114,11 +302,12 @@ func (s *AppointmentService) ConfirmAppointment(appointmentID, ticketID uint) (*
 		return nil, fmt.Errorf("талон не найден: %w", err)
 	}
 
-	if ticket.Status == models.StatusRegistered || ticket.Status == models.StatusInProgress {
-		return nil, fmt.Errorf("этот талон уже используется")
+	if ticket.Status == models.StatusRegistered || ticket.Status == models.StatusInvitedToCabinet || ticket.Status == models.StatusInProgress {
+		return nil, conflictError("этот талон уже используется")
 	}
 
 	appointment.TicketID = &ticketID
+	appointment.Status = models.AppointmentAttended
 	if err := s.repo.Update(appointment); err != nil {
 		return nil, fmt.Errorf("не удалось обновить запись: %w", err)
 	}
@@ -126,6 +315,7 @@ func (s *AppointmentService) ConfirmAppointment(appointmentID, ticketID uint) (*
 	ticket.Status = models.StatusRegistered
 	if err := s.ticketRepo.Update(ticket); err != nil {
 		appointment.TicketID = nil
+		appointment.Status = models.AppointmentBooked
 		s.repo.Update(appointment)
 		return nil, fmt.Errorf("не удалось обновить статус талона: %w", err)
 	}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+const (
+	// defaultAuditLogLimit - размер страницы журнала аудита по умолчанию.
+	defaultAuditLogLimit = 50
+	// maxAuditLogLimit ограничивает размер страницы журнала аудита.
+	maxAuditLogLimit = 500
+	// maxAuditValueLength - строки длиннее (например, изображения в base64) заменяются в журнале описанием размера.
+	maxAuditValueLength = 1024
+	// auditTaskMethod - значение метода в записях журнала об изменениях, выполненных фоновыми задачами.
+	auditTaskMethod = "TASK"
+	// auditSystemActor - автор изменений, выполненных фоновыми задачами сервера.
+	auditSystemActor = "system"
+)
+
+// AuditService ведет журнал аудита изменяющих запросов.
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+// NewAuditService создает новый экземпляр AuditService.
+func NewAuditService(repo repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record сохраняет запись журнала аудита. Если обработчик сообщил об изменении сущности,
+// в запись добавляются ее состояния до и после запроса и список измененных полей.
+func (s *AuditService) Record(entry *models.AuditLog, change *models.AuditChange) error {
+	if change != nil {
+		if change.Entity != "" {
+			entry.Entity = change.Entity
+		}
+		if change.EntityID != "" {
+			entry.EntityID = change.EntityID
+		}
+		before, err := auditSnapshot(change.Before)
+		if err != nil {
+			return fmt.Errorf("не удалось сериализовать состояние до изменения: %w", err)
+		}
+		after, err := auditSnapshot(change.After)
+		if err != nil {
+			return fmt.Errorf("не удалось сериализовать состояние после изменения: %w", err)
+		}
+		if entry.Before, err = auditDocument(before); err != nil {
+			return err
+		}
+		if entry.After, err = auditDocument(after); err != nil {
+			return err
+		}
+		if entry.Diff, err = auditDocument(auditDiff(before, after)); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.Create(entry); err != nil {
+		return fmt.Errorf("не удалось сохранить запись журнала аудита: %w", err)
+	}
+	return nil
+}
+
+// RecordTask сохраняет в журнал аудита изменение, выполненное фоновой задачей без HTTP-запроса.
+// Имя задачи (например, "waitlist/expire-offers") записывается вместо эндпоинта, автором указывается "system".
+// Записи одного запуска задачи объединяются общим идентификатором запроса.
+func (s *AuditService) RecordTask(task string, runAt time.Time, change models.AuditChange) error {
+	entry := &models.AuditLog{
+		RequestID:  fmt.Sprintf("task:%s:%d", task, runAt.Unix()),
+		Actor:      auditSystemActor,
+		Role:       auditSystemActor,
+		Method:     auditTaskMethod,
+		Endpoint:   task,
+		Path:       task,
+		StatusCode: http.StatusOK,
+	}
+	return s.Record(entry, &change)
+}
+
+// Find возвращает страницу журнала аудита по фильтру.
+func (s *AuditService) Find(filter models.AuditLogFilter) (*models.AuditLogPage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = defaultAuditLogLimit
+	}
+	if filter.Limit > maxAuditLogLimit {
+		filter.Limit = maxAuditLogLimit
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, invalidError("начало периода должно быть раньше конца")
+	}
+
+	entries, total, err := s.repo.Find(filter)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала аудита: %w", err)
+	}
+	if entries == nil {
+		entries = []models.AuditLog{}
+	}
+	return &models.AuditLogPage{Page: filter.Page, Limit: filter.Limit, Total: total, Data: entries}, nil
+}
+
+// auditSnapshot приводит состояние сущности к JSON-представлению (с учетом тегов и MarshalJSON моделей)
+// и сокращает слишком длинные строки.
+func auditSnapshot(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return compactAuditValue(snapshot), nil
+}
+
+func compactAuditValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = compactAuditValue(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = compactAuditValue(item)
+		}
+	case string:
+		if len(value) > maxAuditValueLength {
+			return fmt.Sprintf("<%d байт>", len(value))
+		}
+	}
+	return v
+}
+
+// auditDiff возвращает измененные поля в виде {"поле": {"from": ..., "to": ...}}.
+// Разница вычисляется только между двумя объектами; для созданных и удаленных сущностей она пуста.
+func auditDiff(before, after interface{}) map[string]interface{} {
+	from, ok := before.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	to, ok := after.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	diff := map[string]interface{}{}
+	for key, oldValue := range from {
+		if newValue, exists := to[key]; !exists || !reflect.DeepEqual(oldValue, newValue) {
+			diff[key] = map[string]interface{}{"from": oldValue, "to": to[key]}
+		}
+	}
+	for key, newValue := range to {
+		if _, exists := from[key]; !exists {
+			diff[key] = map[string]interface{}{"from": nil, "to": newValue}
+		}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	return diff
+}
+
+func auditDocument(v interface{}) (models.JSONDocument, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Map && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сериализовать запись журнала аудита: %w", err)
+	}
+	return models.JSONDocument(data), nil
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookingRuleViolationError возвращается, когда запись нарушает правила записи по специальности.
+// Регистратор может записать пациента вопреки правилам, указав причину.
+type BookingRuleViolationError struct {
+	Violations []models.BookingRuleViolation
+}
+
+func (e *BookingRuleViolationError) Error() string {
+	return fmt.Sprintf("нарушены правила записи (%d)", len(e.Violations))
+}
+
+// bookingRuleCheck - результат проверки записи по правилам специальности.
+// Referral - направление, которое будет использовано для записи, если правило его требует.
+type bookingRuleCheck struct {
+	Violations []models.BookingRuleViolation
+	Referral   *models.Referral
+}
+
+// ViolationTypes возвращает типы нарушенных правил.
+func (c *bookingRuleCheck) ViolationTypes() []string {
+	types := make([]string, 0, len(c.Violations))
+	for _, v := range c.Violations {
+		types = append(types, v.Type)
+	}
+	return types
+}
+
+// BookingRuleService управляет правилами записи по специальностям и направлениями пациентов
+// и проверяет по ним создаваемые записи.
+type BookingRuleService struct {
+	ruleRepo     repository.BookingRuleRepository
+	referralRepo repository.ReferralRepository
+	patientRepo  repository.PatientRepository
+	doctorRepo   repository.DoctorRepository
+}
+
+// NewBookingRuleService создает новый экземпляр BookingRuleService.
+func NewBookingRuleService(ruleRepo repository.BookingRuleRepository, referralRepo repository.ReferralRepository, patientRepo repository.PatientRepository, doctorRepo repository.DoctorRepository) *BookingRuleService {
+	return &BookingRuleService{
+		ruleRepo:     ruleRepo,
+		referralRepo: referralRepo,
+		patientRepo:  patientRepo,
+		doctorRepo:   doctorRepo,
+	}
+}
+
+// GetRules возвращает правила записи всех специальностей.
+func (s *BookingRuleService) GetRules() ([]models.BookingRule, error) {
+	rules, err := s.ruleRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения правил записи: %w", err)
+	}
+	return rules, nil
+}
+
+// GetRule возвращает правила записи по ID.
+func (s *BookingRuleService) GetRule(id uint) (*models.BookingRule, error) {
+	rule, err := s.ruleRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("правила записи с ID %d не найдены", id)
+		}
+		return nil, fmt.Errorf("ошибка получения правил записи: %w", err)
+	}
+	return rule, nil
+}
+
+// CreateRule добавляет правила записи для специальности.
+func (s *BookingRuleService) CreateRule(req *models.BookingRuleRequest) (*models.BookingRule, error) {
+	rule := &models.BookingRule{IsActive: true}
+	if err := applyBookingRuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	if err := s.ruleRepo.Create(rule); err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, conflictError("правила для специальности '%s' уже существуют", rule.Specialization)
+		}
+		return nil, fmt.Errorf("не удалось создать правила записи: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateRule полностью заменяет правила записи с указанным ID.
+func (s *BookingRuleService) UpdateRule(id uint, req *models.BookingRuleRequest) (*models.BookingRule, error) {
+	rule, err := s.GetRule(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyBookingRuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	if err := s.ruleRepo.Update(rule); err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, conflictError("правила для специальности '%s' уже существуют", rule.Specialization)
+		}
+		return nil, fmt.Errorf("не удалось обновить правила записи: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule удаляет правила записи.
+func (s *BookingRuleService) DeleteRule(id uint) error {
+	if _, err := s.GetRule(id); err != nil {
+		return err
+	}
+	if err := s.ruleRepo.Delete(id); err != nil {
+		return fmt.Errorf("не удалось удалить правила записи: %w", err)
+	}
+	return nil
+}
+
+// GetReferrals возвращает направления пациента.
+func (s *BookingRuleService) GetReferrals(patientID uint) ([]models.Referral, error) {
+	referrals, err := s.referralRepo.FindByPatient(patientID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения направлений: %w", err)
+	}
+	return referrals, nil
+}
+
+// CreateReferral регистрирует направление пациента к специальности.
+func (s *BookingRuleService) CreateReferral(patientID uint, req *models.CreateReferralRequest) (*models.Referral, error) {
+	if _, err := s.patientRepo.GetByID(patientID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("пациент с ID %d не найден", patientID)
+		}
+		return nil, fmt.Errorf("ошибка проверки пациента: %w", err)
+	}
+	specialization := strings.TrimSpace(req.Specialization)
+	if specialization == "" {
+		return nil, invalidError("необходимо указать специальность")
+	}
+	issuedAt, validUntil := truncateToDate(req.IssuedAt), truncateToDate(req.ValidUntil)
+	if validUntil.Before(issuedAt) {
+		return nil, invalidError("срок действия направления заканчивается раньше даты выдачи")
+	}
+
+	referral := &models.Referral{
+		PatientID:      patientID,
+		Specialization: specialization,
+		ReferralNumber: strings.TrimSpace(req.ReferralNumber),
+		IssuedBy:       strings.TrimSpace(req.IssuedBy),
+		IssuedAt:       issuedAt,
+		ValidUntil:     validUntil,
+	}
+	if err := s.referralRepo.Create(referral); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить направление: %w", err)
+	}
+	return referral, nil
+}
+
+// DeleteReferral удаляет ошибочно внесенное направление. Использованное для записи направление удалить нельзя.
+func (s *BookingRuleService) DeleteReferral(id uint) error {
+	referral, err := s.referralRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFoundError("направление с ID %d не найдено", id)
+		}
+		return fmt.Errorf("ошибка получения направления: %w", err)
+	}
+	if referral.AppointmentID != nil {
+		return conflictError("направление уже использовано для записи %d", *referral.AppointmentID)
+	}
+	if err := s.referralRepo.Delete(id); err != nil {
+		return fmt.Errorf("не удалось удалить направление: %w", err)
+	}
+	return nil
+}
+
+// Evaluate проверяет запись пациента на слот по правилам специальности врача.
+func (s *BookingRuleService) Evaluate(patientID uint, schedule *models.Schedule) (*bookingRuleCheck, error) {
+	return s.evaluate(patientID, schedule, 0)
+}
+
+// EvaluateReschedule проверяет перенос записи appointmentID на слот. Переносимая запись не учитывается
+// в лимите и интервале визитов, а ее направление к той же специальности переходит к новой записи.
+func (s *BookingRuleService) EvaluateReschedule(appointmentID, patientID uint, schedule *models.Schedule) (*bookingRuleCheck, error) {
+	return s.evaluate(patientID, schedule, appointmentID)
+}
+
+func (s *BookingRuleService) evaluate(patientID uint, schedule *models.Schedule, movingID uint) (*bookingRuleCheck, error) {
+	check := &bookingRuleCheck{}
+	doctor, err := s.doctorRepo.GetByID(schedule.DoctorID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения врача слота: %w", err)
+	}
+	rule, err := s.ruleRepo.FindActiveBySpecialization(doctor.Specialization)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return check, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения правил записи: %w", err)
+	}
+
+	violation := func(kind, format string, args ...interface{}) {
+		check.Violations = append(check.Violations, models.BookingRuleViolation{
+			Type:           kind,
+			Message:        fmt.Sprintf(format, args...),
+			Specialization: rule.Specialization,
+		})
+	}
+	slotDate := truncateToDate(schedule.Date)
+
+	if rule.MaxActiveAppointments != nil || rule.MinIntervalDays != nil {
+		appointments, err := s.ruleRepo.FindPatientAppointmentsBySpecialization(patientID, doctor.Specialization)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения записей пациента: %w", err)
+		}
+		today := truncateToDate(time.Now())
+		active := 0
+		var nearest *models.Appointment
+		nearestGap := 0
+		for i := range appointments {
+			app := &appointments[i]
+			if app.ID == movingID {
+				continue
+			}
+			date := truncateToDate(app.Schedule.Date)
+			if app.Status == models.AppointmentBooked && !date.Before(today) {
+				active++
+			}
+			gap := int(slotDate.Sub(date).Hours() / 24)
+			if gap < 0 {
+				gap = -gap
+			}
+			if nearest == nil || gap < nearestGap {
+				nearest, nearestGap = app, gap
+			}
+		}
+		if rule.MaxActiveAppointments != nil && active >= *rule.MaxActiveAppointments {
+			violation(models.RuleViolationMaxActive, "у пациента уже %d предстоящих записей к специальности '%s' (допустимо %d)",
+				active, rule.Specialization, *rule.MaxActiveAppointments)
+		}
+		if rule.MinIntervalDays != nil && nearest != nil && nearestGap < *rule.MinIntervalDays {
+			violation(models.RuleViolationInterval, "между визитами к специальности '%s' должно пройти не менее %d дней, ближайший визит %s",
+				rule.Specialization, *rule.MinIntervalDays, nearest.Schedule.Date.Format("2006-01-02"))
+		}
+	}
+
+	if rule.MinAge != nil || rule.MaxAge != nil {
+		patient, err := s.patientRepo.GetByID(patientID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, notFoundError("пациент с ID %d не найден", patientID)
+			}
+			return nil, fmt.Errorf("ошибка получения пациента: %w", err)
+		}
+		age := ageAt(patient.BirthDate, slotDate)
+		if rule.MinAge != nil && age < *rule.MinAge {
+			violation(models.RuleViolationAge, "прием у специальности '%s' возможен с %d лет, возраст пациента %d", rule.Specialization, *rule.MinAge, age)
+		}
+		if rule.MaxAge != nil && age > *rule.MaxAge {
+			violation(models.RuleViolationAge, "прием у специальности '%s' возможен до %d лет, возраст пациента %d", rule.Specialization, *rule.MaxAge, age)
+		}
+	}
+
+	if rule.ReferralRequired && movingID != 0 {
+		attached, err := s.attachedReferral(patientID, movingID, doctor.Specialization, slotDate)
+		if err != nil {
+			return nil, err
+		}
+		if attached {
+			return check, nil
+		}
+	}
+	if rule.ReferralRequired {
+		referral, err := s.referralRepo.FindValid(patientID, doctor.Specialization, slotDate)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			violation(models.RuleViolationReferral, "для записи к специальности '%s' необходимо действующее направление", rule.Specialization)
+		case err != nil:
+			return nil, fmt.Errorf("ошибка поиска направления: %w", err)
+		default:
+			check.Referral = referral
+		}
+	}
+	return check, nil
+}
+
+// attachedReferral проверяет, что к записи appointmentID уже привязано направление к специальности,
+// действующее на дату date.
+func (s *BookingRuleService) attachedReferral(patientID, appointmentID uint, specialization string, date time.Time) (bool, error) {
+	referrals, err := s.referralRepo.FindByPatient(patientID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка поиска направления: %w", err)
+	}
+	for _, r := range referrals {
+		if r.AppointmentID != nil && *r.AppointmentID == appointmentID &&
+			strings.EqualFold(r.Specialization, specialization) &&
+			!truncateToDate(r.IssuedAt).After(date) && !truncateToDate(r.ValidUntil).Before(date) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// attachReferral отмечает направление использованным для созданной записи.
+// Ошибка не отменяет запись, а только журналируется.
+func (s *BookingRuleService) attachReferral(check *bookingRuleCheck, appointmentID uint) {
+	if check.Referral == nil {
+		return
+	}
+	if err := s.referralRepo.AttachToAppointment(check.Referral.ID, appointmentID); err != nil {
+		logger.Default().WithError(err).WithField("referral_id", check.Referral.ID).
+			WithField("appointment_id", appointmentID).Warn("Не удалось отметить направление использованным")
+	}
+}
+
+// moveReferral переносит направление при переносе записи или освобождает его при отмене (toAppointmentID == nil).
+func (s *BookingRuleService) moveReferral(fromAppointmentID uint, toAppointmentID *uint) {
+	if err := s.referralRepo.MoveToAppointment(fromAppointmentID, toAppointmentID); err != nil {
+		logger.Default().WithError(err).WithField("appointment_id", fromAppointmentID).Warn("Не удалось перенести направление записи")
+	}
+}
+
+// applyBookingRuleRequest переносит параметры запроса в правила и проверяет их согласованность.
+func applyBookingRuleRequest(rule *models.BookingRule, req *models.BookingRuleRequest) error {
+	specialization := strings.TrimSpace(req.Specialization)
+	if specialization == "" {
+		return invalidError("необходимо указать специальность")
+	}
+	if req.MinAge != nil && req.MaxAge != nil && *req.MaxAge < *req.MinAge {
+		return invalidError("максимальный возраст меньше минимального")
+	}
+	rule.Specialization = specialization
+	rule.MaxActiveAppointments = req.MaxActiveAppointments
+	rule.MinIntervalDays = req.MinIntervalDays
+	rule.MinAge = req.MinAge
+	rule.MaxAge = req.MaxAge
+	rule.ReferralRequired = req.ReferralRequired
+	rule.Description = strings.TrimSpace(req.Description)
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// ageAt возвращает полное число лет на указанную дату.
+func ageAt(birthDate, date time.Time) int {
+	age := date.Year() - birthDate.Year()
+	if date.Month() < birthDate.Month() || (date.Month() == birthDate.Month() && date.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
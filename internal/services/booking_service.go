@@ -0,0 +1,191 @@
+package services
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultBookingSearchDays - период поиска слотов, если дата окончания не указана.
+	defaultBookingSearchDays = 14
+	// maxBookingSearchDays ограничивает период одного поиска свободных слотов.
+	maxBookingSearchDays = 31
+	// confirmationCodeLength - длина кода подтверждения самостоятельной записи.
+	confirmationCodeLength = 8
+	// confirmationCodeAlphabet не содержит похожих символов (0/O, 1/I/L), чтобы код было легко продиктовать.
+	confirmationCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+)
+
+// BookingService реализует самостоятельную запись пациентов на прием без участия регистратуры.
+type BookingService struct {
+	appointmentRepo repository.AppointmentRepository
+	scheduleRepo    repository.ScheduleRepository
+	patientRepo     repository.PatientRepository
+	calendarRepo    repository.CalendarRepository
+	rules           *BookingRuleService
+	limits          models.BookingLimits
+}
+
+// NewBookingService создает новый экземпляр BookingService.
+// maxActivePerPatient ограничивает число предстоящих записей одного пациента (0 - без ограничений).
+func NewBookingService(appointmentRepo repository.AppointmentRepository, scheduleRepo repository.ScheduleRepository, patientRepo repository.PatientRepository, calendarRepo repository.CalendarRepository, rules *BookingRuleService, maxActivePerPatient int) *BookingService {
+	return &BookingService{
+		appointmentRepo: appointmentRepo,
+		scheduleRepo:    scheduleRepo,
+		patientRepo:     patientRepo,
+		calendarRepo:    calendarRepo,
+		rules:           rules,
+		limits:          models.BookingLimits{MaxActive: maxActivePerPatient, OnePerDoctor: true},
+	}
+}
+
+// SearchSlots ищет свободные слоты всех врачей специальности за период.
+// Прошедшие слоты и слоты, попадающие на нерабочее время клиники или отсутствие врача, не возвращаются.
+func (s *BookingService) SearchSlots(specialization string, from time.Time, to *time.Time) ([]models.BookingSlot, error) {
+	specialization = strings.TrimSpace(specialization)
+	if specialization == "" {
+		return nil, invalidError("необходимо указать специальность")
+	}
+
+	now := time.Now()
+	today := truncateToDate(now)
+	from = truncateToDate(from)
+	if from.Before(today) {
+		from = today
+	}
+	end := from.AddDate(0, 0, defaultBookingSearchDays-1)
+	if to != nil {
+		end = truncateToDate(*to)
+	}
+	if end.Before(from) {
+		return nil, invalidError("дата окончания периода раньше даты начала")
+	}
+	if int(end.Sub(from).Hours()/24) >= maxBookingSearchDays {
+		return nil, invalidError("период поиска не может превышать %d дней", maxBookingSearchDays)
+	}
+
+	slots, err := s.scheduleRepo.FindFreeBySpecialization(specialization, from, end)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска свободных слотов: %w", err)
+	}
+	cal, err := loadClinicCalendar(s.calendarRepo, from, end)
+	if err != nil {
+		return nil, err
+	}
+	slots, _ = cal.filterByCalendar(slots)
+
+	result := make([]models.BookingSlot, 0, len(slots))
+	for _, slot := range slots {
+		if slotStarted(&slot, now) {
+			continue
+		}
+		result = append(result, models.BookingSlot{
+			ScheduleID:     slot.ID,
+			DoctorID:       slot.DoctorID,
+			DoctorName:     slot.Doctor.FullName,
+			Specialization: slot.Doctor.Specialization,
+			Date:           slot.Date.Format("2006-01-02"),
+			StartTime:      slot.StartTime,
+			EndTime:        slot.EndTime,
+			Cabinet:        slot.Cabinet,
+		})
+	}
+	return result, nil
+}
+
+// Book записывает пациента на свободный слот. Пациент подтверждает личность полисом ОМС и датой рождения;
+// при несовпадении данных возвращается одна и та же ошибка, чтобы нельзя было подобрать номер полиса.
+func (s *BookingService) Book(req *models.SelfBookingRequest) (*models.SelfBookingResponse, error) {
+	birthDate, err := time.Parse("2006-01-02", strings.TrimSpace(req.BirthDate))
+	if err != nil {
+		return nil, invalidError("неверный формат даты рождения, используйте YYYY-MM-DD")
+	}
+	patient, err := s.patientRepo.FindByOMS(strings.TrimSpace(req.OmsNumber))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("ошибка поиска пациента: %w", err)
+	}
+	if patient == nil || patient.BirthDate.Format("2006-01-02") != birthDate.Format("2006-01-02") {
+		return nil, notFoundError("пациент с указанными полисом ОМС и датой рождения не найден")
+	}
+
+	schedule, err := s.scheduleRepo.GetByID(req.ScheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("указанный слот в расписании не найден")
+		}
+		return nil, fmt.Errorf("ошибка при поиске слота расписания: %w", err)
+	}
+	if slotStarted(schedule, time.Now()) {
+		return nil, conflictError("запись на прошедшее время невозможна")
+	}
+	day := truncateToDate(schedule.Date)
+	cal, err := loadClinicCalendar(s.calendarRepo, day, day)
+	if err != nil {
+		return nil, err
+	}
+	if reason := cal.blockReason(schedule); reason != "" {
+		return nil, conflictError("запись на выбранное время невозможна: %s", reason)
+	}
+
+	// Самостоятельная запись не может обойти правила записи: переопределение доступно только регистратору
+	check, err := s.rules.Evaluate(patient.ID, schedule)
+	if err != nil {
+		return nil, err
+	}
+	if len(check.Violations) > 0 {
+		return nil, &BookingRuleViolationError{Violations: check.Violations}
+	}
+
+	code, err := generateConfirmationCode()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сформировать код подтверждения: %w", err)
+	}
+	appointment, err := s.appointmentRepo.CreateSelfBookingInTransaction(
+		&models.CreateAppointmentRequest{ScheduleID: req.ScheduleID, PatientID: patient.ID}, code, s.limits)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать запись на прием: %w", err)
+	}
+	s.rules.attachReferral(check, appointment.ID)
+
+	logger.Default().WithField("module", "booking").
+		WithField("appointment_id", appointment.ID).WithField("schedule_id", appointment.ScheduleID).
+		Info("Пациент самостоятельно записался на прием")
+
+	return &models.SelfBookingResponse{
+		AppointmentID:    appointment.ID,
+		ConfirmationCode: code,
+		DoctorName:       appointment.Schedule.Doctor.FullName,
+		Specialization:   appointment.Schedule.Doctor.Specialization,
+		Date:             appointment.Schedule.Date.Format("2006-01-02"),
+		StartTime:        appointment.Schedule.StartTime,
+		Cabinet:          appointment.Schedule.Cabinet,
+	}, nil
+}
+
+// slotStarted проверяет, что прием в слоте уже начался или прошел.
+func slotStarted(slot *models.Schedule, now time.Time) bool {
+	day := truncateToDate(slot.Date)
+	today := truncateToDate(now)
+	return day.Before(today) || (day.Equal(today) && slot.StartTime <= now.Format("15:04:05"))
+}
+
+// generateConfirmationCode формирует случайный код подтверждения записи.
+func generateConfirmationCode() (string, error) {
+	buf := make([]byte, confirmationCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := make([]byte, confirmationCodeLength)
+	for i, b := range buf {
+		code[i] = confirmationCodeAlphabet[int(b)%len(confirmationCodeAlphabet)]
+	}
+	return string(code), nil
+}
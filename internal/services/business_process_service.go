@@ -4,8 +4,11 @@ import (
 	"ElectronicQueue/internal/logger"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/repository"
+	"errors"
 	"fmt"
 	"sync"
+
+	"gorm.io/gorm"
 )
 
 // BusinessProcessService управляет состоянием бизнес-процессов.
@@ -60,6 +63,18 @@ func (s *BusinessProcessService) GetAll() ([]models.BusinessProcess, error) {
 	return s.repo.GetAll()
 }
 
+// GetByName возвращает процесс по имени.
+func (s *BusinessProcessService) GetByName(processName string) (*models.BusinessProcess, error) {
+	process, err := s.repo.FindByName(processName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("process '%s' not found", processName)
+		}
+		return nil, fmt.Errorf("ошибка получения процесса '%s': %w", processName, err)
+	}
+	return process, nil
+}
+
 // UpdateStatus обновляет состояние процесса в БД и в кэше.
 func (s *BusinessProcessService) UpdateStatus(processName string, isEnabled bool) (*models.BusinessProcess, error) {
 	process, err := s.repo.FindByName(processName)
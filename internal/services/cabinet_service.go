@@ -0,0 +1,160 @@
+package services
+
+import (
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"ElectronicQueue/internal/utils"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// CabinetService управляет справочником кабинетов клиники.
+type CabinetService struct {
+	repo repository.CabinetRepository
+	// announcements - приглашения в кабинеты озвучиваются, поэтому для каждого кабинета нужны записи номера.
+	announcements bool
+}
+
+// NewCabinetService создает новый экземпляр CabinetService.
+func NewCabinetService(repo repository.CabinetRepository, announcements bool) *CabinetService {
+	return &CabinetService{repo: repo, announcements: announcements}
+}
+
+// GetAll возвращает кабинеты; при onlyActive - только действующие.
+func (s *CabinetService) GetAll(onlyActive bool) ([]models.Cabinet, error) {
+	cabinets, err := s.repo.GetAll(onlyActive)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка кабинетов: %w", err)
+	}
+	return cabinets, nil
+}
+
+// GetByNumber возвращает кабинет по номеру.
+func (s *CabinetService) GetByNumber(number int) (*models.Cabinet, error) {
+	cabinet, err := s.repo.GetByNumber(number)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("кабинет %d не найден", number)
+		}
+		return nil, fmt.Errorf("ошибка при поиске кабинета: %w", err)
+	}
+	return cabinet, nil
+}
+
+// Create добавляет кабинет в справочник.
+func (s *CabinetService) Create(req *models.CreateCabinetRequest) (*models.Cabinet, error) {
+	if _, err := s.repo.GetByNumber(req.Number); err == nil {
+		return nil, conflictError("кабинет %d уже существует", req.Number)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("ошибка при поиске кабинета: %w", err)
+	}
+	if s.announcements {
+		if err := utils.CheckCabinetAnnouncementFiles([]int{req.Number}, utils.AudioDir); err != nil {
+			return nil, invalidError("номер кабинета нельзя озвучить: %v", err)
+		}
+	}
+
+	cabinet := &models.Cabinet{
+		Number:     req.Number,
+		Name:       strings.TrimSpace(req.Name),
+		Floor:      req.Floor,
+		Wing:       req.Wing,
+		Directions: req.Directions,
+		Equipment:  normalizeEquipment(req.Equipment),
+		IsActive:   true,
+	}
+	if req.IsActive != nil {
+		cabinet.IsActive = *req.IsActive
+	}
+	if err := s.repo.Create(cabinet); err != nil {
+		return nil, fmt.Errorf("не удалось создать кабинет: %w", err)
+	}
+	return cabinet, nil
+}
+
+// Update частично обновляет кабинет. Номер кабинета не изменяется.
+func (s *CabinetService) Update(number int, req *models.UpdateCabinetRequest) (*models.Cabinet, error) {
+	cabinet, err := s.GetByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		cabinet.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Floor != nil {
+		cabinet.Floor = req.Floor
+	}
+	if req.Wing != nil {
+		cabinet.Wing = *req.Wing
+	}
+	if req.Directions != nil {
+		cabinet.Directions = *req.Directions
+	}
+	if req.Equipment != nil {
+		cabinet.Equipment = normalizeEquipment(*req.Equipment)
+	}
+	if req.IsActive != nil {
+		cabinet.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Update(cabinet); err != nil {
+		return nil, fmt.Errorf("не удалось обновить кабинет: %w", err)
+	}
+	return cabinet, nil
+}
+
+// Delete удаляет кабинет, если на него не ссылаются слоты расписания и шаблоны.
+// Используемый кабинет можно только деактивировать.
+func (s *CabinetService) Delete(number int) error {
+	if _, err := s.GetByNumber(number); err != nil {
+		return err
+	}
+	used, err := s.repo.CountUsage(number)
+	if err != nil {
+		return fmt.Errorf("ошибка проверки использования кабинета: %w", err)
+	}
+	if used > 0 {
+		return conflictError("кабинет %d используется в расписании (%d), его можно только деактивировать", number, used)
+	}
+	if err := s.repo.Delete(number); err != nil {
+		return fmt.Errorf("не удалось удалить кабинет: %w", err)
+	}
+	return nil
+}
+
+// normalizeEquipment убирает пустые и повторяющиеся метки оборудования.
+func normalizeEquipment(tags []string) models.StringList {
+	result := models.StringList{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// checkCabinetUsable проверяет, что кабинет существует и действует, чтобы в него можно было назначить прием.
+func checkCabinetUsable(repo repository.CabinetRepository, number *int) error {
+	if number == nil {
+		return nil
+	}
+	cabinet, err := repo.GetByNumber(*number)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFoundError("кабинет %d не найден", *number)
+		}
+		return fmt.Errorf("ошибка при поиске кабинета: %w", err)
+	}
+	if !cabinet.IsActive {
+		return conflictError("кабинет %d выведен из эксплуатации", *number)
+	}
+	return nil
+}
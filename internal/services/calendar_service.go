@@ -0,0 +1,314 @@
+package services
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxAbsenceDays ограничивает длительность одного периода отсутствия врача.
+const maxAbsenceDays = 366
+
+// CalendarService управляет календарем клиники (нерабочие и сокращенные дни) и отсутствиями врачей.
+type CalendarService struct {
+	calendarRepo repository.CalendarRepository
+	doctorRepo   repository.DoctorRepository
+}
+
+// NewCalendarService создает новый экземпляр CalendarService.
+func NewCalendarService(calendarRepo repository.CalendarRepository, doctorRepo repository.DoctorRepository) *CalendarService {
+	return &CalendarService{
+		calendarRepo: calendarRepo,
+		doctorRepo:   doctorRepo,
+	}
+}
+
+// GetClinicDays возвращает особые дни клиники за период.
+func (s *CalendarService) GetClinicDays(from, to time.Time) ([]models.ClinicDay, error) {
+	days, err := s.calendarRepo.FindClinicDays(truncateToDate(from), truncateToDate(to))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения календаря клиники: %w", err)
+	}
+	return days, nil
+}
+
+// GetClinicDay возвращает особый день клиники на дату.
+func (s *CalendarService) GetClinicDay(date time.Time) (*models.ClinicDay, error) {
+	date = truncateToDate(date)
+	day, err := s.calendarRepo.GetClinicDay(date)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("день %s не найден в календаре клиники", date.Format("2006-01-02"))
+		}
+		return nil, fmt.Errorf("ошибка при поиске дня календаря: %w", err)
+	}
+	return day, nil
+}
+
+// SetClinicDay добавляет или заменяет особый день клиники и помечает записи пациентов,
+// которые попали в нерабочее время. Сами записи не удаляются: решение принимает регистратура.
+func (s *CalendarService) SetClinicDay(date time.Time, req *models.ClinicDayRequest) (*models.ClinicDayResult, error) {
+	day := models.ClinicDay{
+		Date:        truncateToDate(date),
+		DayType:     req.DayType,
+		Description: req.Description,
+	}
+	if req.DayType == models.ClinicDayShort {
+		if req.WorkStart == nil || req.WorkEnd == nil {
+			return nil, invalidError("для сокращенного дня необходимо указать часы работы")
+		}
+		start, end := *req.WorkStart, *req.WorkEnd
+		if err := normalizeClockPtr(&start); err != nil {
+			return nil, err
+		}
+		if err := normalizeClockPtr(&end); err != nil {
+			return nil, err
+		}
+		if end <= start {
+			return nil, invalidError("время окончания работы должно быть позже времени начала")
+		}
+		day.WorkStart, day.WorkEnd = &start, &end
+	}
+
+	source := clinicDayFlagSource(day.Date)
+	// Пометки прежней версии дня снимаются, чтобы после замены остались только актуальные.
+	if err := s.calendarRepo.ClearAppointmentFlag(source); err != nil {
+		return nil, fmt.Errorf("не удалось снять пометки с записей: %w", err)
+	}
+	if err := s.calendarRepo.SaveClinicDay(&day); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить день календаря: %w", err)
+	}
+
+	cal := &clinicCalendar{days: map[string]models.ClinicDay{day.Date.Format("2006-01-02"): day}}
+	affected, err := s.flagAppointments(nil, day.Date, day.Date, cal, source, clinicDayFlagReason(day.Date))
+	if err != nil {
+		return nil, err
+	}
+	logger.Default().WithField("module", "calendar").
+		WithField("date", day.Date.Format("2006-01-02")).WithField("day_type", string(day.DayType)).
+		WithField("flagged", len(affected)).
+		Info("Clinic calendar day saved")
+	return &models.ClinicDayResult{Day: day, Appointments: affected}, nil
+}
+
+// DeleteClinicDay удаляет особый день клиники и снимает связанные с ним пометки с записей.
+func (s *CalendarService) DeleteClinicDay(date time.Time) error {
+	date = truncateToDate(date)
+	if _, err := s.GetClinicDay(date); err != nil {
+		return err
+	}
+	if err := s.calendarRepo.DeleteClinicDay(date); err != nil {
+		return fmt.Errorf("не удалось удалить день календаря: %w", err)
+	}
+	if err := s.calendarRepo.ClearAppointmentFlag(clinicDayFlagSource(date)); err != nil {
+		return fmt.Errorf("не удалось снять пометки с записей: %w", err)
+	}
+	return nil
+}
+
+// GetAbsences возвращает отсутствия врачей, пересекающиеся с периодом.
+func (s *CalendarService) GetAbsences(doctorID *uint, from, to time.Time) ([]models.DoctorAbsence, error) {
+	absences, err := s.calendarRepo.FindAbsences(doctorID, truncateToDate(from), truncateToDate(to))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения отсутствий врачей: %w", err)
+	}
+	return absences, nil
+}
+
+// GetAbsence возвращает отсутствие врача по ID.
+func (s *CalendarService) GetAbsence(id uint) (*models.DoctorAbsence, error) {
+	absence, err := s.calendarRepo.GetAbsenceByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("отсутствие с ID %d не найдено", id)
+		}
+		return nil, fmt.Errorf("ошибка при поиске отсутствия врача: %w", err)
+	}
+	return absence, nil
+}
+
+// CreateAbsence регистрирует отсутствие врача и помечает записи пациентов, попавшие в этот период.
+func (s *CalendarService) CreateAbsence(req *models.DoctorAbsenceRequest) (*models.DoctorAbsenceResult, error) {
+	if _, err := s.doctorRepo.GetByID(req.DoctorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("врач с ID %d не найден", req.DoctorID)
+		}
+		return nil, fmt.Errorf("ошибка проверки врача: %w", err)
+	}
+
+	from, to := truncateToDate(req.DateFrom), truncateToDate(req.DateTo)
+	if to.Before(from) {
+		return nil, invalidError("дата окончания отсутствия раньше даты начала")
+	}
+	if int(to.Sub(from).Hours()/24) >= maxAbsenceDays {
+		return nil, invalidError("период отсутствия не может превышать %d дней", maxAbsenceDays)
+	}
+	if (req.StartTime == nil) != (req.EndTime == nil) {
+		return nil, invalidError("для частичного отсутствия необходимо указать время начала и окончания")
+	}
+	if err := normalizeClockPtr(req.StartTime); err != nil {
+		return nil, err
+	}
+	if err := normalizeClockPtr(req.EndTime); err != nil {
+		return nil, err
+	}
+	if req.StartTime != nil && *req.EndTime <= *req.StartTime {
+		return nil, invalidError("время окончания отсутствия должно быть позже времени начала")
+	}
+
+	absence := models.DoctorAbsence{
+		DoctorID:  req.DoctorID,
+		DateFrom:  from,
+		DateTo:    to,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Reason:    req.Reason,
+		Comment:   req.Comment,
+	}
+	if err := s.calendarRepo.CreateAbsence(&absence); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить отсутствие врача: %w", err)
+	}
+
+	cal := &clinicCalendar{absences: []models.DoctorAbsence{absence}}
+	affected, err := s.flagAppointments(&absence.DoctorID, from, to, cal, absenceFlagSource(absence.ID), absenceFlagReason(&absence))
+	if err != nil {
+		return nil, err
+	}
+	logger.Default().WithField("module", "calendar").
+		WithField("doctor_id", absence.DoctorID).WithField("absence_id", absence.ID).
+		WithField("flagged", len(affected)).
+		Info("Doctor absence registered")
+	return &models.DoctorAbsenceResult{Absence: absence, Appointments: affected}, nil
+}
+
+// DeleteAbsence удаляет отсутствие врача и снимает связанные с ним пометки с записей.
+func (s *CalendarService) DeleteAbsence(id uint) error {
+	absence, err := s.GetAbsence(id)
+	if err != nil {
+		return err
+	}
+	if err := s.calendarRepo.DeleteAbsence(id); err != nil {
+		return fmt.Errorf("не удалось удалить отсутствие врача: %w", err)
+	}
+	if err := s.calendarRepo.ClearAppointmentFlag(absenceFlagSource(absence.ID)); err != nil {
+		return fmt.Errorf("не удалось снять пометки с записей: %w", err)
+	}
+	return nil
+}
+
+// flagAppointments помечает от источника source записи за период, слоты которых недоступны по календарю cal.
+func (s *CalendarService) flagAppointments(doctorID *uint, from, to time.Time, cal *clinicCalendar, source, reason string) ([]models.AffectedAppointment, error) {
+	appointments, err := s.calendarRepo.FindAppointmentsInRange(doctorID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения записей пациентов: %w", err)
+	}
+
+	affected := []models.AffectedAppointment{}
+	var ids []uint
+	for _, app := range appointments {
+		if cal.blockReason(&app.Schedule) == "" {
+			continue
+		}
+		ids = append(ids, app.ID)
+		affected = append(affected, models.AffectedAppointment{
+			AppointmentID:   app.ID,
+			PatientID:       app.PatientID,
+			PatientFullName: app.Patient.FullName,
+			PatientPhone:    app.Patient.Phone,
+			Date:            app.Schedule.Date.Format("2006-01-02"),
+			StartTime:       app.Schedule.StartTime,
+			Action:          models.AffectedActionNeedsCall,
+		})
+	}
+	if err := s.calendarRepo.SetAppointmentFlag(ids, source, reason); err != nil {
+		return nil, fmt.Errorf("не удалось пометить записи пациентов: %w", err)
+	}
+	return affected, nil
+}
+
+// clinicDayFlagSource и absenceFlagSource - ключи источников пометок, по которым они снимаются.
+func clinicDayFlagSource(date time.Time) string {
+	return "clinic_day:" + date.Format("2006-01-02")
+}
+
+func absenceFlagSource(absenceID uint) string {
+	return fmt.Sprintf("absence:%d", absenceID)
+}
+
+func clinicDayFlagReason(date time.Time) string {
+	return fmt.Sprintf("календарь клиники: %s", date.Format("2006-01-02"))
+}
+
+func absenceFlagReason(absence *models.DoctorAbsence) string {
+	return fmt.Sprintf("отсутствие врача #%d: %s", absence.ID, absence.Reason)
+}
+
+// clinicCalendar - загруженный фрагмент календаря клиники и отсутствий врачей,
+// по которому проверяется доступность слотов.
+type clinicCalendar struct {
+	days     map[string]models.ClinicDay
+	absences []models.DoctorAbsence
+}
+
+// loadClinicCalendar загружает особые дни и отсутствия врачей за период [from, to].
+func loadClinicCalendar(repo repository.CalendarRepository, from, to time.Time) (*clinicCalendar, error) {
+	days, err := repo.FindClinicDays(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения календаря клиники: %w", err)
+	}
+	absences, err := repo.FindAbsences(nil, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения отсутствий врачей: %w", err)
+	}
+	cal := &clinicCalendar{days: make(map[string]models.ClinicDay, len(days)), absences: absences}
+	for _, d := range days {
+		cal.days[d.Date.Format("2006-01-02")] = d
+	}
+	return cal, nil
+}
+
+// blockReason возвращает причину, по которой прием в слоте невозможен, или пустую строку.
+func (c *clinicCalendar) blockReason(slot *models.Schedule) string {
+	date := slot.Date.Format("2006-01-02")
+	if day, ok := c.days[date]; ok {
+		switch day.DayType {
+		case models.ClinicDayHoliday:
+			return fmt.Sprintf("%s - нерабочий день клиники", date)
+		case models.ClinicDayShort:
+			if day.WorkStart != nil && day.WorkEnd != nil && (slot.StartTime < *day.WorkStart || slot.EndTime > *day.WorkEnd) {
+				return fmt.Sprintf("%s - сокращенный день, клиника работает с %s до %s", date, (*day.WorkStart)[:5], (*day.WorkEnd)[:5])
+			}
+		}
+	}
+
+	slotDate := truncateToDate(slot.Date)
+	for _, a := range c.absences {
+		if a.DoctorID != slot.DoctorID {
+			continue
+		}
+		if slotDate.Before(truncateToDate(a.DateFrom)) || slotDate.After(truncateToDate(a.DateTo)) {
+			continue
+		}
+		if a.StartTime != nil && a.EndTime != nil && !(slot.StartTime < *a.EndTime && *a.StartTime < slot.EndTime) {
+			continue
+		}
+		return fmt.Sprintf("врач отсутствует (%s)", a.Reason)
+	}
+	return ""
+}
+
+// filterByCalendar отбрасывает слоты, недоступные по календарю, и возвращает число отброшенных.
+func (c *clinicCalendar) filterByCalendar(slots []models.Schedule) ([]models.Schedule, int) {
+	result := make([]models.Schedule, 0, len(slots))
+	for i := range slots {
+		if c.blockReason(&slots[i]) == "" {
+			result = append(result, slots[i])
+		}
+	}
+	return result, len(slots) - len(result)
+}
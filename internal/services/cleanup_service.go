@@ -19,9 +19,9 @@ func NewCleanupService(repo repository.CleanupRepository) *CleanupService {
 	}
 }
 
-// CleanTickets удаляет завершенные tickets и осиротевшие appointments
+// CleanTickets удаляет завершенные tickets и отмечает неявки по прошедшим appointments
 func (s *CleanupService) CleanTickets() error {
-	s.log.Info("Начинаю очистку завершенных tickets и осиротевших appointments")
+	s.log.Info("Начинаю очистку завершенных tickets и отметку неявок по appointments")
 
 	// Получаем количество завершенных tickets
 	ticketsCount, err := s.repo.GetTicketsCount()
@@ -30,16 +30,16 @@ func (s *CleanupService) CleanTickets() error {
 		return err
 	}
 
-	// Получаем количество осиротевших appointments
-	appointmentsCount, err := s.repo.GetOrphanedAppointmentsCount()
+	// Получаем количество прошедших appointments без явки
+	appointmentsCount, err := s.repo.GetMissedAppointmentsCount()
 	if err != nil {
-		s.log.WithError(err).Error("Ошибка получения количества осиротевших appointments")
+		s.log.WithError(err).Error("Ошибка получения количества неявок по appointments")
 		return err
 	}
 
 	s.log.WithFields(logrus.Fields{
-		"completed_tickets_count":     ticketsCount,
-		"orphaned_appointments_count": appointmentsCount,
+		"completed_tickets_count":   ticketsCount,
+		"missed_appointments_count": appointmentsCount,
 	}).Info("Найдено записей для очистки")
 
 	// Выполняем очистку
@@ -48,6 +48,6 @@ func (s *CleanupService) CleanTickets() error {
 		return err
 	}
 
-	s.log.Info("Очистка завершенных tickets и осиротевших appointments завершена успешно")
+	s.log.Info("Очистка завершенных tickets и отметка неявок по appointments завершена успешно")
 	return nil
 }
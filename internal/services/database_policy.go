@@ -0,0 +1,61 @@
+package services
+
+import "ElectronicQueue/internal/models"
+
+var allOperations = []string{
+	models.OperationSelect,
+	models.OperationInsert,
+	models.OperationUpdate,
+	models.OperationDelete,
+}
+
+// defaultTablePolicies - перечень таблиц, доступных через внешний API базы данных.
+// Таблицы, которых нет в списке (учетные записи, бизнес-процессы, API-ключи), недоступны.
+// Для записи открыты только явно перечисленные столбцы: первичные ключи, статусы и признаки занятости
+// меняются только через API приложения, которое проверяет записи, блокировки слотов и лист ожидания.
+var defaultTablePolicies = map[string]models.TablePolicy{
+	// Талоны выдаются терминалом, их номер и статус ведет очередь.
+	"tickets": {
+		Operations:      []string{models.OperationSelect, models.OperationUpdate, models.OperationDelete},
+		WritableColumns: []string{"service_type", "window_number"},
+	},
+	"services": {
+		Operations:      allOperations,
+		WritableColumns: []string{"name", "letter"},
+	},
+	// Новые слоты можно добавить напрямую (они создаются свободными), а изменение и удаление
+	// слотов выполняется через API расписания с проверкой записей и удержаний листа ожидания.
+	"schedules": {
+		Operations:      []string{models.OperationSelect, models.OperationInsert},
+		WritableColumns: []string{"doctor_id", "date", "start_time", "end_time", "cabinet"},
+	},
+	// Записи создаются, переносятся и отменяются только через API регистратуры и самозаписи.
+	"appointments": {
+		Operations: []string{models.OperationSelect},
+	},
+	"doctors": {
+		Operations:      []string{models.OperationSelect, models.OperationInsert, models.OperationUpdate},
+		HiddenColumns:   []string{"login", "password_hash"},
+		WritableColumns: []string{"full_name", "specialization", "status"},
+	},
+	// Паспорт и полис ОМС шифруются приложением, поэтому их нельзя ни прочитать, ни записать напрямую;
+	// телефон выдается маскированным. Пациенты создаются только через API регистратуры.
+	"patients": {
+		Operations: []string{models.OperationSelect, models.OperationUpdate},
+		HiddenColumns: []string{
+			"passport_series", "passport_number", "oms_number",
+			"passport_series_enc", "passport_number_enc", "oms_number_enc", "passport_index", "oms_index",
+		},
+		MaskedColumns:   []string{"phone"},
+		WritableColumns: []string{"full_name", "birth_date", "phone"},
+	},
+	"reception_logs": {
+		Operations: []string{models.OperationSelect},
+	},
+	"ads": {
+		Operations: []string{models.OperationSelect},
+	},
+	"cabinets": {
+		Operations: []string{models.OperationSelect},
+	},
+}
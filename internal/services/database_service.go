@@ -1,132 +1,601 @@
 package services
 
 import (
+	"ElectronicQueue/internal/export"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/repository"
+	"ElectronicQueue/internal/utils"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
+const (
+	// maskVisibleChars - сколько последних символов остается видимым в маскированных столбцах.
+	maskVisibleChars = 2
+	// maxSelectLimit - максимальный размер страницы выборки.
+	maxSelectLimit = 1000
+	// maxFilterDepth - максимальная вложенность групп условий.
+	maxFilterDepth = 5
+	// maxAuditRows - сколько затронутых строк сохраняется в журнале аудита при изменении через API.
+	maxAuditRows = 100
+)
+
 // DatabaseService предоставляет методы для работы с данными таблиц.
 type DatabaseService struct {
-	repo repository.DatabaseRepository
+	repo     repository.DatabaseRepository
+	policies map[string]models.TablePolicy
 }
 
 func NewDatabaseService(repo repository.DatabaseRepository) *DatabaseService {
-	return &DatabaseService{repo: repo}
+	return &DatabaseService{repo: repo, policies: defaultTablePolicies}
+}
+
+// tableAccess содержит политику таблицы, разрешенную для конкретной операции, и вычисленные наборы столбцов.
+type tableAccess struct {
+	policy   models.TablePolicy
+	columns  []string
+	readable map[string]bool
+	writable map[string]bool
+	masked   map[string]bool
+}
+
+// readableColumns возвращает читаемые столбцы в порядке их следования в таблице.
+func (a *tableAccess) readableColumns() []string {
+	var result []string
+	for _, col := range a.columns {
+		if a.readable[col] {
+			result = append(result, col)
+		}
+	}
+	return result
+}
+
+// resolveAccess проверяет, что таблица открыта для операции, и вычисляет доступные столбцы.
+func (s *DatabaseService) resolveAccess(tableName, operation string) (*tableAccess, error) {
+	policy, ok := s.policies[tableName]
+	if !ok {
+		return nil, forbiddenError("таблица '%s' недоступна через API", tableName)
+	}
+	if !policy.Allows(operation) {
+		return nil, forbiddenError("операция '%s' запрещена для таблицы '%s'", operation, tableName)
+	}
+
+	columns, err := s.repo.GetTableColumns(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить таблицу '%s': %w", tableName, err)
+	}
+
+	hidden := toSet(policy.HiddenColumns)
+	access := &tableAccess{
+		policy:   policy,
+		columns:  columns,
+		readable: make(map[string]bool),
+		writable: make(map[string]bool),
+		masked:   toSet(policy.MaskedColumns),
+	}
+
+	readableList := toSet(policy.ReadableColumns)
+	writableList := toSet(policy.WritableColumns)
+	for _, col := range columns {
+		if hidden[col] {
+			continue
+		}
+		if len(readableList) == 0 || readableList[col] {
+			access.readable[col] = true
+		}
+		if writableList[col] {
+			access.writable[col] = true
+		}
+	}
+	return access, nil
+}
+
+// ListTables возвращает таблицы, доступные через API, с операциями, разрешенными ключу.
+// Таблицы, для которых у ключа нет ни одной области доступа, не включаются.
+func (s *DatabaseService) ListTables(key *models.APIKey) []models.TableSummary {
+	names := make([]string, 0, len(s.policies))
+	for name := range s.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tables := make([]models.TableSummary, 0, len(names))
+	for _, name := range names {
+		ops := s.keyOperations(name, key)
+		if len(ops) == 0 {
+			continue
+		}
+		tables = append(tables, models.TableSummary{Name: name, Operations: ops})
+	}
+	return tables
+}
+
+// GetTableSchema возвращает описание столбцов таблицы с учетом политики и прав ключа.
+// Скрытые и нечитаемые столбцы не выводятся, внешние ключи на закрытые таблицы опускаются.
+func (s *DatabaseService) GetTableSchema(tableName string, key *models.APIKey) (*models.TableSchema, error) {
+	ops := s.keyOperations(tableName, key)
+	if len(ops) == 0 {
+		return nil, forbiddenError("таблица '%s' недоступна через API", tableName)
+	}
+
+	access, err := s.resolveAccess(tableName, ops[0])
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := s.repo.GetColumnSchemas(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	canWrite := false
+	for _, op := range ops {
+		if op == models.OperationInsert || op == models.OperationUpdate {
+			canWrite = true
+		}
+	}
+
+	schema := &models.TableSchema{Name: tableName, Operations: ops, Columns: make([]models.ColumnSchema, 0, len(columns))}
+	for _, col := range columns {
+		if !access.readable[col.Name] {
+			continue
+		}
+		col.Writable = canWrite && access.writable[col.Name]
+		col.Masked = access.masked[col.Name]
+		if col.References != nil {
+			if _, exposed := s.policies[col.References.Table]; !exposed {
+				col.References = nil
+			}
+		}
+		schema.Columns = append(schema.Columns, col)
+	}
+	return schema, nil
+}
+
+// keyOperations возвращает операции над таблицей, разрешенные и политикой, и областями доступа ключа.
+func (s *DatabaseService) keyOperations(tableName string, key *models.APIKey) []string {
+	policy, ok := s.policies[tableName]
+	if !ok {
+		return nil
+	}
+	var ops []string
+	for _, op := range policy.Operations {
+		if key == nil || key.HasScope(fmt.Sprintf("database:%s:%s", tableName, op)) {
+			ops = append(ops, op)
+		}
+	}
+	return ops
 }
 
 // GetData выполняет валидацию и вызывает репозиторий для получения данных.
-func (s *DatabaseService) GetData(tableName string, request models.GetDataRequest) ([]map[string]interface{}, int64, error) {
-	allowedColumns, err := s.repo.GetTableColumns(tableName)
+// Возвращает курсор следующей страницы, если выборка упорядочена и страница заполнена целиком.
+func (s *DatabaseService) GetData(tableName string, request models.GetDataRequest) ([]map[string]interface{}, int64, string, error) {
+	access, err := s.resolveAccess(tableName, models.OperationSelect)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	if err := s.validateFilters(request.Filters, access, 0); err != nil {
+		return nil, 0, "", err
+	}
+
+	fields, err := s.resolveFields(request.Fields, access)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	ordering, err := s.resolveSort(tableName, request, access)
 	if err != nil {
-		return nil, 0, fmt.Errorf("не удалось проверить таблицу '%s': %w", tableName, err)
+		return nil, 0, "", err
+	}
+
+	query := models.DataQuery{
+		Columns: fields,
+		Sort:    ordering,
+		Filters: request.Filters,
+		Page:    request.Page,
+		Limit:   request.Limit,
+	}
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 || query.Limit > maxSelectLimit {
+		query.Limit = maxSelectLimit
+	}
+
+	if request.Cursor != "" {
+		after, err := decodeCursor(request.Cursor, len(ordering))
+		if err != nil {
+			return nil, 0, "", err
+		}
+		query.After = after
+	}
+
+	// Для построения курсора столбцы сортировки должны попасть в выборку,
+	// даже если клиент их не запрашивал; лишние столбцы удаляются после.
+	requested := toSet(fields)
+	var extra []string
+	for _, sf := range ordering {
+		if !requested[sf.Field] {
+			extra = append(extra, sf.Field)
+			requested[sf.Field] = true
+		}
 	}
+	query.Columns = append(query.Columns, extra...)
 
-	if err := s.validateFilters(request.Filters, allowedColumns); err != nil {
-		return nil, 0, err
+	data, total, err := s.repo.GetData(tableName, query)
+	if err != nil {
+		return nil, 0, "", err
 	}
 
-	page := request.Page
-	if page <= 0 {
-		page = 1
+	var nextCursor string
+	if len(ordering) > 0 && len(data) == query.Limit {
+		nextCursor, err = encodeCursor(data[len(data)-1], ordering)
+		if err != nil {
+			return nil, 0, "", err
+		}
 	}
-	limit := request.Limit
-	if limit <= 0 {
-		limit = 1000
+
+	for _, row := range data {
+		for _, col := range extra {
+			delete(row, col)
+		}
 	}
 
-	return s.repo.GetData(tableName, page, limit, request.Filters)
+	s.maskRows(data, access)
+	return data, total, nextCursor, nil
 }
 
-func (s *DatabaseService) InsertData(tableName string, request models.InsertRequest) (int64, error) {
-	_, err := s.repo.GetTableColumns(tableName)
+// ExportData выгружает все записи таблицы, удовлетворяющие фильтрам запроса, в w.
+// Пагинация и курсор игнорируются, сортировка и выбор полей применяются как в GetData.
+func (s *DatabaseService) ExportData(tableName string, request models.GetDataRequest, w export.Writer) error {
+	access, err := s.resolveAccess(tableName, models.OperationSelect)
+	if err != nil {
+		return err
+	}
+
+	if err := s.validateFilters(request.Filters, access, 0); err != nil {
+		return err
+	}
+
+	fields, err := s.resolveFields(request.Fields, access)
 	if err != nil {
-		return 0, fmt.Errorf("не удалось проверить таблицу '%s': %w", tableName, err)
+		return err
 	}
 
-	return s.repo.InsertData(tableName, request.Data)
+	request.Cursor = ""
+	ordering, err := s.resolveSort(tableName, request, access)
+	if err != nil {
+		return err
+	}
+
+	columns := make([]export.Column, len(fields))
+	for i, f := range fields {
+		columns[i] = export.Column{Key: f, Title: f}
+	}
+	if err := w.WriteHeader(export.Titles(columns)); err != nil {
+		return err
+	}
+
+	query := models.DataQuery{Columns: fields, Sort: ordering, Filters: request.Filters}
+	batch := make([]map[string]interface{}, 1)
+	return s.repo.StreamData(tableName, query, func(row map[string]interface{}) error {
+		batch[0] = row
+		s.maskRows(batch, access)
+		return export.WriteMaps(w, columns, row)
+	})
 }
 
-func (s *DatabaseService) UpdateData(tableName string, request models.UpdateRequest) (int64, error) {
-	allowedColumns, err := s.repo.GetTableColumns(tableName)
+// resolveFields проверяет запрошенную проекцию; пустой список означает все читаемые столбцы.
+func (s *DatabaseService) resolveFields(fields []string, access *tableAccess) ([]string, error) {
+	if len(fields) == 0 {
+		return access.readableColumns(), nil
+	}
+	seen := make(map[string]bool, len(fields))
+	result := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !access.readable[f] {
+			return nil, invalidError("field '%s' is not allowed for selection in this table", f)
+		}
+		if !seen[f] {
+			seen[f] = true
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// resolveSort проверяет поля сортировки и дополняет их первичным ключом,
+// чтобы порядок был детерминированным и пригодным для keyset-пагинации.
+// Для tickets без явной сортировки и курсора сохраняется сортировка по статусу.
+func (s *DatabaseService) resolveSort(tableName string, request models.GetDataRequest, access *tableAccess) ([]models.SortField, error) {
+	if len(request.Sort) == 0 && request.Cursor == "" && tableName == "tickets" {
+		return nil, nil
+	}
+
+	used := make(map[string]bool)
+	var ordering []models.SortField
+	for _, sf := range request.Sort {
+		if !access.readable[sf.Field] {
+			return nil, invalidError("field '%s' is not allowed for sorting in this table", sf.Field)
+		}
+		if access.masked[sf.Field] {
+			return nil, invalidError("field '%s' cannot be used for sorting", sf.Field)
+		}
+		dir := strings.ToLower(sf.Direction)
+		if dir == "" {
+			dir = "asc"
+		}
+		if dir != "asc" && dir != "desc" {
+			return nil, invalidError("invalid sort direction: %s", sf.Direction)
+		}
+		if used[sf.Field] {
+			continue
+		}
+		used[sf.Field] = true
+		ordering = append(ordering, models.SortField{Field: sf.Field, Direction: dir})
+	}
+
+	pk, err := s.repo.GetPrimaryKey(tableName)
 	if err != nil {
-		return 0, fmt.Errorf("не удалось проверить таблицу '%s': %w", tableName, err)
+		return nil, err
 	}
+	if len(pk) == 0 && request.Cursor != "" {
+		return nil, invalidError("таблица '%s' не имеет первичного ключа, курсорная пагинация недоступна", tableName)
+	}
+	for _, col := range pk {
+		if !used[col] {
+			used[col] = true
+			ordering = append(ordering, models.SortField{Field: col, Direction: "asc"})
+		}
+	}
+	return ordering, nil
+}
 
-	if len(request.Filters.Conditions) == 0 {
-		return 0, fmt.Errorf("обновление без фильтров запрещено")
+// encodeCursor кодирует значения ключей сортировки строки в непрозрачный курсор.
+func encodeCursor(row map[string]interface{}, ordering []models.SortField) (string, error) {
+	values := make([]interface{}, len(ordering))
+	for i, sf := range ordering {
+		values[i] = row[sf.Field]
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("не удалось сформировать курсор: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor разбирает курсор и проверяет, что он соответствует текущей сортировке.
+func decodeCursor(cursor string, expected int) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalidError("некорректный курсор")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var values []interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return nil, invalidError("некорректный курсор")
 	}
+	if len(values) != expected {
+		return nil, invalidError("курсор не соответствует параметрам сортировки")
+	}
+	for i, v := range values {
+		if v == nil {
+			return nil, invalidError("курсор не поддерживает сортировку по столбцам со значением NULL")
+		}
+		if num, ok := v.(json.Number); ok {
+			if n, err := num.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := num.Float64(); err == nil {
+				values[i] = f
+			}
+		}
+	}
+	return values, nil
+}
 
-	if err := s.validateFilters(request.Filters, allowedColumns); err != nil {
+func (s *DatabaseService) InsertData(tableName string, request models.InsertRequest) (int64, error) {
+	access, err := s.resolveAccess(tableName, models.OperationInsert)
+	if err != nil {
 		return 0, err
 	}
 
-	colsMap := make(map[string]bool)
-	for _, col := range allowedColumns {
-		colsMap[col] = true
+	rows, err := normalizeInsertData(request.Data)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, invalidError("нет данных для вставки")
 	}
-	for key := range request.Data {
-		if !colsMap[key] {
-			return 0, fmt.Errorf("поле '%s' не найдено в таблице '%s'", key, tableName)
+
+	for _, row := range rows {
+		if err := s.validateWritableKeys(tableName, row, access); err != nil {
+			return 0, err
 		}
 	}
 
-	return s.repo.UpdateData(tableName, request.Data, request.Filters)
+	affected, err := s.repo.InsertData(tableName, rows)
+	return affected, err
+}
+
+func (s *DatabaseService) UpdateData(tableName string, request models.UpdateRequest) (int64, error) {
+	access, err := s.resolveAccess(tableName, models.OperationUpdate)
+	if err != nil {
+		return 0, err
+	}
+
+	if request.Filters.IsEmpty() {
+		return 0, invalidError("обновление без фильтров запрещено")
+	}
+
+	if err := s.validateFilters(request.Filters, access, 0); err != nil {
+		return 0, err
+	}
+
+	if err := s.validateWritableKeys(tableName, request.Data, access); err != nil {
+		return 0, err
+	}
+
+	affected, err := s.repo.UpdateData(tableName, request.Data, request.Filters)
+	return affected, err
 }
 
 func (s *DatabaseService) DeleteData(tableName string, request models.DeleteRequest) (int64, error) {
-	allowedColumns, err := s.repo.GetTableColumns(tableName)
+	access, err := s.resolveAccess(tableName, models.OperationDelete)
 	if err != nil {
-		return 0, fmt.Errorf("не удалось проверить таблицу '%s': %w", tableName, err)
+		return 0, err
 	}
 
-	if len(request.Filters.Conditions) == 0 {
-		return 0, fmt.Errorf("удаление без фильтров запрещено")
+	if request.Filters.IsEmpty() {
+		return 0, invalidError("удаление без фильтров запрещено")
 	}
 
-	if err := s.validateFilters(request.Filters, allowedColumns); err != nil {
+	if err := s.validateFilters(request.Filters, access, 0); err != nil {
 		return 0, err
 	}
 
-	return s.repo.DeleteData(tableName, request.Filters)
+	affected, err := s.repo.DeleteData(tableName, request.Filters)
+	return affected, err
+}
+
+// AuditRows возвращает до maxAuditRows строк таблицы, подходящих под фильтры, для журнала аудита.
+// Скрытые и маскируемые столбцы обрабатываются так же, как при выборке. Ошибка выборки возвращается,
+// чтобы изменение не выполнялось без исходного состояния.
+func (s *DatabaseService) AuditRows(tableName string, filters models.Filters) ([]map[string]interface{}, error) {
+	rows, _, _, err := s.GetData(tableName, models.GetDataRequest{Page: 1, Limit: maxAuditRows, Filters: filters})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// validateWritableKeys проверяет, что все ключи записи существуют в таблице и разрешены для записи.
+func (s *DatabaseService) validateWritableKeys(tableName string, data map[string]interface{}, access *tableAccess) error {
+	existing := toSet(access.columns)
+	for key := range data {
+		if !existing[key] {
+			return invalidError("поле '%s' не найдено в таблице '%s'", key, tableName)
+		}
+		if !access.writable[key] {
+			return invalidError("поле '%s' таблицы '%s' недоступно для записи", key, tableName)
+		}
+	}
+	return nil
+}
+
+// maskRows маскирует значения чувствительных столбцов в результатах выборки.
+func (s *DatabaseService) maskRows(rows []map[string]interface{}, access *tableAccess) {
+	if len(access.masked) == 0 {
+		return
+	}
+	for _, row := range rows {
+		for col := range access.masked {
+			value, ok := row[col]
+			if !ok || value == nil {
+				continue
+			}
+			if str, isString := value.(string); isString {
+				row[col] = utils.MaskString(str, maskVisibleChars)
+			} else {
+				row[col] = "***"
+			}
+		}
+	}
 }
 
-func (s *DatabaseService) validateFilters(filters models.Filters, allowedColumns []string) error {
+func (s *DatabaseService) validateFilters(filters models.Filters, access *tableAccess, depth int) error {
+	if depth > maxFilterDepth {
+		return invalidError("filter groups are nested deeper than %d levels", maxFilterDepth)
+	}
+
 	op := strings.ToUpper(filters.LogicalOperator)
 	if op != "AND" && op != "OR" && op != "" {
-		return fmt.Errorf("invalid logical operator: %s", filters.LogicalOperator)
+		return invalidError("invalid logical operator: %s", filters.LogicalOperator)
 	}
 
 	allowedOps := map[string]bool{
-		"=": true, "!=": true, "<>": true, ">": true, "<": true, ">=": true, "<=": true, "LIKE": true, "IN": true,
-		"IS": true, "IS NOT": true,
-	}
-
-	colsMap := make(map[string]bool)
-	for _, col := range allowedColumns {
-		colsMap[col] = true
+		"=": true, "!=": true, "<>": true, ">": true, "<": true, ">=": true, "<=": true,
+		"LIKE": true, "ILIKE": true, "IN": true, "NOT IN": true, "BETWEEN": true,
+		"IS": true, "IS NOT": true, "IS NULL": true, "IS NOT NULL": true,
 	}
 
 	for _, cond := range filters.Conditions {
-		if !colsMap[cond.Field] {
-			return fmt.Errorf("field '%s' is not allowed for filtering in this table", cond.Field)
+		if !access.readable[cond.Field] {
+			return invalidError("field '%s' is not allowed for filtering in this table", cond.Field)
 		}
 
-		if !allowedOps[strings.ToUpper(cond.Operator)] {
-			return fmt.Errorf("operator '%s' is not allowed", cond.Operator)
+		condOp := strings.ToUpper(cond.Operator)
+		if !allowedOps[condOp] {
+			return invalidError("operator '%s' is not allowed", cond.Operator)
 		}
 
-		if strings.ToUpper(cond.Operator) == "IN" {
+		// По маскированным столбцам фильтровать нельзя совсем: даже точное совпадение позволяет
+		// подобрать скрытое значение перебором.
+		if access.masked[cond.Field] {
+			return invalidError("field '%s' cannot be used for filtering", cond.Field)
+		}
+
+		switch condOp {
+		case "IN", "NOT IN":
 			if cond.Value == nil {
-				return fmt.Errorf("value for 'IN' operator on field '%s' cannot be null", cond.Field)
+				return invalidError("value for '%s' operator on field '%s' cannot be null", condOp, cond.Field)
 			}
 			val := reflect.ValueOf(cond.Value)
 			if val.Kind() != reflect.Slice {
-				return fmt.Errorf("value for 'IN' operator on field '%s' must be an array", cond.Field)
+				return invalidError("value for '%s' operator on field '%s' must be an array", condOp, cond.Field)
+			}
+			if val.Len() == 0 {
+				return invalidError("value for '%s' operator on field '%s' must not be empty", condOp, cond.Field)
+			}
+		case "BETWEEN":
+			val := reflect.ValueOf(cond.Value)
+			if cond.Value == nil || val.Kind() != reflect.Slice || val.Len() != 2 {
+				return invalidError("value for 'BETWEEN' operator on field '%s' must be an array of two elements", cond.Field)
+			}
+			if val.Index(0).Interface() == nil || val.Index(1).Interface() == nil {
+				return invalidError("bounds for 'BETWEEN' operator on field '%s' cannot be null", cond.Field)
 			}
 		}
 	}
+
+	for _, group := range filters.Groups {
+		if err := s.validateFilters(group, access, depth+1); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// normalizeInsertData приводит данные вставки (объект или массив объектов) к списку записей.
+func normalizeInsertData(data interface{}) ([]map[string]interface{}, error) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, nil
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for i, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, invalidError("элемент %d массива data должен быть объектом", i)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	default:
+		return nil, invalidError("unsupported data type for insert: %T", data)
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -5,28 +5,47 @@ import (
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/pubsub"
 	"ElectronicQueue/internal/repository"
+	"ElectronicQueue/internal/utils"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// icd10Code - код диагноза по МКБ-10: буква, две цифры и необязательное уточнение после точки (например, J06.9).
+var icd10Code = regexp.MustCompile(`^[A-Z][0-9]{2}(\.[0-9A-Z]{1,2})?$`)
+
+// referralValidDays - срок действия направления, выданного врачом при завершении приема.
+const referralValidDays = 30
+
 // DoctorService предоставляет методы для работы врача с талонами
 type DoctorService struct {
-	ticketRepo   repository.TicketRepository
-	doctorRepo   repository.DoctorRepository
-	scheduleRepo repository.ScheduleRepository
-	broker       *pubsub.Broker
+	ticketRepo      repository.TicketRepository
+	doctorRepo      repository.DoctorRepository
+	scheduleRepo    repository.ScheduleRepository
+	cabinetRepo     repository.CabinetRepository
+	appointmentRepo repository.AppointmentRepository
+	broker          *pubsub.Broker
+	// announceCabinets - приглашения в кабинет сопровождаются озвучкой (CABINET_ANNOUNCEMENTS).
+	announceCabinets bool
 }
 
 // NewDoctorService создает новый экземпляр DoctorService.
-func NewDoctorService(ticketRepo repository.TicketRepository, doctorRepo repository.DoctorRepository, scheduleRepo repository.ScheduleRepository, broker *pubsub.Broker) *DoctorService {
+func NewDoctorService(ticketRepo repository.TicketRepository, doctorRepo repository.DoctorRepository, scheduleRepo repository.ScheduleRepository, cabinetRepo repository.CabinetRepository, appointmentRepo repository.AppointmentRepository, broker *pubsub.Broker, announceCabinets bool) *DoctorService {
 	return &DoctorService{
-		ticketRepo:   ticketRepo,
-		doctorRepo:   doctorRepo,
-		scheduleRepo: scheduleRepo,
-		broker:       broker,
+		ticketRepo:       ticketRepo,
+		doctorRepo:       doctorRepo,
+		scheduleRepo:     scheduleRepo,
+		cabinetRepo:      cabinetRepo,
+		appointmentRepo:  appointmentRepo,
+		broker:           broker,
+		announceCabinets: announceCabinets,
 	}
 }
 
@@ -40,9 +59,9 @@ func (s *DoctorService) GetAllActiveDoctors() ([]models.Doctor, error) {
 	return doctors, nil
 }
 
-// GetRegisteredTickets возвращает талоны со статусом "зарегистрирован"
-func (s *DoctorService) GetRegisteredTickets() ([]models.TicketResponse, error) {
-	tickets, err := s.ticketRepo.FindByStatus(models.StatusRegistered)
+// Получить очередь к врачу (только его талоны)
+func (s *DoctorService) GetRegisteredTicketsForDoctor(doctorID uint) ([]models.TicketResponse, error) {
+	tickets, err := s.ticketRepo.FindByStatusAndDoctor(models.StatusRegistered, doctorID)
 	if err != nil {
 		return nil, err
 	}
@@ -55,9 +74,9 @@ func (s *DoctorService) GetRegisteredTickets() ([]models.TicketResponse, error)
 	return response, nil
 }
 
-// Получить очередь к врачу (только его талоны)
-func (s *DoctorService) GetRegisteredTicketsForDoctor(doctorID uint) ([]models.TicketResponse, error) {
-	tickets, err := s.ticketRepo.FindByStatusAndDoctor(models.StatusRegistered, doctorID)
+// Получить талоны на приеме у врача
+func (s *DoctorService) GetInProgressTicketsForDoctor(doctorID uint) ([]models.TicketResponse, error) {
+	tickets, err := s.ticketRepo.FindByStatusAndDoctor(models.StatusInProgress, doctorID)
 	if err != nil {
 		return nil, err
 	}
@@ -70,9 +89,9 @@ func (s *DoctorService) GetRegisteredTicketsForDoctor(doctorID uint) ([]models.T
 	return response, nil
 }
 
-// GetInProgressTickets возвращает талоны со статусом "на_приеме"
-func (s *DoctorService) GetInProgressTickets() ([]models.TicketResponse, error) {
-	tickets, err := s.ticketRepo.FindByStatus(models.StatusInProgress)
+// GetInvitedTicketsForDoctor возвращает талоны пациентов, которых врач пригласил в кабинет
+func (s *DoctorService) GetInvitedTicketsForDoctor(doctorID uint) ([]models.TicketResponse, error) {
+	tickets, err := s.ticketRepo.FindByStatusAndDoctor(models.StatusInvitedToCabinet, doctorID)
 	if err != nil {
 		return nil, err
 	}
@@ -85,30 +104,110 @@ func (s *DoctorService) GetInProgressTickets() ([]models.TicketResponse, error)
 	return response, nil
 }
 
-// Получить талоны на приеме у врача
-func (s *DoctorService) GetInProgressTicketsForDoctor(doctorID uint) ([]models.TicketResponse, error) {
-	tickets, err := s.ticketRepo.FindByStatusAndDoctor(models.StatusInProgress, doctorID)
+// InviteToCabinet приглашает пациента из очереди к врачу в кабинет: талон переходит в статус
+// 'приглашен_в_кабинет', а табло кабинета получает событие с озвучкой приглашения. Время приглашения
+// сохраняется в invited_at, время вызова к окну регистратуры (called_at) не меняется.
+func (s *DoctorService) InviteToCabinet(doctorID, ticketID uint) (*models.Ticket, error) {
+	return s.callToCabinet(doctorID, ticketID, false)
+}
+
+// RecallToCabinet повторно приглашает пациента, который уже приглашен в кабинет, но не подошел.
+func (s *DoctorService) RecallToCabinet(doctorID, ticketID uint) (*models.Ticket, error) {
+	return s.callToCabinet(doctorID, ticketID, true)
+}
+
+// callToCabinet выполняет приглашение или повторный вызов пациента в кабинет, указанный в слоте его записи.
+// Ссылка на озвучку добавляется в уведомление, только если озвучка приглашений включена в конфигурации.
+func (s *DoctorService) callToCabinet(doctorID, ticketID uint, recall bool) (*models.Ticket, error) {
+	log := logger.Default().WithField("service", "callToCabinet").WithField("doctor_id", doctorID).WithField("ticket_id", ticketID)
+
+	ticket, appointment, err := s.ticketForDoctor(doctorID, ticketID)
 	if err != nil {
 		return nil, err
 	}
+	if recall && ticket.Status != models.StatusInvitedToCabinet {
+		return nil, conflictError("для повторного вызова талон должен иметь статус 'приглашен_в_кабинет'")
+	}
+	if !recall && ticket.Status != models.StatusRegistered {
+		return nil, conflictError("для приглашения в кабинет талон должен иметь статус 'зарегистрирован'")
+	}
+	if appointment.Schedule.Cabinet == nil {
+		return nil, conflictError("в расписании не указан кабинет, пригласить пациента нельзя")
+	}
+	cabinet := *appointment.Schedule.Cabinet
+	if s.announceCabinets {
+		// Записи проверяются при запуске и при добавлении кабинета, поэтому их отсутствие здесь - сбой установки.
+		if err := utils.CheckCabinetAnnouncementFiles([]int{cabinet}, utils.AudioDir); err != nil {
+			return nil, fmt.Errorf("приглашение в кабинет %d нельзя озвучить: %w", cabinet, err)
+		}
+	}
 
-	var response []models.TicketResponse
-	for _, ticket := range tickets {
-		response = append(response, ticket.ToResponse())
+	now := time.Now()
+	ticket.Status = models.StatusInvitedToCabinet
+	ticket.InvitedAt = &now
+
+	if err := s.ticketRepo.Update(ticket); err != nil {
+		return nil, fmt.Errorf("не удалось обновить талон: %w", err)
 	}
 
-	return response, nil
+	notification := models.CabinetInviteNotification{
+		Event:      models.CabinetInviteEvent,
+		TicketID:   ticket.ID,
+		Ticket:     ticket.TicketNumber,
+		Cabinet:    cabinet,
+		DoctorID:   doctorID,
+		DoctorName: appointment.Schedule.Doctor.FullName,
+		Recall:     recall,
+		InvitedAt:  now,
+	}
+	if s.announceCabinets {
+		notification.AudioURL = "/api/audio/announce/cabinet?" + url.Values{
+			"ticket":  {ticket.TicketNumber},
+			"cabinet": {strconv.Itoa(cabinet)},
+		}.Encode()
+	}
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		// Статус талона уже обновлен, табло получит его вместе с очередью.
+		log.WithError(err).Error("Не удалось сформировать уведомление о приглашении в кабинет")
+		return ticket, nil
+	}
+	s.broker.Publish(string(payload))
+	log.WithField("cabinet", cabinet).WithField("recall", recall).Info("Пациент приглашен в кабинет")
+
+	return ticket, nil
 }
 
-// StartAppointment начинает прием пациента
-func (s *DoctorService) StartAppointment(ticketID uint) (*models.Ticket, error) {
+// ticketForDoctor загружает талон и запись, к которой он привязан, и проверяет, что пациент записан к врачу doctorID.
+// Талоны без записи врачу недоступны: их нельзя соотнести с его расписанием.
+func (s *DoctorService) ticketForDoctor(doctorID, ticketID uint) (*models.Ticket, *models.Appointment, error) {
 	ticket, err := s.ticketRepo.GetByID(ticketID)
 	if err != nil {
-		return nil, fmt.Errorf("талон не найден: %w", err)
+		return nil, nil, fmt.Errorf("талон не найден: %w", err)
+	}
+
+	appointment, err := s.appointmentRepo.FindByTicketID(ticketID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, notFoundError("талон %s не привязан к записи к врачу", ticket.TicketNumber)
+		}
+		return nil, nil, fmt.Errorf("ошибка получения записи по талону: %w", err)
+	}
+	if appointment.Schedule.DoctorID != doctorID {
+		return nil, nil, forbiddenError("пациент по талону %s записан к другому врачу", ticket.TicketNumber)
+	}
+	return ticket, appointment, nil
+}
+
+// StartAppointment начинает прием пациента, записанного к врачу doctorID
+func (s *DoctorService) StartAppointment(doctorID, ticketID uint) (*models.Ticket, error) {
+	ticket, _, err := s.ticketForDoctor(doctorID, ticketID)
+	if err != nil {
+		return nil, err
 	}
 
-	if ticket.Status != models.StatusRegistered {
-		return nil, fmt.Errorf("для начала приема талон должен иметь статус 'зарегистрирован'")
+	if ticket.Status != models.StatusRegistered && ticket.Status != models.StatusInvitedToCabinet {
+		return nil, conflictError("для начала приема талон должен иметь статус 'зарегистрирован' или 'приглашен_в_кабинет'")
 	}
 
 	now := time.Now()
@@ -122,15 +221,25 @@ func (s *DoctorService) StartAppointment(ticketID uint) (*models.Ticket, error)
 	return ticket, nil
 }
 
-// CompleteAppointment завершает прием пациента
-func (s *DoctorService) CompleteAppointment(ticketID uint) (*models.Ticket, error) {
-	ticket, err := s.ticketRepo.GetByID(ticketID)
+// CompleteAppointment завершает прием пациента. Если врач указал итог приема (диагноз, заметку,
+// необходимость повторного визита, направление), он сохраняется в записи, к которой привязан талон;
+// направление к другому специалисту оформляется как направление пациента.
+// Завершить можно только прием пациента, записанного к врачу doctorID.
+func (s *DoctorService) CompleteAppointment(doctorID, ticketID uint, outcome *models.UpdateAppointmentRequest, actor string) (*models.Ticket, *models.Appointment, error) {
+	ticket, appointment, err := s.ticketForDoctor(doctorID, ticketID)
 	if err != nil {
-		return nil, fmt.Errorf("талон не найден: %w", err)
+		return nil, nil, err
 	}
 
 	if ticket.Status != models.StatusInProgress {
-		return nil, fmt.Errorf("для завершения приема талон должен иметь статус 'на_приеме'")
+		return nil, nil, conflictError("для завершения приема талон должен иметь статус 'на_приеме'")
+	}
+
+	hasOutcome := outcome != nil && !outcome.IsEmpty()
+	if hasOutcome {
+		if err := applyVisitOutcome(appointment, outcome); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	now := time.Now()
@@ -138,10 +247,51 @@ func (s *DoctorService) CompleteAppointment(ticketID uint) (*models.Ticket, erro
 	ticket.CompletedAt = &now
 
 	if err := s.ticketRepo.Update(ticket); err != nil {
-		return nil, fmt.Errorf("не удалось обновить талон: %w", err)
+		return nil, nil, fmt.Errorf("не удалось обновить талон: %w", err)
 	}
 
-	return ticket, nil
+	if hasOutcome {
+		appointment.OutcomeRecordedAt = &now
+		appointment.OutcomeRecordedBy = &actor
+		var referral *models.Referral
+		if appointment.ReferralSpecialization != nil {
+			issuedAt := truncateToDate(now)
+			referral = &models.Referral{
+				PatientID:      appointment.PatientID,
+				Specialization: *appointment.ReferralSpecialization,
+				IssuedBy:       strings.TrimSpace(appointment.Schedule.Doctor.Specialization + " " + appointment.Schedule.Doctor.FullName),
+				IssuedAt:       issuedAt,
+				ValidUntil:     issuedAt.AddDate(0, 0, referralValidDays),
+			}
+		}
+		if err := s.appointmentRepo.SaveOutcome(appointment, referral); err != nil {
+			return ticket, nil, fmt.Errorf("прием завершен, но не удалось сохранить его итог: %w", err)
+		}
+	}
+
+	return ticket, appointment, nil
+}
+
+// applyVisitOutcome проверяет итог приема и переносит его в запись.
+func applyVisitOutcome(appointment *models.Appointment, outcome *models.UpdateAppointmentRequest) error {
+	code := strings.ToUpper(strings.TrimSpace(outcome.DiagnosisCode))
+	if code == "" {
+		return invalidError("необходимо указать код диагноза по МКБ-10")
+	}
+	if !icd10Code.MatchString(code) {
+		return invalidError("неверный код диагноза '%s', ожидается код МКБ-10, например J06.9", outcome.DiagnosisCode)
+	}
+	appointment.DiagnosisCode = &code
+	appointment.VisitNote = nil
+	if note := strings.TrimSpace(outcome.Note); note != "" {
+		appointment.VisitNote = &note
+	}
+	appointment.FollowUpNeeded = outcome.FollowUpNeeded
+	appointment.ReferralSpecialization = nil
+	if specialization := strings.TrimSpace(outcome.ReferralSpecialization); specialization != "" {
+		appointment.ReferralSpecialization = &specialization
+	}
+	return nil
 }
 
 // GetDoctorScreenState находит расписание врача и полную очередь к его кабинету.
@@ -169,13 +319,30 @@ func (s *DoctorService) GetDoctorScreenState(cabinetNumber int) (*models.Schedul
 	return nil, []models.DoctorQueueTicketResponse{}, nil
 }
 
-// GetAllUniqueCabinets возвращает список всех уникальных кабинетов.
+// GetAllUniqueCabinets возвращает номера всех действующих кабинетов из справочника,
+// включая кабинеты, в которых сегодня нет приема.
 func (s *DoctorService) GetAllUniqueCabinets() ([]int, error) {
-	cabinets, err := s.scheduleRepo.GetAllUniqueCabinets()
+	cabinets, err := s.cabinetRepo.GetAll(true)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения списка всех кабинетов: %w", err)
 	}
-	return cabinets, nil
+	numbers := make([]int, 0, len(cabinets))
+	for _, cabinet := range cabinets {
+		numbers = append(numbers, cabinet.Number)
+	}
+	return numbers, nil
+}
+
+// GetCabinetInfo возвращает сведения о кабинете для табло. Если кабинета нет в справочнике, возвращает nil без ошибки.
+func (s *DoctorService) GetCabinetInfo(cabinetNumber int) (*models.Cabinet, error) {
+	cabinet, err := s.cabinetRepo.GetByNumber(cabinetNumber)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения сведений о кабинете: %w", err)
+	}
+	return cabinet, nil
 }
 
 // StartBreak начинает перерыв врача
@@ -190,7 +357,7 @@ func (s *DoctorService) StartBreak(doctorID uint) error {
 
 	if doctor.Status != models.DoctorStatusActive {
 		log.WithField("current_status", doctor.Status).Error("Врач должен быть активен для начала перерыва")
-		return fmt.Errorf("врач должен быть активен для начала перерыва")
+		return conflictError("врач должен быть активен для начала перерыва")
 	}
 
 	if err := s.doctorRepo.UpdateStatus(doctorID, models.DoctorStatusOnBreak); err != nil {
@@ -215,7 +382,7 @@ func (s *DoctorService) EndBreak(doctorID uint) error {
 
 	if doctor.Status != models.DoctorStatusOnBreak {
 		log.WithField("current_status", doctor.Status).Error("Врач должен быть на перерыве для его завершения")
-		return fmt.Errorf("врач должен быть на перерыве для его завершения")
+		return conflictError("врач должен быть на перерыве для его завершения")
 	}
 
 	if err := s.doctorRepo.UpdateStatus(doctorID, models.DoctorStatusActive); err != nil {
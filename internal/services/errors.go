@@ -0,0 +1,28 @@
+package services
+
+import "ElectronicQueue/internal/repository"
+
+// Виды ошибок сервисов, по которым обработчики выбирают HTTP-статус (см. repository.ErrNotFound).
+// Ошибки без вида считаются внутренними.
+var (
+	ErrNotFound     = repository.ErrNotFound
+	ErrConflict     = repository.ErrConflict
+	ErrInvalidInput = repository.ErrInvalidInput
+	ErrForbidden    = repository.ErrForbidden
+)
+
+func notFoundError(format string, args ...any) error {
+	return repository.NewError(ErrNotFound, format, args...)
+}
+
+func conflictError(format string, args ...any) error {
+	return repository.NewError(ErrConflict, format, args...)
+}
+
+func invalidError(format string, args ...any) error {
+	return repository.NewError(ErrInvalidInput, format, args...)
+}
+
+func forbiddenError(format string, args ...any) error {
+	return repository.NewError(ErrForbidden, format, args...)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FollowUpService позволяет врачу с приема записать пациента на повторный визит
+// или к врачу другой специальности, не отправляя его обратно в регистратуру.
+type FollowUpService struct {
+	appointmentRepo repository.AppointmentRepository
+	ticketRepo      repository.TicketRepository
+	scheduleRepo    repository.ScheduleRepository
+	doctorRepo      repository.DoctorRepository
+	booking         *BookingService
+	appointments    *AppointmentService
+}
+
+// NewFollowUpService создает новый экземпляр FollowUpService.
+// Поиск слотов выполняется так же, как при самостоятельной записи, а запись - так же, как в регистратуре.
+func NewFollowUpService(appointmentRepo repository.AppointmentRepository, ticketRepo repository.TicketRepository, scheduleRepo repository.ScheduleRepository, doctorRepo repository.DoctorRepository, booking *BookingService, appointments *AppointmentService) *FollowUpService {
+	return &FollowUpService{
+		appointmentRepo: appointmentRepo,
+		ticketRepo:      ticketRepo,
+		scheduleRepo:    scheduleRepo,
+		doctorRepo:      doctorRepo,
+		booking:         booking,
+		appointments:    appointments,
+	}
+}
+
+// SearchSlots ищет свободные слоты для записи с приема. Без специальности возвращаются слоты самого врача.
+func (s *FollowUpService) SearchSlots(doctorID uint, specialization string, from time.Time, to *time.Time) ([]models.BookingSlot, error) {
+	specialization = strings.TrimSpace(specialization)
+	if specialization != "" {
+		return s.booking.SearchSlots(specialization, from, to)
+	}
+
+	doctor, err := s.doctorRepo.GetByID(doctorID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("врач с ID %d не найден", doctorID)
+		}
+		return nil, fmt.Errorf("ошибка получения врача: %w", err)
+	}
+	slots, err := s.booking.SearchSlots(doctor.Specialization, from, to)
+	if err != nil {
+		return nil, err
+	}
+	own := make([]models.BookingSlot, 0, len(slots))
+	for _, slot := range slots {
+		if slot.DoctorID == doctorID {
+			own = append(own, slot)
+		}
+	}
+	return own, nil
+}
+
+// Book записывает пациента текущего приема на выбранный слот. Пациент определяется по талону,
+// который сейчас на приеме у врача или прием по которому врач завершил сегодня. Запись к врачу той же
+// специальности считается повторным визитом, к другой - записью по направлению; правила записи проверяются
+// так же, как в регистратуре, и врач может записать вопреки им, указав причину.
+func (s *FollowUpService) Book(doctorID uint, req *models.DoctorBookingRequest, actor string) (*models.Appointment, error) {
+	ticket, err := s.ticketRepo.GetByID(req.TicketID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("талон с ID %d не найден", req.TicketID)
+		}
+		return nil, fmt.Errorf("ошибка получения талона: %w", err)
+	}
+	completedToday := ticket.Status == models.StatusCompleted && ticket.CompletedAt != nil &&
+		truncateToDate(*ticket.CompletedAt).Equal(truncateToDate(time.Now()))
+	if ticket.Status != models.StatusInProgress && !completedToday {
+		return nil, conflictError("записать можно только пациента, который на приеме или был на приеме сегодня")
+	}
+
+	source, err := s.appointmentRepo.FindByTicketID(req.TicketID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("талон не привязан к записи, пациент не найден")
+		}
+		return nil, fmt.Errorf("ошибка получения записи по талону: %w", err)
+	}
+	if source.Schedule.DoctorID != doctorID {
+		return nil, forbiddenError("пациент по талону %s записан к другому врачу", ticket.TicketNumber)
+	}
+
+	kind := models.BookingKindFollowUp
+	slot, err := s.scheduleRepo.GetByID(req.ScheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("указанный слот в расписании не найден")
+		}
+		return nil, fmt.Errorf("ошибка при поиске слота расписания: %w", err)
+	}
+	if slotStarted(slot, time.Now()) {
+		return nil, conflictError("запись на выбранное время невозможна: прием уже начался")
+	}
+	if slot.DoctorID != doctorID {
+		doctor, err := s.doctorRepo.GetByID(slot.DoctorID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения врача слота: %w", err)
+		}
+		if !strings.EqualFold(doctor.Specialization, source.Schedule.Doctor.Specialization) {
+			kind = models.BookingKindReferral
+		}
+	}
+
+	return s.appointments.CreateAppointment(&models.CreateAppointmentRequest{
+		ScheduleID:          req.ScheduleID,
+		PatientID:           source.PatientID,
+		OverrideReason:      req.OverrideReason,
+		BookingKind:         kind,
+		SourceAppointmentID: &source.ID,
+	}, actor)
+}
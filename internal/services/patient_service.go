@@ -1,11 +1,39 @@
 package services
 
 import (
+	"ElectronicQueue/internal/logger"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/repository"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultPatientSearchLimit - размер страницы поиска пациентов по умолчанию.
+	defaultPatientSearchLimit = 20
+	// maxPatientSearchLimit ограничивает размер страницы поиска пациентов.
+	maxPatientSearchLimit = 100
+)
+
+var (
+	nonDigits = regexp.MustCompile(`[^0-9]+`)
+	// digitsQuery - номер документа или телефона, возможно с пробелами, скобками и дефисами.
+	digitsQuery = regexp.MustCompile(`^[0-9+()\s-]+$`)
+	// initialsQuery - "фамилия и.о.", "фамилия и. о." или "фамилия и." (запрос уже в нижнем регистре).
+	initialsQuery = regexp.MustCompile(`^(\p{L}[\p{L}-]+)\s+(\p{L})\.\s*(?:(\p{L})\.?)?$`)
 )
 
+// reencryptionBatchSize - число карточек пациентов, перешифровываемых за один проход.
+const reencryptionBatchSize = 200
+
+// maxNameDistance - наибольшее число опечаток в ФИО, при котором карточки с одной датой рождения считаются похожими.
+const maxNameDistance = 2
+
 type PatientService struct {
 	repo repository.PatientRepository
 }
@@ -30,14 +58,260 @@ func (s *PatientService) CreatePatient(req *models.CreatePatientRequest) (*model
 	return createdPatient, nil
 }
 
-// SearchPatients вызывает универсальный поиск.
-func (s *PatientService) SearchPatients(query string) ([]models.Patient, error) {
-	if len(query) < 2 {
-		return []models.Patient{}, nil
+// SearchPatients выполняет ранжированный поиск пациентов. Запрос из цифр ищется по полису ОМС,
+// паспорту и телефону, запрос вида "Фамилия И.О." - по фамилии и инициалам, остальные - по ФИО с учетом опечаток.
+func (s *PatientService) SearchPatients(query string, birthDate *time.Time, phone string, page, limit int) (*models.PatientSearchResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPatientSearchLimit
 	}
-	patients, err := s.repo.Search(query)
+	if limit > maxPatientSearchLimit {
+		limit = maxPatientSearchLimit
+	}
+
+	q := parsePatientSearchQuery(query)
+	q.BirthDate = birthDate
+	q.Phone = nonDigits.ReplaceAllString(phone, "")
+	q.Page, q.Limit = page, limit
+
+	result := &models.PatientSearchResult{Page: page, Limit: limit, Data: []models.PatientSearchHit{}}
+	if q.Text == "" && q.Surname == "" && q.Digits == "" && q.BirthDate == nil && q.Phone == "" {
+		return result, nil
+	}
+
+	hits, total, err := s.repo.Search(q)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка поиска пациентов в репозитории: %w", err)
 	}
-	return patients, nil
+	result.Total = total
+	if hits != nil {
+		result.Data = hits
+	}
+	return result, nil
+}
+
+// parsePatientSearchQuery определяет вид поискового запроса. Запросы короче двух символов не используются.
+func parsePatientSearchQuery(query string) models.PatientSearchQuery {
+	var q models.PatientSearchQuery
+	text := normalizePersonName(query)
+	if len([]rune(text)) < 2 {
+		return q
+	}
+	if digitsQuery.MatchString(text) {
+		if digits := nonDigits.ReplaceAllString(text, ""); len(digits) >= 2 {
+			q.Digits = digits
+		}
+		return q
+	}
+	if m := initialsQuery.FindStringSubmatch(text); m != nil {
+		q.Surname, q.FirstInitial, q.MiddleInitial = m[1], m[2], m[3]
+		return q
+	}
+	q.Text = text
+	return q
+}
+
+// GetPatient возвращает действующую карточку пациента.
+func (s *PatientService) GetPatient(id uint) (*models.Patient, error) {
+	patient, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("пациент с ID %d не найден", id)
+		}
+		return nil, fmt.Errorf("ошибка получения пациента: %w", err)
+	}
+	return patient, nil
+}
+
+// UpdatePatient изменяет переданные поля карточки пациента. Пустые поля не изменяются.
+func (s *PatientService) UpdatePatient(id uint, req *models.UpdatePatientRequest, actor string) (*models.Patient, error) {
+	normalized := &models.UpdatePatientRequest{
+		PassportSeries: strings.TrimSpace(req.PassportSeries),
+		PassportNumber: strings.TrimSpace(req.PassportNumber),
+		FullName:       strings.Join(strings.Fields(req.FullName), " "),
+		BirthDate:      req.BirthDate,
+		Phone:          strings.TrimSpace(req.Phone),
+		OmsNumber:      strings.TrimSpace(req.OmsNumber),
+	}
+
+	patient, err := s.repo.Update(id, normalized)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("пациент с ID %d не найден", id)
+		}
+		if repository.IsUniqueViolation(err) {
+			return nil, conflictError("пациент с таким паспортом уже существует")
+		}
+		return nil, fmt.Errorf("не удалось обновить пациента: %w", err)
+	}
+	logger.Default().WithField("module", "patients").WithField("patient_id", id).WithField("actor", actor).
+		Info("Карточка пациента изменена")
+	return patient, nil
+}
+
+// FindDuplicates ищет карточки, которые могут быть дубликатами указанной: с тем же полисом ОМС
+// или с той же датой рождения и ФИО, отличающимся не более чем на maxNameDistance символов.
+func (s *PatientService) FindDuplicates(id uint) ([]models.PatientDuplicate, error) {
+	patient, err := s.GetPatient(id)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.repo.FindDuplicateCandidates(patient)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска дубликатов: %w", err)
+	}
+
+	name := normalizePersonName(patient.FullName)
+	result := make([]models.PatientDuplicate, 0, len(candidates))
+	for _, candidate := range candidates {
+		var reasons []string
+		if candidate.OmsNumber == patient.OmsNumber {
+			reasons = append(reasons, "совпадает полис ОМС")
+		}
+		if candidate.BirthDate.Format("2006-01-02") == patient.BirthDate.Format("2006-01-02") &&
+			levenshtein(name, normalizePersonName(candidate.FullName)) <= maxNameDistance {
+			reasons = append(reasons, "похожее ФИО и совпадает дата рождения")
+		}
+		if len(reasons) > 0 {
+			result = append(result, models.PatientDuplicate{Patient: candidate, Reasons: reasons})
+		}
+	}
+	return result, nil
+}
+
+// MergePatients объединяет дубликат с основной карточкой пациента.
+func (s *PatientService) MergePatients(targetID uint, req *models.MergePatientsRequest, actor string) (*models.PatientMerge, error) {
+	if req.SourcePatientID == targetID {
+		return nil, invalidError("нельзя объединить карточку пациента саму с собой")
+	}
+	merge, err := s.repo.Merge(req.SourcePatientID, targetID, actor, strings.TrimSpace(req.Reason))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("не удалось объединить карточки пациентов: %w", err)
+	}
+	logger.Default().WithField("module", "patients").WithField("source_patient_id", merge.SourcePatientID).
+		WithField("target_patient_id", merge.TargetPatientID).WithField("actor", actor).
+		WithField("moved_appointments", merge.MovedAppointments).Info("Карточки пациентов объединены")
+	return merge, nil
+}
+
+// GetMerges возвращает журнал объединений карточки пациента.
+func (s *PatientService) GetMerges(id uint) ([]models.PatientMerge, error) {
+	merges, err := s.repo.FindMerges(id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала объединений: %w", err)
+	}
+	return merges, nil
+}
+
+// DeletePatient помечает карточку пациента удаленной. История записей сохраняется.
+func (s *PatientService) DeletePatient(id uint, reason, actor string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return invalidError("необходимо указать причину удаления")
+	}
+	if err := s.repo.SoftDelete(id, actor, reason); err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) {
+			return err
+		}
+		return fmt.Errorf("не удалось удалить пациента: %w", err)
+	}
+	logger.Default().WithField("module", "patients").WithField("patient_id", id).WithField("actor", actor).
+		WithField("reason", reason).Info("Карточка пациента удалена")
+	return nil
+}
+
+// RevealIdentifiers возвращает полные паспортные данные и полис ОМС пациента и записывает
+// раскрытие в журнал доступа. Без указания причины данные не раскрываются.
+func (s *PatientService) RevealIdentifiers(id uint, actor, reason, clientIP string) (*models.PatientIdentifiers, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, invalidError("необходимо указать причину просмотра документов")
+	}
+	patient, err := s.GetPatient(id)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.PIIAccessLog{
+		PatientID: id,
+		Actor:     actor,
+		Fields:    models.StringList{"passport_series", "passport_number", "oms_number"},
+		Reason:    reason,
+		ClientIP:  clientIP,
+	}
+	if err := s.repo.LogIdentifierAccess(entry); err != nil {
+		return nil, fmt.Errorf("не удалось записать журнал доступа к документам: %w", err)
+	}
+	logger.Default().WithField("module", "patients").WithField("patient_id", id).WithField("actor", actor).
+		WithField("reason", reason).Info("Раскрыты документы пациента")
+
+	identifiers := patient.Identifiers()
+	return &identifiers, nil
+}
+
+// GetIdentifierAccess возвращает журнал раскрытия документов пациента.
+func (s *PatientService) GetIdentifierAccess(id uint) ([]models.PIIAccessLog, error) {
+	entries, err := s.repo.FindIdentifierAccess(id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала доступа к документам: %w", err)
+	}
+	return entries, nil
+}
+
+// ReencryptIdentifiers перешифровывает активным ключом все карточки, зашифрованные другим ключом
+// или еще хранящие документы в открытом виде. После завершения старые ключи можно удалить из конфигурации.
+func (s *PatientService) ReencryptIdentifiers() (*models.PIIReencryptionResult, error) {
+	keyID, err := models.PIIActiveKeyID()
+	if err != nil {
+		return nil, err
+	}
+	result := &models.PIIReencryptionResult{ActiveKeyID: keyID}
+	for {
+		processed, err := s.repo.ReencryptPatients(reencryptionBatchSize)
+		result.Processed += processed
+		if err != nil {
+			return result, fmt.Errorf("ошибка перешифрования карточек пациентов: %w", err)
+		}
+		if processed < reencryptionBatchSize {
+			break
+		}
+	}
+	if result.Processed > 0 {
+		logger.Default().WithField("module", "patients").WithField("key_id", keyID).
+			WithField("processed", result.Processed).Info("Карточки пациентов перешифрованы")
+	}
+	return result, nil
+}
+
+// normalizePersonName приводит ФИО к виду для сравнения: нижний регистр, "ё" заменена на "е", одиночные пробелы.
+func normalizePersonName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// levenshtein вычисляет расстояние редактирования между строками по символам Unicode.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
 }
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"testing"
+	"time"
+)
+
+// searchRecorder - репозиторий пациентов, запоминающий последний поисковый запрос.
+type searchRecorder struct {
+	repository.PatientRepository
+	calls int
+	query models.PatientSearchQuery
+}
+
+func (r *searchRecorder) Search(query models.PatientSearchQuery) ([]models.PatientSearchHit, int64, error) {
+	r.calls++
+	r.query = query
+	return nil, 0, nil
+}
+
+func TestParsePatientSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  models.PatientSearchQuery
+	}{
+		{name: "пустой запрос", query: "", want: models.PatientSearchQuery{}},
+		{name: "один символ", query: " и ", want: models.PatientSearchQuery{}},
+		{name: "фамилия и инициалы", query: "Иванов И.И.", want: models.PatientSearchQuery{Surname: "иванов", FirstInitial: "и", MiddleInitial: "и"}},
+		{name: "инициалы через пробел", query: "Петрова  А. С.", want: models.PatientSearchQuery{Surname: "петрова", FirstInitial: "а", MiddleInitial: "с"}},
+		{name: "только первый инициал", query: "Сидоров П.", want: models.PatientSearchQuery{Surname: "сидоров", FirstInitial: "п"}},
+		{name: "двойная фамилия", query: "Римский-Корсаков Н.А.", want: models.PatientSearchQuery{Surname: "римский-корсаков", FirstInitial: "н", MiddleInitial: "а"}},
+		{name: "ё в фамилии", query: "Алёшин А.А.", want: models.PatientSearchQuery{Surname: "алешин", FirstInitial: "а", MiddleInitial: "а"}},
+		{name: "номер полиса", query: "1234 5678 9012 3456", want: models.PatientSearchQuery{Digits: "1234567890123456"}},
+		{name: "телефон с форматированием", query: "+7 (912) 345-67-89", want: models.PatientSearchQuery{Digits: "79123456789"}},
+		{name: "знаки без цифр", query: "--", want: models.PatientSearchQuery{}},
+		{name: "полное ФИО", query: "  Иванов   Иван Иванович ", want: models.PatientSearchQuery{Text: "иванов иван иванович"}},
+		{name: "часть фамилии", query: "Ив", want: models.PatientSearchQuery{Text: "ив"}},
+		{name: "буквы с цифрами", query: "Иванов 1980", want: models.PatientSearchQuery{Text: "иванов 1980"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePatientSearchQuery(tt.query); got != tt.want {
+				t.Errorf("parsePatientSearchQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPatientsFilters(t *testing.T) {
+	birthDate := time.Date(1980, 5, 17, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		query     string
+		birthDate *time.Time
+		phone     string
+		wantCall  bool
+		wantPhone string
+	}{
+		{name: "без условий поиск не выполняется", wantCall: false},
+		{name: "короткий запрос без фильтров", query: "и", wantCall: false},
+		{name: "только дата рождения", birthDate: &birthDate, wantCall: true},
+		{name: "только телефон", phone: "+7 (912) 345-67-89", wantCall: true, wantPhone: "79123456789"},
+		{name: "телефон без цифр не фильтрует", phone: "()-", wantCall: false},
+		{name: "запрос с фильтрами", query: "Иванов", birthDate: &birthDate, phone: "8-912", wantCall: true, wantPhone: "8912"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &searchRecorder{}
+			result, err := NewPatientService(repo).SearchPatients(tt.query, tt.birthDate, tt.phone, 1, 20)
+			if err != nil {
+				t.Fatalf("SearchPatients() error = %v", err)
+			}
+			if got := repo.calls > 0; got != tt.wantCall {
+				t.Fatalf("repository called = %v, want %v", got, tt.wantCall)
+			}
+			if result.Data == nil {
+				t.Error("SearchPatients() Data = nil, want empty slice")
+			}
+			if !tt.wantCall {
+				return
+			}
+			if repo.query.BirthDate != tt.birthDate {
+				t.Errorf("BirthDate = %v, want %v", repo.query.BirthDate, tt.birthDate)
+			}
+			if repo.query.Phone != tt.wantPhone {
+				t.Errorf("Phone = %q, want %q", repo.query.Phone, tt.wantPhone)
+			}
+		})
+	}
+}
+
+func TestSearchPatientsPagination(t *testing.T) {
+	tests := []struct {
+		name                string
+		page, limit         int
+		wantPage, wantLimit int
+	}{
+		{name: "значения по умолчанию", page: 0, limit: 0, wantPage: 1, wantLimit: defaultPatientSearchLimit},
+		{name: "отрицательные значения", page: -3, limit: -1, wantPage: 1, wantLimit: defaultPatientSearchLimit},
+		{name: "допустимые значения", page: 4, limit: 50, wantPage: 4, wantLimit: 50},
+		{name: "граница лимита", page: 1, limit: maxPatientSearchLimit, wantPage: 1, wantLimit: maxPatientSearchLimit},
+		{name: "лимит больше максимума", page: 2, limit: 1000, wantPage: 2, wantLimit: maxPatientSearchLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &searchRecorder{}
+			result, err := NewPatientService(repo).SearchPatients("Иванов", nil, "", tt.page, tt.limit)
+			if err != nil {
+				t.Fatalf("SearchPatients() error = %v", err)
+			}
+			if result.Page != tt.wantPage || result.Limit != tt.wantLimit {
+				t.Errorf("result page/limit = %d/%d, want %d/%d", result.Page, result.Limit, tt.wantPage, tt.wantLimit)
+			}
+			if repo.query.Page != tt.wantPage || repo.query.Limit != tt.wantLimit {
+				t.Errorf("query page/limit = %d/%d, want %d/%d", repo.query.Page, repo.query.Limit, tt.wantPage, tt.wantLimit)
+			}
+		})
+	}
+}
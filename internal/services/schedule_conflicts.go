@@ -0,0 +1,160 @@
+package services
+
+import (
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"fmt"
+)
+
+// ScheduleConflictError возвращается, когда создаваемые или изменяемые слоты конфликтуют с расписанием.
+type ScheduleConflictError struct {
+	Conflicts []models.ScheduleConflict
+}
+
+func (e *ScheduleConflictError) Error() string {
+	return fmt.Sprintf("обнаружены конфликты расписания (%d)", len(e.Conflicts))
+}
+
+// scheduleConflictReport - результат проверки набора слотов.
+// Duplicates содержит индексы слотов, полностью совпадающих с уже существующими (врач, дата, начало, окончание):
+// такие слоты не считаются конфликтом, их достаточно не создавать повторно.
+type scheduleConflictReport struct {
+	Conflicts   []models.ScheduleConflict
+	Conflicting map[int]bool
+	Duplicates  map[int]bool
+}
+
+// detectScheduleConflicts проверяет слоты-кандидаты на корректность интервала, пересечение
+// со слотами того же врача и занятость кабинета другим врачом, как среди существующих слотов,
+// так и между самими кандидатами. Слоты с ID из ignoreIDs (например, изменяемые) не учитываются.
+// При allowDuplicates слоты, совпадающие с существующими, попадают в Duplicates, иначе считаются конфликтом.
+func detectScheduleConflicts(repo repository.ScheduleRepository, candidates []models.Schedule, ignoreIDs map[uint]bool, allowDuplicates bool) (*scheduleConflictReport, error) {
+	report := &scheduleConflictReport{Conflicting: map[int]bool{}, Duplicates: map[int]bool{}}
+	if len(candidates) == 0 {
+		return report, nil
+	}
+
+	dateSet := map[string]bool{}
+	doctorSet := map[uint]bool{}
+	cabinetSet := map[int]bool{}
+	for _, c := range candidates {
+		dateSet[c.Date.Format("2006-01-02")] = true
+		doctorSet[c.DoctorID] = true
+		if c.Cabinet != nil {
+			cabinetSet[*c.Cabinet] = true
+		}
+	}
+	dates := make([]string, 0, len(dateSet))
+	for d := range dateSet {
+		dates = append(dates, d)
+	}
+	doctorIDs := make([]uint, 0, len(doctorSet))
+	for id := range doctorSet {
+		doctorIDs = append(doctorIDs, id)
+	}
+	cabinets := make([]int, 0, len(cabinetSet))
+	for cab := range cabinetSet {
+		cabinets = append(cabinets, cab)
+	}
+
+	existing, err := repo.FindForConflictCheck(dates, doctorIDs, cabinets)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки конфликтов расписания: %w", err)
+	}
+
+	// Существующие слоты группируются по дате, чтобы не сравнивать все со всеми.
+	existingByDate := map[string][]models.Schedule{}
+	for _, e := range existing {
+		if ignoreIDs[e.ID] {
+			continue
+		}
+		key := e.Date.Format("2006-01-02")
+		existingByDate[key] = append(existingByDate[key], e)
+	}
+	acceptedByDate := map[string][]models.Schedule{}
+
+	for i := range candidates {
+		c := candidates[i]
+		date := c.Date.Format("2006-01-02")
+
+		if c.EndTime <= c.StartTime {
+			report.add(i, models.ScheduleConflict{
+				Type:    models.ConflictInvalidRange,
+				Message: fmt.Sprintf("время окончания %s не позже времени начала %s", c.EndTime, c.StartTime),
+				Slot:    c.ToResponse(),
+			})
+			continue
+		}
+
+		duplicate := false
+		if allowDuplicates {
+			for _, e := range existingByDate[date] {
+				if e.DoctorID == c.DoctorID && e.StartTime == c.StartTime && e.EndTime == c.EndTime {
+					duplicate = true
+					break
+				}
+			}
+		}
+		if duplicate {
+			report.Duplicates[i] = true
+			continue
+		}
+
+		conflicted := false
+		others := make([]models.Schedule, 0, len(existingByDate[date])+len(acceptedByDate[date]))
+		others = append(others, existingByDate[date]...)
+		others = append(others, acceptedByDate[date]...)
+		for j := range others {
+			o := others[j]
+			if !(c.StartTime < o.EndTime && o.StartTime < c.EndTime) {
+				continue
+			}
+			oResp := o.ToResponse()
+			switch {
+			case o.DoctorID == c.DoctorID:
+				report.add(i, models.ScheduleConflict{
+					Type:            models.ConflictDoctorOverlap,
+					Message:         fmt.Sprintf("слот %s %s-%s пересекается с другим слотом врача %s-%s", date, c.StartTime, c.EndTime, o.StartTime, o.EndTime),
+					Slot:            c.ToResponse(),
+					ConflictingSlot: &oResp,
+				})
+				conflicted = true
+			case c.Cabinet != nil && o.Cabinet != nil && *c.Cabinet == *o.Cabinet:
+				report.add(i, models.ScheduleConflict{
+					Type:            models.ConflictCabinetBusy,
+					Message:         fmt.Sprintf("кабинет %d занят врачом %d %s %s-%s", *c.Cabinet, o.DoctorID, date, o.StartTime, o.EndTime),
+					Slot:            c.ToResponse(),
+					ConflictingSlot: &oResp,
+				})
+				conflicted = true
+			}
+		}
+		if !conflicted {
+			acceptedByDate[date] = append(acceptedByDate[date], c)
+		}
+	}
+	return report, nil
+}
+
+func (r *scheduleConflictReport) add(index int, conflict models.ScheduleConflict) {
+	r.Conflicting[index] = true
+	r.Conflicts = append(r.Conflicts, conflict)
+}
+
+// filterSlots возвращает слоты, индексы которых не входят ни в одно из множеств exclude.
+func filterSlots(slots []models.Schedule, exclude ...map[int]bool) []models.Schedule {
+	result := make([]models.Schedule, 0, len(slots))
+	for i, s := range slots {
+		skip := false
+		for _, set := range exclude {
+			if set[i] {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -14,13 +14,17 @@ import (
 type ScheduleService struct {
 	scheduleRepo repository.ScheduleRepository
 	doctorRepo   repository.DoctorRepository
+	calendarRepo repository.CalendarRepository
+	cabinetRepo  repository.CabinetRepository
 }
 
 // NewScheduleService создает новый экземпляр ScheduleService.
-func NewScheduleService(scheduleRepo repository.ScheduleRepository, doctorRepo repository.DoctorRepository) *ScheduleService {
+func NewScheduleService(scheduleRepo repository.ScheduleRepository, doctorRepo repository.DoctorRepository, calendarRepo repository.CalendarRepository, cabinetRepo repository.CabinetRepository) *ScheduleService {
 	return &ScheduleService{
 		scheduleRepo: scheduleRepo,
 		doctorRepo:   doctorRepo,
+		calendarRepo: calendarRepo,
+		cabinetRepo:  cabinetRepo,
 	}
 }
 
@@ -29,10 +33,13 @@ func (s *ScheduleService) CreateSchedule(req *models.CreateScheduleRequest) (*mo
 	_, err := s.doctorRepo.GetByID(req.DoctorID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("врач с ID %d не найден", req.DoctorID)
+			return nil, notFoundError("врач с ID %d не найден", req.DoctorID)
 		}
 		return nil, fmt.Errorf("ошибка проверки врача: %w", err)
 	}
+	if err := checkCabinetUsable(s.cabinetRepo, req.Cabinet); err != nil {
+		return nil, err
+	}
 
 	isAvailable := true
 	if req.IsAvailable != nil {
@@ -48,6 +55,14 @@ func (s *ScheduleService) CreateSchedule(req *models.CreateScheduleRequest) (*mo
 		Cabinet:     req.Cabinet,
 	}
 
+	report, err := detectScheduleConflicts(s.scheduleRepo, []models.Schedule{*schedule}, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Conflicts) > 0 {
+		return nil, &ScheduleConflictError{Conflicts: report.Conflicts}
+	}
+
 	if err := s.scheduleRepo.Create(schedule); err != nil {
 		return nil, fmt.Errorf("не удалось создать слот в расписании: %w", err)
 	}
@@ -55,28 +70,440 @@ func (s *ScheduleService) CreateSchedule(req *models.CreateScheduleRequest) (*mo
 	return schedule, nil
 }
 
+// UpdateSchedule частично изменяет слот: доступность, кабинет, дату и время.
+// Новое положение слота проверяется на конфликты расписания и календарь клиники.
+// Слот с записью пациента нельзя освободить, а перенести или сменить кабинет можно только при Force;
+// в этом случае запись возвращается в результате для уведомления пациента. Слот, удерживаемый по предложению
+// из листа ожидания, освободить нельзя. Изменяются только переданные поля.
+// Изменение рассылается табло расписания триггером schedule_update.
+func (s *ScheduleService) UpdateSchedule(id uint, req *models.UpdateScheduleRequest) (*models.UpdateScheduleResult, error) {
+	schedule, err := s.scheduleRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("слот расписания с ID %d не найден", id)
+		}
+		return nil, fmt.Errorf("ошибка при поиске слота расписания: %w", err)
+	}
+	if req.Cabinet != nil && req.ClearCabinet {
+		return nil, invalidError("нельзя одновременно указать кабинет и снять привязку к кабинету")
+	}
+	if err := normalizeClockPtr(req.StartTime); err != nil {
+		return nil, err
+	}
+	if err := normalizeClockPtr(req.EndTime); err != nil {
+		return nil, err
+	}
+
+	original := *schedule
+	updated := *schedule
+	if req.Date != nil {
+		updated.Date = truncateToDate(*req.Date)
+	}
+	if req.StartTime != nil {
+		updated.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		updated.EndTime = *req.EndTime
+	}
+	switch {
+	case req.Cabinet != nil:
+		updated.Cabinet = req.Cabinet
+	case req.ClearCabinet:
+		updated.Cabinet = nil
+	}
+	if req.IsAvailable != nil {
+		updated.IsAvailable = *req.IsAvailable
+	}
+
+	moved := !truncateToDate(updated.Date).Equal(truncateToDate(original.Date)) ||
+		updated.StartTime != original.StartTime || updated.EndTime != original.EndTime
+	cabinetChanged := !sameCabinet(updated.Cabinet, original.Cabinet)
+
+	if cabinetChanged {
+		if err := checkCabinetUsable(s.cabinetRepo, updated.Cabinet); err != nil {
+			return nil, err
+		}
+	}
+	if moved || cabinetChanged {
+		report, err := detectScheduleConflicts(s.scheduleRepo, []models.Schedule{updated}, map[uint]bool{updated.ID: true}, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(report.Conflicts) > 0 {
+			return nil, &ScheduleConflictError{Conflicts: report.Conflicts}
+		}
+	}
+	if moved && !req.Force {
+		day := truncateToDate(updated.Date)
+		cal, err := loadClinicCalendar(s.calendarRepo, day, day)
+		if err != nil {
+			return nil, err
+		}
+		if reason := cal.blockReason(&updated); reason != "" {
+			return nil, conflictError("новое время слота недоступно: %s", reason)
+		}
+	}
+
+	changes := map[string]interface{}{}
+	if !truncateToDate(updated.Date).Equal(truncateToDate(original.Date)) {
+		changes["date"] = updated.Date
+	}
+	if updated.StartTime != original.StartTime {
+		changes["start_time"] = updated.StartTime
+	}
+	if updated.EndTime != original.EndTime {
+		changes["end_time"] = updated.EndTime
+	}
+	if cabinetChanged {
+		changes["cabinet"] = updated.Cabinet
+	}
+	if req.IsAvailable != nil {
+		changes["is_available"] = updated.IsAvailable
+	}
+
+	// Запись и удержание слота проверяются под блокировкой слота, чтобы параллельная запись пациента
+	// или предложение из листа ожидания не проскочили между проверкой и изменением.
+	var appointment *models.Appointment
+	err = s.scheduleRepo.UpdateFields(id, changes, func(occupant *models.Appointment, held bool) error {
+		appointment = occupant
+		if occupant != nil {
+			if req.IsAvailable != nil && *req.IsAvailable {
+				return conflictError("слот занят записью пациента; чтобы освободить его, отмените запись")
+			}
+			if (moved || cabinetChanged) && !req.Force {
+				return conflictError("слот занят записью пациента %s; для изменения укажите force", occupant.Patient.FullName)
+			}
+		}
+		if held && req.IsAvailable != nil && *req.IsAvailable {
+			return conflictError("слот удерживается по предложению из листа ожидания; он освободится после ответа пациента или истечения срока")
+		}
+		return nil
+	})
+	if err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, conflictError("слот совпадает с существующим слотом врача")
+		}
+		return nil, fmt.Errorf("не удалось обновить слот расписания: %w", err)
+	}
+
+	result := &models.UpdateScheduleResult{Schedule: updated.ToResponse()}
+	if appointment != nil && (moved || cabinetChanged) {
+		affected := models.AffectedAppointment{
+			AppointmentID:   appointment.ID,
+			PatientID:       appointment.PatientID,
+			PatientFullName: appointment.Patient.FullName,
+			PatientPhone:    appointment.Patient.Phone,
+			Date:            original.Date.Format("2006-01-02"),
+			StartTime:       original.StartTime,
+			Action:          models.AffectedActionTimeChanged,
+		}
+		if moved {
+			newStart := updated.StartTime
+			affected.NewStartTime = &newStart
+		}
+		result.Appointment = &affected
+	}
+	return result, nil
+}
+
+// sameCabinet сравнивает необязательные номера кабинетов.
+func sameCabinet(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// GetSchedule возвращает слот расписания по ID.
+func (s *ScheduleService) GetSchedule(id uint) (*models.Schedule, error) {
+	schedule, err := s.scheduleRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("слот расписания с ID %d не найден", id)
+		}
+		return nil, fmt.Errorf("ошибка при поиске слота расписания: %w", err)
+	}
+	return schedule, nil
+}
+
 // DeleteSchedule удаляет слот из расписания по ID.
 func (s *ScheduleService) DeleteSchedule(id uint) error {
 	_, err := s.scheduleRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("слот расписания с ID %d не найден", id)
+			return notFoundError("слот расписания с ID %d не найден", id)
 		}
 		return fmt.Errorf("ошибка при поиске слота расписания: %w", err)
 	}
 
 	if err := s.scheduleRepo.Delete(id); err != nil {
+		// На слот ссылаются записи пациентов (в том числе отмененные), удаление потеряло бы историю.
+		if repository.IsForeignKeyViolation(err) {
+			return conflictError("слот %d используется в записях пациентов, его можно только заблокировать", id)
+		}
 		return fmt.Errorf("не удалось удалить слот из расписания: %w", err)
 	}
 	return nil
 }
 
+// CancelSchedules отменяет прием врача за период и возвращает отчет о затронутых пациентах.
+// При ReassignToDoctorID записи переносятся к указанному врачу, при AutoReassign - к любому врачу
+// той же специализации, у которого свободно то же время. Слоты, закрытые календарем клиники или
+// отсутствием врача, для переноса не используются.
+func (s *ScheduleService) CancelSchedules(req *models.CancelSchedulesRequest, actor string) (*models.CancelSchedulesResult, error) {
+	doctor, err := s.doctorRepo.GetByID(req.DoctorID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("врач с ID %d не найден", req.DoctorID)
+		}
+		return nil, fmt.Errorf("ошибка проверки врача: %w", err)
+	}
+
+	req.DateFrom = truncateToDate(req.DateFrom)
+	req.DateTo = truncateToDate(req.DateTo)
+	if req.DateTo.Before(req.DateFrom) {
+		return nil, invalidError("дата окончания периода раньше даты начала")
+	}
+	if err := normalizeClockPtr(req.StartTime); err != nil {
+		return nil, err
+	}
+	if err := normalizeClockPtr(req.EndTime); err != nil {
+		return nil, err
+	}
+
+	var candidates []uint
+	switch {
+	case req.ReassignToDoctorID != nil:
+		if *req.ReassignToDoctorID == req.DoctorID {
+			return nil, invalidError("нельзя перенести записи к тому же врачу")
+		}
+		target, err := s.doctorRepo.GetByID(*req.ReassignToDoctorID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, notFoundError("врач с ID %d не найден", *req.ReassignToDoctorID)
+			}
+			return nil, fmt.Errorf("ошибка проверки врача: %w", err)
+		}
+		if target.Specialization != doctor.Specialization {
+			return nil, invalidError("врач %s имеет другую специализацию (%s)", target.FullName, target.Specialization)
+		}
+		candidates = []uint{target.ID}
+	case req.AutoReassign:
+		doctors, err := s.doctorRepo.GetAll(false)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения списка врачей: %w", err)
+		}
+		for _, d := range doctors {
+			if d.ID != doctor.ID && d.Specialization == doctor.Specialization {
+				candidates = append(candidates, d.ID)
+			}
+		}
+	}
+
+	targets, err := s.reassignTargets(candidates, req.DateFrom, req.DateTo)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.scheduleRepo.CancelSlots(req, targets, actor)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отменить прием: %w", err)
+	}
+	return result, nil
+}
+
+// reassignTargets возвращает свободные слоты врачей doctorIDs за период, доступные по календарю клиники.
+func (s *ScheduleService) reassignTargets(doctorIDs []uint, from, to time.Time) ([]uint, error) {
+	if len(doctorIDs) == 0 {
+		return nil, nil
+	}
+	cal, err := loadClinicCalendar(s.calendarRepo, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []uint
+	for _, id := range doctorIDs {
+		slots, err := s.scheduleRepo.FindInRange(&id, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения расписания врача: %w", err)
+		}
+		for i := range slots {
+			if slots[i].IsAvailable && cal.blockReason(&slots[i]) == "" {
+				targets = append(targets, slots[i].ID)
+			}
+		}
+	}
+	return targets, nil
+}
+
+// CopyWeek копирует слоты недели (пн-вс) на следующие недели, начиная с TargetWeekStart.
+// Копии создаются свободными; уже существующие слоты и слоты, недоступные по календарю клиники, пропускаются.
+func (s *ScheduleService) CopyWeek(req *models.CopyWeekRequest) (*models.CopyWeekResult, error) {
+	if req.DoctorID != nil {
+		if _, err := s.doctorRepo.GetByID(*req.DoctorID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, notFoundError("врач с ID %d не найден", *req.DoctorID)
+			}
+			return nil, fmt.Errorf("ошибка проверки врача: %w", err)
+		}
+	}
+
+	sourceStart := weekStart(req.SourceWeekStart)
+	targetStart := weekStart(req.TargetWeekStart)
+	if !targetStart.After(sourceStart) {
+		return nil, invalidError("целевая неделя должна быть позже исходной")
+	}
+	weeks := req.Weeks
+	if weeks <= 0 {
+		weeks = 1
+	}
+
+	source, err := s.scheduleRepo.FindInRange(req.DoctorID, sourceStart, sourceStart.AddDate(0, 0, 6))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения исходной недели: %w", err)
+	}
+
+	offsetDays := int(targetStart.Sub(sourceStart).Hours()/24 + 0.5)
+	var copies []models.Schedule
+	for w := 0; w < weeks; w++ {
+		for _, slot := range source {
+			copies = append(copies, models.Schedule{
+				DoctorID:    slot.DoctorID,
+				Date:        truncateToDate(slot.Date).AddDate(0, 0, offsetDays+7*w),
+				StartTime:   slot.StartTime,
+				EndTime:     slot.EndTime,
+				IsAvailable: true,
+				Cabinet:     slot.Cabinet,
+			})
+		}
+	}
+
+	cal, err := loadClinicCalendar(s.calendarRepo, targetStart, targetStart.AddDate(0, 0, 7*weeks-1))
+	if err != nil {
+		return nil, err
+	}
+	workingCopies, skippedByCalendar := cal.filterByCalendar(copies)
+
+	report, err := detectScheduleConflicts(s.scheduleRepo, workingCopies, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Conflicts) > 0 && !req.SkipConflicts {
+		return nil, &ScheduleConflictError{Conflicts: report.Conflicts}
+	}
+
+	toCreate := filterSlots(workingCopies, report.Duplicates, report.Conflicting)
+	created, err := s.scheduleRepo.CreateBatchSkipExisting(toCreate)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось скопировать расписание: %w", err)
+	}
+	return &models.CopyWeekResult{
+		Planned:           len(copies),
+		Created:           created,
+		Skipped:           int64(len(copies)) - created,
+		SkippedByCalendar: skippedByCalendar,
+		Conflicts:         report.Conflicts,
+	}, nil
+}
+
+// ShiftSchedules сдвигает слоты врача за день на указанное число минут (может быть отрицательным)
+// и возвращает список пациентов, у которых изменилось время приема.
+func (s *ScheduleService) ShiftSchedules(req *models.ShiftSchedulesRequest) (*models.ShiftSchedulesResult, error) {
+	if _, err := s.doctorRepo.GetByID(req.DoctorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("врач с ID %d не найден", req.DoctorID)
+		}
+		return nil, fmt.Errorf("ошибка проверки врача: %w", err)
+	}
+	if req.ShiftMinutes == 0 {
+		return nil, invalidError("величина сдвига не может быть нулевой")
+	}
+	if err := normalizeClockPtr(req.FromTime); err != nil {
+		return nil, err
+	}
+	if err := normalizeClockPtr(req.ToTime); err != nil {
+		return nil, err
+	}
+	req.Date = truncateToDate(req.Date)
+	shift := time.Duration(req.ShiftMinutes) * time.Minute
+
+	// Проверяем итоговую раскладку дня до изменения: сдвигаемые слоты сравниваются
+	// с остальными слотами врача и со слотами других врачей в тех же кабинетах.
+	daySlots, err := s.scheduleRepo.FindInRange(&req.DoctorID, req.Date, req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения слотов врача: %w", err)
+	}
+	var shifted []models.Schedule
+	ignore := map[uint]bool{}
+	for _, slot := range daySlots {
+		if req.FromTime != nil && slot.StartTime < *req.FromTime {
+			continue
+		}
+		if req.ToTime != nil && slot.StartTime >= *req.ToTime {
+			continue
+		}
+		start, err := parseClock(slot.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(slot.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		newStart, newEnd := start.Add(shift), end.Add(shift)
+		if newStart.Day() != start.Day() || newEnd.Day() != end.Day() {
+			return nil, invalidError("слот %s-%s после сдвига выходит за пределы суток", slot.StartTime, slot.EndTime)
+		}
+		slot.StartTime = newStart.Format("15:04:05")
+		slot.EndTime = newEnd.Format("15:04:05")
+		shifted = append(shifted, slot)
+		ignore[slot.ID] = true
+	}
+	report, err := detectScheduleConflicts(s.scheduleRepo, shifted, ignore, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Conflicts) > 0 {
+		return nil, &ScheduleConflictError{Conflicts: report.Conflicts}
+	}
+
+	result, err := s.scheduleRepo.ShiftSlots(req, shift)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сдвинуть слоты: %w", err)
+	}
+	return result, nil
+}
+
+// normalizeClockPtr проверяет необязательное время суток и приводит его к формату ЧЧ:ММ:СС.
+func normalizeClockPtr(value *string) error {
+	if value == nil {
+		return nil
+	}
+	t, err := parseClock(*value)
+	if err != nil {
+		return err
+	}
+	*value = t.Format("15:04:05")
+	return nil
+}
+
+// weekStart возвращает понедельник недели, в которую попадает дата.
+func weekStart(t time.Time) time.Time {
+	d := truncateToDate(t)
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
 // TodayScheduleResponse определяет структуру для ежедневного расписания.
+// DayType и DayDescription заполняются, если сегодня особый день календаря клиники.
 type TodayScheduleResponse struct {
-	Date         string                `json:"date"`
-	MinStartTime string                `json:"min_start_time"`
-	MaxEndTime   string                `json:"max_end_time"`
-	Doctors      []DoctorScheduleModel `json:"doctors"`
+	Date           string                `json:"date"`
+	MinStartTime   string                `json:"min_start_time"`
+	MaxEndTime     string                `json:"max_end_time"`
+	DayType        string                `json:"day_type,omitempty"`
+	DayDescription string                `json:"day_description,omitempty"`
+	Doctors        []DoctorScheduleModel `json:"doctors"`
 }
 
 // DoctorScheduleModel представляет расписание для одного врача.
@@ -88,17 +515,36 @@ type DoctorScheduleModel struct {
 }
 
 // TimeSlotModel представляет один временной слот в расписании.
+// UnavailableReason заполняется, если прием в слоте невозможен по календарю клиники или из-за отсутствия врача.
 type TimeSlotModel struct {
-	StartTime   string `json:"start_time"`
-	EndTime     string `json:"end_time"`
-	IsAvailable bool   `json:"is_available"`
-	Cabinet     *int   `json:"cabinet,omitempty"`
+	StartTime         string `json:"start_time"`
+	EndTime           string `json:"end_time"`
+	IsAvailable       bool   `json:"is_available"`
+	Cabinet           *int   `json:"cabinet,omitempty"`
+	UnavailableReason string `json:"unavailable_reason,omitempty"`
 }
 
 // GetTodayScheduleState подготавливает данные для отображения дневного расписания.
+// В нерабочий день клиники врачи не выводятся, а слоты вне часов сокращенного дня
+// и на время отсутствия врача отмечаются недоступными.
 func (s *ScheduleService) GetTodayScheduleState() (*TodayScheduleResponse, error) {
 	today := time.Now()
 
+	cal, err := loadClinicCalendar(s.calendarRepo, truncateToDate(today), truncateToDate(today))
+	if err != nil {
+		return nil, err
+	}
+	if day, ok := cal.days[today.Format("2006-01-02")]; ok && day.DayType == models.ClinicDayHoliday {
+		return &TodayScheduleResponse{
+			Date:           today.Format("2006-01-02"),
+			MinStartTime:   "09:00:00",
+			MaxEndTime:     "18:00:00",
+			DayType:        string(day.DayType),
+			DayDescription: day.Description,
+			Doctors:        []DoctorScheduleModel{},
+		}, nil
+	}
+
 	minTime, maxTime, err := s.scheduleRepo.FindMinMaxTimesForDate(today)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -133,11 +579,13 @@ func (s *ScheduleService) GetTodayScheduleState() (*TodayScheduleResponse, error
 			}
 		}
 
+		reason := cal.blockReason(&schedule)
 		docSchedule.Slots = append(docSchedule.Slots, TimeSlotModel{
-			StartTime:   schedule.StartTime,
-			EndTime:     schedule.EndTime,
-			IsAvailable: schedule.IsAvailable,
-			Cabinet:     schedule.Cabinet,
+			StartTime:         schedule.StartTime,
+			EndTime:           schedule.EndTime,
+			IsAvailable:       schedule.IsAvailable && reason == "",
+			Cabinet:           schedule.Cabinet,
+			UnavailableReason: reason,
 		})
 		schedulesByDoctor[schedule.DoctorID] = docSchedule
 	}
@@ -153,6 +601,10 @@ func (s *ScheduleService) GetTodayScheduleState() (*TodayScheduleResponse, error
 		MaxEndTime:   maxTime.Format("15:04:05"),
 		Doctors:      doctorSchedules,
 	}
+	if day, ok := cal.days[today.Format("2006-01-02")]; ok {
+		response.DayType = string(day.DayType)
+		response.DayDescription = day.Description
+	}
 
 	return response, nil
 }
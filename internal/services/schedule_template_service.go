@@ -0,0 +1,322 @@
+package services
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/repository"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxGenerationDays ограничивает период одной генерации, чтобы случайно не создать расписание на годы вперед.
+const maxGenerationDays = 92
+
+// ScheduleTemplateService управляет шаблонами расписания и генерацией слотов по ним.
+type ScheduleTemplateService struct {
+	templateRepo repository.ScheduleTemplateRepository
+	scheduleRepo repository.ScheduleRepository
+	doctorRepo   repository.DoctorRepository
+	calendarRepo repository.CalendarRepository
+	cabinetRepo  repository.CabinetRepository
+}
+
+// NewScheduleTemplateService создает новый экземпляр ScheduleTemplateService.
+func NewScheduleTemplateService(templateRepo repository.ScheduleTemplateRepository, scheduleRepo repository.ScheduleRepository, doctorRepo repository.DoctorRepository, calendarRepo repository.CalendarRepository, cabinetRepo repository.CabinetRepository) *ScheduleTemplateService {
+	return &ScheduleTemplateService{
+		templateRepo: templateRepo,
+		scheduleRepo: scheduleRepo,
+		doctorRepo:   doctorRepo,
+		calendarRepo: calendarRepo,
+		cabinetRepo:  cabinetRepo,
+	}
+}
+
+// GetAll возвращает все шаблоны расписания.
+func (s *ScheduleTemplateService) GetAll() ([]models.ScheduleTemplate, error) {
+	return s.templateRepo.GetAll()
+}
+
+// Create создает шаблон расписания.
+func (s *ScheduleTemplateService) Create(req *models.ScheduleTemplateRequest) (*models.ScheduleTemplate, error) {
+	template := &models.ScheduleTemplate{IsActive: true}
+	if err := s.applyRequest(template, req); err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.Create(template); err != nil {
+		return nil, fmt.Errorf("не удалось создать шаблон расписания: %w", err)
+	}
+	return template, nil
+}
+
+// Update полностью заменяет параметры шаблона расписания.
+// Уже сгенерированные слоты не изменяются.
+func (s *ScheduleTemplateService) Update(id uint, req *models.ScheduleTemplateRequest) (*models.ScheduleTemplate, error) {
+	template, err := s.getTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRequest(template, req); err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.Update(template); err != nil {
+		return nil, fmt.Errorf("не удалось обновить шаблон расписания: %w", err)
+	}
+	return template, nil
+}
+
+// Delete удаляет шаблон расписания. Уже сгенерированные слоты сохраняются.
+func (s *ScheduleTemplateService) Delete(id uint) error {
+	if _, err := s.getTemplate(id); err != nil {
+		return err
+	}
+	if err := s.templateRepo.Delete(id); err != nil {
+		return fmt.Errorf("не удалось удалить шаблон расписания: %w", err)
+	}
+	return nil
+}
+
+// Generate создает слоты по шаблонам за период [from, to] включительно.
+// Повторный запуск безопасен: существующие слоты (врач, дата, время начала) пропускаются.
+// Слоты в нерабочие дни клиники, вне часов сокращенного дня и на время отсутствия врача не создаются.
+func (s *ScheduleTemplateService) Generate(req *models.GenerateSchedulesRequest) (*models.GenerateSchedulesResult, error) {
+	from := truncateToDate(req.From)
+	to := truncateToDate(req.To)
+	if to.Before(from) {
+		return nil, invalidError("дата окончания периода раньше даты начала")
+	}
+	if int(to.Sub(from).Hours()/24) >= maxGenerationDays {
+		return nil, invalidError("период генерации не может превышать %d дней", maxGenerationDays)
+	}
+
+	var templates []models.ScheduleTemplate
+	if req.TemplateID != nil {
+		template, err := s.getTemplate(*req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		templates = []models.ScheduleTemplate{*template}
+	} else {
+		found, err := s.templateRepo.FindActiveForRange(from, to)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения шаблонов расписания: %w", err)
+		}
+		for _, t := range found {
+			if req.DoctorID == nil || t.DoctorID == *req.DoctorID {
+				templates = append(templates, t)
+			}
+		}
+	}
+
+	var slots []models.Schedule
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		for i := range templates {
+			if !templates[i].AppliesTo(day) {
+				continue
+			}
+			daySlots, err := buildTemplateSlots(&templates[i], day)
+			if err != nil {
+				return nil, fmt.Errorf("шаблон %d: %w", templates[i].ID, err)
+			}
+			slots = append(slots, daySlots...)
+		}
+	}
+
+	cal, err := loadClinicCalendar(s.calendarRepo, from, to)
+	if err != nil {
+		return nil, err
+	}
+	workingSlots, skippedByCalendar := cal.filterByCalendar(slots)
+
+	report, err := detectScheduleConflicts(s.scheduleRepo, workingSlots, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Conflicts) > 0 && !req.SkipConflicts {
+		return nil, &ScheduleConflictError{Conflicts: report.Conflicts}
+	}
+
+	created, err := s.scheduleRepo.CreateBatchSkipExisting(filterSlots(workingSlots, report.Duplicates, report.Conflicting))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать слоты по шаблонам: %w", err)
+	}
+
+	result := &models.GenerateSchedulesResult{
+		From:              from.Format("2006-01-02"),
+		To:                to.Format("2006-01-02"),
+		Planned:           len(slots),
+		Created:           created,
+		Skipped:           int64(len(slots)) - created,
+		SkippedByCalendar: skippedByCalendar,
+		Conflicts:         report.Conflicts,
+	}
+	logger.Default().WithField("module", "schedule_templates").
+		WithField("from", result.From).WithField("to", result.To).
+		WithField("created", result.Created).WithField("skipped", result.Skipped).
+		Info("Schedule slots generated from templates")
+	return result, nil
+}
+
+// GenerateAhead генерирует слоты на days дней вперед, начиная с сегодняшнего дня.
+// Конфликтующие слоты пропускаются, чтобы один конфликт не останавливал ночную генерацию.
+func (s *ScheduleTemplateService) GenerateAhead(days int) (*models.GenerateSchedulesResult, error) {
+	if days <= 0 {
+		return &models.GenerateSchedulesResult{}, nil
+	}
+	today := time.Now()
+	result, err := s.Generate(&models.GenerateSchedulesRequest{From: today, To: today.AddDate(0, 0, days-1), SkipConflicts: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Conflicts) > 0 {
+		logger.Default().WithField("module", "schedule_templates").
+			WithField("conflicts", len(result.Conflicts)).
+			Warn("Some template slots were skipped due to schedule conflicts")
+	}
+	return result, nil
+}
+
+func (s *ScheduleTemplateService) getTemplate(id uint) (*models.ScheduleTemplate, error) {
+	template, err := s.templateRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("шаблон расписания с ID %d не найден", id)
+		}
+		return nil, fmt.Errorf("ошибка при поиске шаблона расписания: %w", err)
+	}
+	return template, nil
+}
+
+// applyRequest проверяет запрос и переносит его значения в шаблон.
+func (s *ScheduleTemplateService) applyRequest(template *models.ScheduleTemplate, req *models.ScheduleTemplateRequest) error {
+	if _, err := s.doctorRepo.GetByID(req.DoctorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFoundError("врач с ID %d не найден", req.DoctorID)
+		}
+		return fmt.Errorf("ошибка проверки врача: %w", err)
+	}
+	if err := checkCabinetUsable(s.cabinetRepo, req.Cabinet); err != nil {
+		return err
+	}
+
+	start, err := parseClock(req.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(req.EndTime)
+	if err != nil {
+		return err
+	}
+	if !end.After(start) {
+		return invalidError("время окончания приема должно быть позже времени начала")
+	}
+	if end.Sub(start) < time.Duration(req.SlotMinutes)*time.Minute {
+		return invalidError("длительность слота превышает рабочее время")
+	}
+
+	for _, br := range req.Breaks {
+		bStart, err := parseClock(br.Start)
+		if err != nil {
+			return err
+		}
+		bEnd, err := parseClock(br.End)
+		if err != nil {
+			return err
+		}
+		if !bEnd.After(bStart) {
+			return invalidError("перерыв %s-%s: время окончания должно быть позже времени начала", br.Start, br.End)
+		}
+	}
+
+	if req.ValidTo != nil && truncateToDate(*req.ValidTo).Before(truncateToDate(req.ValidFrom)) {
+		return invalidError("дата окончания действия шаблона раньше даты начала")
+	}
+
+	template.DoctorID = req.DoctorID
+	template.Weekdays = models.IntList(req.Weekdays)
+	template.StartTime = start.Format("15:04:05")
+	template.EndTime = end.Format("15:04:05")
+	template.SlotMinutes = req.SlotMinutes
+	template.Cabinet = req.Cabinet
+	template.Breaks = models.TimeRangeList(req.Breaks)
+	template.ValidFrom = truncateToDate(req.ValidFrom)
+	if req.ValidTo != nil {
+		validTo := truncateToDate(*req.ValidTo)
+		template.ValidTo = &validTo
+	} else {
+		template.ValidTo = nil
+	}
+	if req.IsActive != nil {
+		template.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// buildTemplateSlots нарезает рабочее время шаблона на слоты заданной длины,
+// пропуская слоты, пересекающиеся с перерывами. Неполный слот в конце дня не создается.
+func buildTemplateSlots(template *models.ScheduleTemplate, day time.Time) ([]models.Schedule, error) {
+	start, err := parseClock(template.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(template.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	type interval struct{ start, end time.Time }
+	breaks := make([]interval, 0, len(template.Breaks))
+	for _, br := range template.Breaks {
+		bStart, err := parseClock(br.Start)
+		if err != nil {
+			return nil, err
+		}
+		bEnd, err := parseClock(br.End)
+		if err != nil {
+			return nil, err
+		}
+		breaks = append(breaks, interval{bStart, bEnd})
+	}
+
+	step := time.Duration(template.SlotMinutes) * time.Minute
+	var slots []models.Schedule
+	for slotStart := start; !slotStart.Add(step).After(end); slotStart = slotStart.Add(step) {
+		slotEnd := slotStart.Add(step)
+		inBreak := false
+		for _, br := range breaks {
+			if slotStart.Before(br.end) && br.start.Before(slotEnd) {
+				inBreak = true
+				break
+			}
+		}
+		if inBreak {
+			continue
+		}
+		slots = append(slots, models.Schedule{
+			DoctorID:    template.DoctorID,
+			Date:        day,
+			StartTime:   slotStart.Format("15:04:05"),
+			EndTime:     slotEnd.Format("15:04:05"),
+			IsAvailable: true,
+			Cabinet:     template.Cabinet,
+		})
+	}
+	return slots, nil
+}
+
+// parseClock разбирает время суток в формате "HH:MM" или "HH:MM:SS".
+func parseClock(value string) (time.Time, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, invalidError("неверный формат времени '%s', ожидается ЧЧ:ММ", value)
+}
+
+// truncateToDate отбрасывает время, оставляя календарную дату в локальном часовом поясе.
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
@@ -8,19 +8,26 @@ import (
 
 	"ElectronicQueue/internal/config"
 	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
 )
 
 type TasksTimerService struct {
-	cleanupService *CleanupService
-	config         *config.Config
-	log            *logger.AsyncLogger
+	cleanupService          *CleanupService
+	scheduleTemplateService *ScheduleTemplateService
+	appointmentService      *AppointmentService
+	auditService            *AuditService
+	config                  *config.Config
+	log                     *logger.AsyncLogger
 }
 
-func NewTasksTimerService(cleanupService *CleanupService, config *config.Config) *TasksTimerService {
+func NewTasksTimerService(cleanupService *CleanupService, scheduleTemplateService *ScheduleTemplateService, appointmentService *AppointmentService, auditService *AuditService, config *config.Config) *TasksTimerService {
 	return &TasksTimerService{
-		cleanupService: cleanupService,
-		config:         config,
-		log:            logger.Default().WithField("module", "tasks_timer"),
+		cleanupService:          cleanupService,
+		scheduleTemplateService: scheduleTemplateService,
+		appointmentService:      appointmentService,
+		auditService:            auditService,
+		config:                  config,
+		log:                     logger.Default().WithField("module", "tasks_timer"),
 	}
 }
 
@@ -36,10 +43,27 @@ func (s *TasksTimerService) Start(ctx context.Context) {
 		// Ждем до времени выполнения
 		select {
 		case <-time.After(time.Until(nextRun)):
+			runAt := time.Now()
 			// Выполняем очистку
 			if err := s.cleanupService.CleanTickets(); err != nil {
 				s.log.WithError(err).Error("Ошибка выполнения очистки tickets")
 			}
+			// Отмечаем неявку по записям прошедших дней, на которые пациент не пришел
+			if ids, err := s.appointmentService.MarkNoShows(runAt); err != nil {
+				s.log.WithError(err).Error("Ошибка отметки неявок по записям")
+			} else if len(ids) > 0 {
+				s.audit("appointments/mark-no-shows", runAt, models.AuditChange{
+					Entity: "appointments",
+					Before: map[string]interface{}{"appointment_ids": ids, "status": models.AppointmentBooked},
+					After:  map[string]interface{}{"appointment_ids": ids, "status": models.AppointmentNoShow},
+				})
+			}
+			// Достраиваем расписание по шаблонам на горизонт планирования
+			if result, err := s.scheduleTemplateService.GenerateAhead(s.config.ScheduleGenerationDays); err != nil {
+				s.log.WithError(err).Error("Ошибка генерации расписания по шаблонам")
+			} else if result.Created > 0 {
+				s.audit("schedule-templates/generate", runAt, models.AuditChange{Entity: "schedules", After: result})
+			}
 		case <-ctx.Done():
 			s.log.Info("Планировщик задач остановлен")
 			return
@@ -47,6 +71,13 @@ func (s *TasksTimerService) Start(ctx context.Context) {
 	}
 }
 
+// audit записывает в журнал аудита изменение, выполненное задачей. Ошибка записи не прерывает задачи.
+func (s *TasksTimerService) audit(task string, runAt time.Time, change models.AuditChange) {
+	if err := s.auditService.RecordTask(task, runAt, change); err != nil {
+		s.log.WithError(err).WithField("task", task).Error("Не удалось записать изменение в журнал аудита")
+	}
+}
+
 // calculateNextRun вычисляет время следующего запуска
 func (s *TasksTimerService) calculateNextRun() time.Time {
 	now := time.Now()
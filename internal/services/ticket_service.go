@@ -1,6 +1,7 @@
 package services
 
 import (
+	"ElectronicQueue/internal/export"
 	"ElectronicQueue/internal/logger"
 	"ElectronicQueue/internal/models"
 	"ElectronicQueue/internal/repository"
@@ -16,20 +17,31 @@ import (
 
 const maxTicketNumber = 1000
 
+// checkInServiceID - услуга, по которой выдаются талоны при регистрации явки на прием.
+const checkInServiceID = "confirm_appointment"
+
 type TicketService struct {
 	repo             repository.TicketRepository
 	serviceRepo      repository.ServiceRepository
 	receptionLogRepo repository.ReceptionLogRepository
 	patientRepo      repository.PatientRepository
 	appointmentRepo  repository.AppointmentRepository
+	calendarRepo     repository.CalendarRepository
+	checkInBefore    time.Duration
+	checkInAfter     time.Duration
 }
 
+// NewTicketService создает новый экземпляр TicketService.
+// checkInBeforeMinutes и checkInAfterMinutes задают окно регистрации явки относительно времени начала приема.
 func NewTicketService(
 	repo repository.TicketRepository,
 	serviceRepo repository.ServiceRepository,
 	receptionLogRepo repository.ReceptionLogRepository,
 	patientRepo repository.PatientRepository,
 	appointmentRepo repository.AppointmentRepository,
+	calendarRepo repository.CalendarRepository,
+	checkInBeforeMinutes int,
+	checkInAfterMinutes int,
 ) *TicketService {
 	return &TicketService{
 		repo:             repo,
@@ -37,6 +49,9 @@ func NewTicketService(
 		receptionLogRepo: receptionLogRepo,
 		patientRepo:      patientRepo,
 		appointmentRepo:  appointmentRepo,
+		calendarRepo:     calendarRepo,
+		checkInBefore:    time.Duration(checkInBeforeMinutes) * time.Minute,
+		checkInAfter:     time.Duration(checkInAfterMinutes) * time.Minute,
 	}
 }
 
@@ -82,7 +97,7 @@ func (s *TicketService) GetByID(idStr string) (*models.Ticket, error) {
 	_, err := fmt.Sscanf(idStr, "%d", &id)
 	if err != nil {
 		logger.Default().Error(fmt.Sprintf("GetByID: invalid id: %v", err))
-		return nil, fmt.Errorf("invalid id")
+		return nil, invalidError("invalid id")
 	}
 	ticket, err := s.repo.GetByID(id)
 	if err != nil {
@@ -95,7 +110,7 @@ func (s *TicketService) GetByID(idStr string) (*models.Ticket, error) {
 func (s *TicketService) CreateTicket(serviceID string) (*models.Ticket, error) {
 	if serviceID == "" {
 		logger.Default().Error("CreateTicket: serviceID is required")
-		return nil, fmt.Errorf("serviceID is required")
+		return nil, invalidError("serviceID is required")
 	}
 	ticketNumber, err := s.generateTicketNumber(serviceID)
 	if err != nil {
@@ -130,18 +145,25 @@ func (s *TicketService) UpdateTicket(ticket *models.Ticket) error {
 	return err
 }
 
-func (s *TicketService) DeleteTicket(idStr string) error {
+// DeleteTicket удаляет талон и возвращает его состояние до удаления.
+func (s *TicketService) DeleteTicket(idStr string) (*models.Ticket, error) {
 	var id uint
 	_, err := fmt.Sscanf(idStr, "%d", &id)
 	if err != nil {
 		logger.Default().Error(fmt.Sprintf("DeleteTicket: invalid id: %v", err))
-		return fmt.Errorf("invalid id")
+		return nil, invalidError("invalid id")
+	}
+	ticket, err := s.repo.GetByID(id)
+	if err != nil {
+		logger.Default().Error(fmt.Sprintf("DeleteTicket: repo get error: %v", err))
+		return nil, err
 	}
 	err = s.repo.Delete(id)
 	if err != nil {
 		logger.Default().Error(fmt.Sprintf("DeleteTicket: repo delete error: %v", err))
+		return nil, err
 	}
-	return err
+	return ticket, nil
 }
 
 func (s *TicketService) CallNextTicket(windowNumber int, categoryPrefix string) (*models.Ticket, error) {
@@ -149,7 +171,7 @@ func (s *TicketService) CallNextTicket(windowNumber int, categoryPrefix string)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.Default().WithField("category", categoryPrefix).Info("CallNextTicket: no waiting tickets in queue for category")
-			return nil, fmt.Errorf("очередь пуста")
+			return nil, notFoundError("очередь пуста")
 		}
 		logger.Default().WithError(err).Error("CallNextTicket: repo error getting next ticket")
 		return nil, err
@@ -182,14 +204,14 @@ func (s *TicketService) CallSpecificTicket(ticketID uint, windowNumber int) (*mo
 	ticket, err := s.repo.GetByID(ticketID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("талон с ID %d не найден", ticketID)
+			return nil, notFoundError("талон с ID %d не найден", ticketID)
 		}
 		logger.Default().WithError(err).Error(fmt.Sprintf("CallSpecificTicket: repo error getting ticket by id %d", ticketID))
 		return nil, fmt.Errorf("ошибка получения талона")
 	}
 
 	if ticket.Status != models.StatusWaiting {
-		return nil, fmt.Errorf("талон %s имеет неверный статус '%s' для вызова (ожидался 'ожидает')", ticket.TicketNumber, ticket.Status)
+		return nil, invalidError("талон %s имеет неверный статус '%s' для вызова (ожидался 'ожидает')", ticket.TicketNumber, ticket.Status)
 	}
 
 	now := time.Now()
@@ -215,45 +237,285 @@ func (s *TicketService) CallSpecificTicket(ticketID uint, windowNumber int) (*mo
 	return ticket, nil
 }
 
-func (s *TicketService) CheckInByPhone(phone string) (*models.Ticket, error) {
-	nonAlphanumericRegex := regexp.MustCompile(`[^0-9]+`)
-	sanitizedPhone := nonAlphanumericRegex.ReplaceAllString(phone, "")
+// checkInPatientNotFound - единый ответ на любое несовпадение телефона и даты рождения,
+// чтобы по терминалу нельзя было узнать, зарегистрирован ли номер телефона.
+const checkInPatientNotFound = "пациент с указанными номером телефона и датой рождения не найден"
+
+// CheckInByPhone регистрирует явку пациента на прием по номеру телефона.
+// Регистрация проходит в несколько шагов: сначала терминал запрашивает дату рождения (до обращения к базе,
+// поэтому ответ не зависит от того, зарегистрирован ли телефон), затем, если под телефон и дату рождения
+// подходят несколько пациентов или у пациента несколько записей, возвращает варианты для выбора.
+// Выбранные записи регистрируются одновременно, на каждую выдается талон.
+func (s *TicketService) CheckInByPhone(req *models.CheckInByPhoneRequest) (*models.CheckInResult, error) {
+	nonDigitRegex := regexp.MustCompile(`[^0-9]+`)
+	sanitizedPhone := nonDigitRegex.ReplaceAllString(req.Phone, "")
+	if sanitizedPhone == "" {
+		return nil, invalidError("необходимо указать номер телефона")
+	}
+
+	rawBirthDate := strings.TrimSpace(req.BirthDate)
+	if rawBirthDate == "" {
+		return &models.CheckInResult{Status: models.CheckInConfirmBirthDate, Message: "Введите дату рождения"}, nil
+	}
+	birthDate, err := time.Parse("2006-01-02", rawBirthDate)
+	if err != nil {
+		return nil, invalidError("неверный формат даты рождения, используйте YYYY-MM-DD")
+	}
 
-	patient, err := s.patientRepo.FindByPhone(sanitizedPhone)
+	patients, err := s.patientRepo.FindAllByPhone(sanitizedPhone)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска пациента: %w", err)
+	}
+	var matched []models.Patient
+	for _, p := range patients {
+		if p.BirthDate.Format("2006-01-02") == birthDate.Format("2006-01-02") {
+			matched = append(matched, p)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, notFoundError(checkInPatientNotFound)
+	}
+
+	var patient *models.Patient
+	switch {
+	case req.PatientID != nil:
+		for i := range matched {
+			if matched[i].ID == *req.PatientID {
+				patient = &matched[i]
+				break
+			}
+		}
+		if patient == nil {
+			return nil, notFoundError(checkInPatientNotFound)
+		}
+	case len(matched) == 1:
+		patient = &matched[0]
+	default:
+		candidates := make([]models.CheckInPatientCandidate, 0, len(matched))
+		for _, p := range matched {
+			candidates = append(candidates, models.CheckInPatientCandidate{ID: p.ID, ShortName: shortPatientName(p.FullName)})
+		}
+		return &models.CheckInResult{Status: models.CheckInSelectPatient, Message: "Выберите пациента", Patients: candidates}, nil
+	}
+
+	return s.checkInAppointments(patient, req.AppointmentIDs)
+}
+
+// CheckInByOMS регистрирует явку на прием по номеру полиса ОМС.
+func (s *TicketService) CheckInByOMS(req *models.CheckInByOMSRequest) (*models.CheckInResult, error) {
+	omsNumber := strings.TrimSpace(req.OmsNumber)
+	if omsNumber == "" {
+		return nil, invalidError("необходимо указать номер полиса ОМС")
+	}
+	patient, err := s.patientRepo.FindByOMS(omsNumber)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("пациент с указанным номером телефона не найден")
+			return nil, notFoundError("пациент с указанным полисом ОМС не найден")
 		}
 		return nil, fmt.Errorf("ошибка поиска пациента: %w", err)
 	}
+	return s.checkInAppointments(patient, req.AppointmentIDs)
+}
 
-	now := time.Now()
-	appointment, err := s.appointmentRepo.FindUpcomingByPatientID(patient.ID, now)
+// CheckInByPassport регистрирует явку на прием по серии и номеру паспорта.
+func (s *TicketService) CheckInByPassport(req *models.CheckInByPassportRequest) (*models.CheckInResult, error) {
+	patient, err := s.patientRepo.FindByPassport(strings.TrimSpace(req.PassportSeries), strings.TrimSpace(req.PassportNumber))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("пациент с указанным паспортом не найден")
+		}
+		return nil, fmt.Errorf("ошибка поиска пациента: %w", err)
+	}
+	return s.checkInAppointments(patient, req.AppointmentIDs)
+}
+
+// CheckInByCode регистрирует явку по коду подтверждения самостоятельной записи.
+// Код однозначно определяет запись, поэтому выбор пациента и записей не требуется.
+func (s *TicketService) CheckInByCode(req *models.CheckInByCodeRequest) (*models.CheckInResult, error) {
+	code := strings.ToUpper(strings.Join(strings.FieldsFunc(req.ConfirmationCode, func(r rune) bool {
+		return r == ' ' || r == '-'
+	}), ""))
+	if code == "" {
+		return nil, invalidError("необходимо указать код подтверждения")
+	}
+	appointment, err := s.appointmentRepo.FindByConfirmationCode(code)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("у вас нет предстоящих записей на сегодня")
+			return nil, notFoundError("запись с указанным кодом подтверждения не найдена")
 		}
 		return nil, fmt.Errorf("ошибка поиска записи: %w", err)
 	}
+	if appointment.TicketID != nil {
+		return nil, conflictError("явка по записи уже зарегистрирована")
+	}
+	if appointment.Status != models.AppointmentBooked {
+		return nil, conflictError("ваша запись на %s: запись в статусе '%s', обратитесь в регистратуру", appointment.Schedule.StartTime, appointment.Status)
+	}
+	if !truncateToDate(appointment.Schedule.Date).Equal(truncateToDate(time.Now())) {
+		return nil, conflictError("ваша запись на %s %s: регистрация доступна только в день приема",
+			appointment.Schedule.Date.Format("02.01.2006"), appointment.Schedule.StartTime)
+	}
 
-	serviceID := "confirm_appointment"
-	ticketNumber, err := s.generateTicketNumber(serviceID)
+	patient, err := s.patientRepo.GetByID(appointment.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска пациента: %w", err)
+	}
+	return s.checkInAppointments(patient, []uint{appointment.ID})
+}
+
+// checkInAppointments регистрирует явку по сегодняшним записям пациента. Если записи не выбраны,
+// а доступных для регистрации несколько, возвращает их для выбора.
+func (s *TicketService) checkInAppointments(patient *models.Patient, appointmentIDs []uint) (*models.CheckInResult, error) {
+	now := time.Now()
+	today := truncateToDate(now)
+	appointments, err := s.appointmentRepo.FindForCheckIn(patient.ID, today)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска записи: %w", err)
+	}
+	if len(appointments) == 0 {
+		return nil, notFoundError("у вас нет предстоящих записей на сегодня")
+	}
+
+	// Талон не выдается, если прием сегодня отменен календарем клиники или отсутствием врача.
+	cal, err := loadClinicCalendar(s.calendarRepo, today, today)
 	if err != nil {
 		return nil, err
 	}
 
-	newTicket := &models.Ticket{
-		TicketNumber: ticketNumber,
-		Status:       models.StatusWaiting,
-		CreatedAt:    time.Now(),
-		ServiceType:  &serviceID,
+	candidates := make([]models.CheckInAppointmentCandidate, 0, len(appointments))
+	var available []models.Appointment
+	for _, app := range appointments {
+		reason := s.checkInBlockReason(cal, &app.Schedule, now)
+		candidates = append(candidates, models.CheckInAppointmentCandidate{
+			AppointmentID:  app.ID,
+			DoctorName:     app.Schedule.Doctor.FullName,
+			Specialization: app.Schedule.Doctor.Specialization,
+			StartTime:      app.Schedule.StartTime,
+			Cabinet:        app.Schedule.Cabinet,
+			Available:      reason == "",
+			Reason:         reason,
+		})
+		if reason == "" {
+			available = append(available, app)
+		}
+	}
+
+	var selected []models.Appointment
+	if len(appointmentIDs) > 0 {
+		for _, id := range appointmentIDs {
+			idx := -1
+			for i := range appointments {
+				if appointments[i].ID == id {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				return nil, notFoundError("запись с ID %d не найдена среди ваших записей на сегодня", id)
+			}
+			if !candidates[idx].Available {
+				return nil, conflictError("ваша запись на %s: %s", candidates[idx].StartTime, candidates[idx].Reason)
+			}
+			selected = append(selected, appointments[idx])
+		}
+	} else {
+		switch len(available) {
+		case 0:
+			if len(candidates) == 1 {
+				return nil, conflictError("ваша запись на %s: %s", candidates[0].StartTime, candidates[0].Reason)
+			}
+			return &models.CheckInResult{
+				Status:       models.CheckInSelectAppointments,
+				Message:      "Регистрация по вашим записям сейчас недоступна",
+				PatientName:  shortPatientName(patient.FullName),
+				Appointments: candidates,
+			}, nil
+		case 1:
+			selected = available
+		default:
+			return &models.CheckInResult{
+				Status:       models.CheckInSelectAppointments,
+				Message:      "Выберите записи для регистрации",
+				PatientName:  shortPatientName(patient.FullName),
+				Appointments: candidates,
+			}, nil
+		}
 	}
 
-	if err := s.appointmentRepo.AssignTicketToAppointment(appointment, newTicket); err != nil {
+	numbers, err := s.generateTicketNumbers(checkInServiceID, len(selected))
+	if err != nil {
+		return nil, err
+	}
+	serviceID := checkInServiceID
+	tickets := make([]*models.Ticket, len(selected))
+	for i, number := range numbers {
+		tickets[i] = &models.Ticket{
+			TicketNumber: number,
+			Status:       models.StatusWaiting,
+			CreatedAt:    now,
+			ServiceType:  &serviceID,
+		}
+	}
+	if err := s.appointmentRepo.AssignTicketsToAppointments(selected, tickets); err != nil {
 		return nil, fmt.Errorf("не удалось создать талон и привязать к записи: %w", err)
 	}
 
-	return newTicket, nil
+	serviceName := s.MapServiceIDToName(serviceID)
+	result := &models.CheckInResult{
+		Status:      models.CheckInCompleted,
+		Message:     "Ваш электронный талон",
+		PatientName: shortPatientName(patient.FullName),
+		Timeout:     10,
+	}
+	for i, app := range selected {
+		result.Tickets = append(result.Tickets, models.CheckInTicket{
+			AppointmentID: app.ID,
+			TicketNumber:  tickets[i].TicketNumber,
+			ServiceName:   serviceName,
+			DoctorName:    app.Schedule.Doctor.FullName,
+			StartTime:     app.Schedule.StartTime,
+			Cabinet:       app.Schedule.Cabinet,
+		})
+	}
+	logger.Default().WithField("module", "checkin").WithField("patient_id", patient.ID).
+		WithField("appointments", len(selected)).Info("Зарегистрирована явка пациента на прием")
+	return result, nil
+}
+
+// checkInBlockReason возвращает причину, по которой явку на прием в слоте сейчас зарегистрировать нельзя,
+// или пустую строку, если регистрация доступна.
+func (s *TicketService) checkInBlockReason(cal *clinicCalendar, slot *models.Schedule, now time.Time) string {
+	if reason := cal.blockReason(slot); reason != "" {
+		return fmt.Sprintf("прием не может состояться: %s, обратитесь в регистратуру", reason)
+	}
+	clock, err := parseClock(slot.StartTime)
+	if err != nil {
+		return "неверное время приема, обратитесь в регистратуру"
+	}
+	day := truncateToDate(slot.Date)
+	start := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, time.Local)
+	opensAt := start.Add(-s.checkInBefore)
+	closesAt := start.Add(s.checkInAfter)
+	switch {
+	case now.Before(opensAt):
+		return fmt.Sprintf("регистрация откроется в %s", opensAt.Format("15:04"))
+	case now.After(closesAt):
+		return "время регистрации истекло, обратитесь в регистратуру"
+	}
+	return ""
+}
+
+// shortPatientName сокращает ФИО до фамилии с инициалами, чтобы не показывать полные данные на терминале.
+func shortPatientName(fullName string) string {
+	parts := strings.Fields(fullName)
+	if len(parts) == 0 {
+		return ""
+	}
+	short := parts[0]
+	for _, part := range parts[1:] {
+		short += " " + string([]rune(part)[:1]) + "."
+	}
+	return short
 }
 
 func (s *TicketService) finalizeReceptionAndUpdateTicket(ticket *models.Ticket) error {
@@ -297,24 +559,66 @@ func (s *TicketService) GetDailyReport() ([]models.DailyReportRow, error) {
 	return report, nil
 }
 
+// dailyReportTitles - заголовки столбцов выгрузки дневного отчета в порядке полей DailyReportRow.
+var dailyReportTitles = []string{
+	"Номер талона", "Пациент", "Врач", "Специализация", "Кабинет",
+	"Время записи", "Статус", "Вызван", "Завершен", "Длительность",
+}
+
+// ExportDailyReport выгружает дневной отчет по талонам в файл.
+func (s *TicketService) ExportDailyReport(w export.Writer) error {
+	report, err := s.GetDailyReport()
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteHeader(dailyReportTitles); err != nil {
+		return err
+	}
+	for _, row := range report {
+		values := []interface{}{
+			row.TicketNumber, row.PatientFullName, row.DoctorFullName, row.DoctorSpecialization, row.CabinetNumber,
+			row.AppointmentTime, string(row.Status), row.CalledAt, row.CompletedAt, row.Duration,
+		}
+		if err := w.WriteRow(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *TicketService) generateTicketNumber(serviceID string) (string, error) {
+	numbers, err := s.generateTicketNumbers(serviceID, 1)
+	if err != nil {
+		return "", err
+	}
+	return numbers[0], nil
+}
+
+// generateTicketNumbers формирует count последовательных номеров талонов для услуги.
+func (s *TicketService) generateTicketNumbers(serviceID string, count int) ([]string, error) {
 	service, err := s.serviceRepo.GetByServiceID(serviceID)
 	if err != nil {
 		logger.Default().Error(fmt.Sprintf("generateTicketNumber: service not found: %v", err))
-		return "", err
+		return nil, err
 	}
 	letter := service.Letter
 	maxNum, err := s.repo.GetMaxTicketNumberForPrefix(letter)
 	if err != nil {
 		logger.Default().Error(fmt.Sprintf("generateTicketNumber: repo error getting max number for prefix %s: %v", letter, err))
-		return "", err
+		return nil, err
 	}
 
-	num := maxNum + 1
-	if num >= maxTicketNumber {
-		num = 1
+	numbers := make([]string, 0, count)
+	num := maxNum
+	for i := 0; i < count; i++ {
+		num++
+		if num >= maxTicketNumber {
+			num = 1
+		}
+		numbers = append(numbers, fmt.Sprintf("%s%03d", letter, num))
 	}
-	return fmt.Sprintf("%s%03d", letter, num), nil
+	return numbers, nil
 }
 
 func (s *TicketService) MapServiceIDToName(serviceID string) string {
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"ElectronicQueue/internal/logger"
+	"ElectronicQueue/internal/models"
+	"ElectronicQueue/internal/pubsub"
+	"ElectronicQueue/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// waitlistSweepInterval - период проверки предложений с истекшим сроком удержания слота.
+const waitlistSweepInterval = time.Minute
+
+// WaitlistService управляет листом ожидания: освободившийся слот удерживается для первой подходящей заявки
+// на заданное время, предложение рассылается через брокер, а при отказе или истечении срока слот
+// предлагается следующему пациенту.
+type WaitlistService struct {
+	repo         repository.WaitlistRepository
+	scheduleRepo repository.ScheduleRepository
+	doctorRepo   repository.DoctorRepository
+	calendarRepo repository.CalendarRepository
+	rules        *BookingRuleService
+	broker       *pubsub.Broker
+	audit        *AuditService
+	holdDuration time.Duration
+	log          *logger.AsyncLogger
+}
+
+// NewWaitlistService создает новый экземпляр WaitlistService. holdMinutes задает время удержания слота за пациентом.
+// Принятие предложения проверяется по правилам записи rules, закрытие истекших предложений записывается в журнал аудита audit.
+func NewWaitlistService(repo repository.WaitlistRepository, scheduleRepo repository.ScheduleRepository, doctorRepo repository.DoctorRepository, calendarRepo repository.CalendarRepository, rules *BookingRuleService, broker *pubsub.Broker, audit *AuditService, holdMinutes int) *WaitlistService {
+	if holdMinutes <= 0 {
+		holdMinutes = 30
+	}
+	return &WaitlistService{
+		repo:         repo,
+		scheduleRepo: scheduleRepo,
+		doctorRepo:   doctorRepo,
+		calendarRepo: calendarRepo,
+		rules:        rules,
+		broker:       broker,
+		audit:        audit,
+		holdDuration: time.Duration(holdMinutes) * time.Minute,
+		log:          logger.Default().WithField("module", "waitlist"),
+	}
+}
+
+// GetEntries возвращает заявки листа ожидания в порядке очереди.
+func (s *WaitlistService) GetEntries(status string, doctorID *uint) ([]models.WaitlistEntry, error) {
+	var filter *models.WaitlistStatus
+	if status != "" {
+		st := models.WaitlistStatus(status)
+		filter = &st
+	}
+	entries, err := s.repo.FindEntries(filter, doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения листа ожидания: %w", err)
+	}
+	return entries, nil
+}
+
+// CreateEntry ставит пациента в лист ожидания к врачу или к любому врачу специальности.
+func (s *WaitlistService) CreateEntry(req *models.CreateWaitlistEntryRequest, actor string) (*models.WaitlistEntry, error) {
+	if req.Specialization != nil {
+		spec := strings.TrimSpace(*req.Specialization)
+		req.Specialization = &spec
+		if spec == "" {
+			req.Specialization = nil
+		}
+	}
+	if req.DoctorID == nil && req.Specialization == nil {
+		return nil, invalidError("необходимо указать врача или специальность")
+	}
+	if req.DoctorID != nil {
+		if _, err := s.doctorRepo.GetByID(*req.DoctorID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, notFoundError("врач с ID %d не найден", *req.DoctorID)
+			}
+			return nil, fmt.Errorf("ошибка проверки врача: %w", err)
+		}
+	}
+
+	from, to := truncateToDate(req.DateFrom), truncateToDate(req.DateTo)
+	if to.Before(from) {
+		return nil, invalidError("дата окончания периода раньше даты начала")
+	}
+	if to.Before(truncateToDate(time.Now())) {
+		return nil, invalidError("желаемый период уже прошел")
+	}
+
+	entry := models.WaitlistEntry{
+		PatientID:      req.PatientID,
+		DoctorID:       req.DoctorID,
+		Specialization: req.Specialization,
+		DateFrom:       from,
+		DateTo:         to,
+		Status:         models.WaitlistWaiting,
+		Comment:        strings.TrimSpace(req.Comment),
+		CreatedBy:      actor,
+	}
+	if err := s.repo.CreateEntry(&entry); err != nil {
+		if repository.IsForeignKeyViolation(err) {
+			return nil, notFoundError("пациент с ID %d не найден", req.PatientID)
+		}
+		return nil, fmt.Errorf("не удалось добавить заявку в лист ожидания: %w", err)
+	}
+	return s.repo.GetEntryByID(entry.ID)
+}
+
+// CancelEntry снимает заявку с листа ожидания. Если по заявке действует предложение,
+// оно считается отклоненным и слот передается следующему пациенту.
+func (s *WaitlistService) CancelEntry(id uint) error {
+	entry, err := s.repo.GetEntryByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFoundError("заявка с ID %d не найдена", id)
+		}
+		return fmt.Errorf("ошибка получения заявки: %w", err)
+	}
+	if entry.Status == models.WaitlistBooked || entry.Status == models.WaitlistCancelled {
+		return conflictError("заявка в статусе '%s' уже закрыта", entry.Status)
+	}
+	if err := s.repo.UpdateEntryStatus(id, models.WaitlistCancelled); err != nil {
+		return fmt.Errorf("не удалось отменить заявку: %w", err)
+	}
+
+	offer, err := s.repo.FindPendingOfferByEntry(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка поиска предложения по заявке: %w", err)
+	}
+	_, err = s.closeOffer(offer.ID, models.OfferDeclined)
+	return err
+}
+
+// GetOffers возвращает предложения слотов. Если статус не указан, возвращаются все.
+func (s *WaitlistService) GetOffers(status string) ([]models.WaitlistOffer, error) {
+	var filter *models.WaitlistOfferStatus
+	if status != "" {
+		st := models.WaitlistOfferStatus(status)
+		filter = &st
+	}
+	offers, err := s.repo.FindOffers(filter)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предложений: %w", err)
+	}
+	return offers, nil
+}
+
+// AcceptOffer записывает пациента на предложенный слот. Запись проверяется по правилам записи так же,
+// как при создании записи регистратором: при нарушениях без OverrideReason возвращается BookingRuleViolationError.
+func (s *WaitlistService) AcceptOffer(offerID uint, req *models.AcceptOfferRequest, actor string) (*models.Appointment, error) {
+	pending, err := s.repo.GetOfferByID(offerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFoundError("предложение с ID %d не найдено", offerID)
+		}
+		return nil, fmt.Errorf("ошибка при поиске предложения: %w", err)
+	}
+	check, err := s.rules.Evaluate(pending.Entry.PatientID, &pending.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	req.BookedBy = actor
+	req.OverrideReason = strings.TrimSpace(req.OverrideReason)
+	if len(check.Violations) > 0 {
+		if req.OverrideReason == "" {
+			return nil, &BookingRuleViolationError{Violations: check.Violations}
+		}
+		req.OverriddenBy = actor
+		req.OverriddenRules = check.ViolationTypes()
+	} else {
+		req.OverrideReason = ""
+	}
+
+	offer, appointment, err := s.repo.AcceptOffer(offerID, req)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось принять предложение: %w", err)
+	}
+	s.rules.attachReferral(check, appointment.ID)
+	s.publish(offer)
+	return appointment, nil
+}
+
+// DeclineOffer фиксирует отказ пациента и предлагает слот следующему в листе ожидания.
+func (s *WaitlistService) DeclineOffer(offerID uint) (*models.WaitlistOffer, error) {
+	return s.closeOffer(offerID, models.OfferDeclined)
+}
+
+// OnSlotFreed вызывается после отмены или переноса записи, пока слот еще недоступен: слот удерживается
+// для первой подходящей заявки либо освобождается. Ошибки не прерывают основную операцию, а только
+// журналируются; если предложить слот не удалось, он освобождается, чтобы не остаться занятым.
+func (s *WaitlistService) OnSlotFreed(scheduleID uint) {
+	if err := s.offerSlot(scheduleID); err != nil {
+		s.log.WithError(err).WithField("schedule_id", scheduleID).Error("Не удалось предложить освободившийся слот из листа ожидания")
+		if err := s.repo.ReleaseSlot(scheduleID); err != nil {
+			s.log.WithError(err).WithField("schedule_id", scheduleID).Error("Не удалось освободить слот")
+		}
+	}
+}
+
+// ExpireOffers закрывает предложения с истекшим сроком удержания и передает слоты следующим пациентам.
+func (s *WaitlistService) ExpireOffers() error {
+	runAt := time.Now()
+	offers, err := s.repo.FindExpiredOffers(runAt)
+	if err != nil {
+		return fmt.Errorf("ошибка поиска истекших предложений: %w", err)
+	}
+	for i := range offers {
+		closed, err := s.closeOffer(offers[i].ID, models.OfferExpired)
+		if closed != nil {
+			change := models.AuditChange{Entity: "offers", EntityID: strconv.FormatUint(uint64(closed.ID), 10), Before: &offers[i], After: closed}
+			if err := s.audit.RecordTask("waitlist/expire-offers", runAt, change); err != nil {
+				s.log.WithError(err).WithField("offer_id", closed.ID).Error("Не удалось записать закрытие предложения в журнал аудита")
+			}
+		}
+		if err != nil {
+			s.log.WithError(err).WithField("offer_id", offers[i].ID).Error("Не удалось закрыть истекшее предложение")
+		}
+	}
+	return nil
+}
+
+// Start периодически проверяет истекшие предложения до отмены контекста.
+func (s *WaitlistService) Start(ctx context.Context) {
+	ticker := time.NewTicker(waitlistSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.ExpireOffers(); err != nil {
+				s.log.WithError(err).Error("Ошибка обработки истекших предложений листа ожидания")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// closeOffer закрывает предложение и передает удерживаемый слот следующему пациенту.
+// Если слот не удалось передать, вместе с ошибкой возвращается уже закрытое предложение.
+func (s *WaitlistService) closeOffer(offerID uint, status models.WaitlistOfferStatus) (*models.WaitlistOffer, error) {
+	offer, err := s.repo.ResolveOffer(offerID, status)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось закрыть предложение: %w", err)
+	}
+	s.publish(offer)
+	if err := s.offerSlot(offer.ScheduleID); err != nil {
+		return offer, err
+	}
+	return offer, nil
+}
+
+// offerSlot предлагает слот первой подходящей заявке. Прошедшие слоты и слоты, отмененные календарем клиники,
+// не предлагаются и освобождаются.
+func (s *WaitlistService) offerSlot(scheduleID uint) error {
+	schedule, err := s.scheduleRepo.GetByID(scheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("ошибка получения слота расписания: %w", err)
+	}
+
+	offerable, err := s.slotOfferable(schedule)
+	if err != nil {
+		return err
+	}
+	if !offerable {
+		if err := s.repo.ReleaseSlot(scheduleID); err != nil {
+			return fmt.Errorf("не удалось освободить слот: %w", err)
+		}
+		return nil
+	}
+
+	offer, err := s.repo.OfferSlot(scheduleID, time.Now().Add(s.holdDuration))
+	if err != nil {
+		return fmt.Errorf("не удалось удержать слот для листа ожидания: %w", err)
+	}
+	if offer != nil {
+		s.log.WithField("offer_id", offer.ID).WithField("schedule_id", scheduleID).
+			WithField("patient_id", offer.Entry.PatientID).Info("Освободившийся слот предложен пациенту из листа ожидания")
+		s.publish(offer)
+	}
+	return nil
+}
+
+// slotOfferable проверяет, что прием в слоте еще не начался и не отменен календарем клиники.
+func (s *WaitlistService) slotOfferable(schedule *models.Schedule) (bool, error) {
+	if slotStarted(schedule, time.Now()) {
+		return false, nil
+	}
+	day := truncateToDate(schedule.Date)
+	cal, err := loadClinicCalendar(s.calendarRepo, day, day)
+	if err != nil {
+		return false, err
+	}
+	return cal.blockReason(schedule) == "", nil
+}
+
+// publish рассылает изменение предложения подписчикам потока регистратуры.
+func (s *WaitlistService) publish(offer *models.WaitlistOffer) {
+	payload, err := json.Marshal(models.WaitlistOfferNotification{
+		Event:       models.WaitlistOfferEvent,
+		OfferID:     offer.ID,
+		EntryID:     offer.EntryID,
+		Status:      offer.Status,
+		PatientID:   offer.Entry.PatientID,
+		PatientName: offer.Entry.Patient.FullName,
+		Phone:       offer.Entry.Patient.Phone,
+		ScheduleID:  offer.ScheduleID,
+		DoctorID:    offer.Schedule.DoctorID,
+		DoctorName:  offer.Schedule.Doctor.FullName,
+		Date:        offer.Schedule.Date.Format("2006-01-02"),
+		StartTime:   offer.Schedule.StartTime,
+		Cabinet:     offer.Schedule.Cabinet,
+		HeldUntil:   offer.HeldUntil,
+	})
+	if err != nil {
+		s.log.WithError(err).Error("Не удалось сформировать уведомление о предложении слота")
+		return
+	}
+	s.broker.Publish(string(payload))
+}
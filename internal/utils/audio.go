@@ -14,6 +14,18 @@ import (
 	"strings"
 )
 
+const (
+	// AudioDir - каталог с записями фраз и чисел для оповещений.
+	AudioDir = "assets/audio"
+	// MaxCabinetNumber - наибольший номер кабинета, который можно озвучить: сотни озвучиваются
+	// записями 100.wav - 900.wav.
+	MaxCabinetNumber = 999
+	// maxQueueNumber - наибольший номер окна или талона, который можно озвучить.
+	maxQueueNumber = 99
+
+	cabinetPhraseFile = "Пройдите_в_кабинет_номер.wav"
+)
+
 // WAVHeader представляет заголовок WAV файла
 type WAVHeader struct {
 	ChunkID       [4]byte
@@ -40,6 +52,48 @@ type WAVData struct {
 // GenerateAnnouncementWav создает WAV файл с озвучкой талона
 // ИЗМЕНЕНО: Добавлен параметр backgroundMusicEnabled
 func GenerateAnnouncementWav(ticketNumber, windowNumber, audioDir string, backgroundMusicEnabled bool) ([]byte, error) {
+	return generateAnnouncementWav(ticketNumber, "Подойдите_к_окну_номер.wav", windowNumber, maxQueueNumber, "окна", audioDir, backgroundMusicEnabled)
+}
+
+// GenerateCabinetAnnouncementWav создает WAV файл с приглашением пациента в кабинет врача:
+// "Клиент номер A7, пройдите в кабинет номер 12".
+func GenerateCabinetAnnouncementWav(ticketNumber, cabinetNumber, audioDir string, backgroundMusicEnabled bool) ([]byte, error) {
+	return generateAnnouncementWav(ticketNumber, cabinetPhraseFile, cabinetNumber, MaxCabinetNumber, "кабинета", audioDir, backgroundMusicEnabled)
+}
+
+// CheckCabinetAnnouncementFiles проверяет, что в audioDir есть все записи для приглашения в кабинеты
+// cabinetNumbers: фраза "пройдите в кабинет номер" и записи номеров. Ошибка перечисляет недостающие
+// файлы и номера, которые нельзя озвучить.
+func CheckCabinetAnnouncementFiles(cabinetNumbers []int, audioDir string) error {
+	files := []string{filepath.Join(audioDir, cabinetPhraseFile)}
+	for _, number := range cabinetNumbers {
+		numberFiles, err := getNumberFiles(strconv.Itoa(number), MaxCabinetNumber, audioDir)
+		if err != nil {
+			return fmt.Errorf("кабинет %d: %v", number, err)
+		}
+		files = append(files, numberFiles...)
+	}
+
+	var missing []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+		if _, err := os.Stat(file); err != nil {
+			missing = append(missing, filepath.Base(file))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("в каталоге %s нет записей для приглашения в кабинет: %s", audioDir, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// generateAnnouncementWav озвучивает номер талона, фразу-приглашение phraseFile и номер места (окна или кабинета)
+// от 1 до placeMax. placeName используется только в сообщениях об ошибках.
+func generateAnnouncementWav(ticketNumber, phraseFile, placeNumber string, placeMax int, placeName, audioDir string, backgroundMusicEnabled bool) ([]byte, error) {
 	// Парсим номер талона
 	letter, number, err := parseTicketNumber(ticketNumber)
 	if err != nil {
@@ -56,21 +110,21 @@ func GenerateAnnouncementWav(ticketNumber, windowNumber, audioDir string, backgr
 	audioFiles = append(audioFiles, filepath.Join(audioDir, fmt.Sprintf("%s.wav", letter)))
 
 	// 3. Номер талона (разбиваем на составляющие)
-	numberFiles, err := getNumberFiles(number, audioDir)
+	numberFiles, err := getNumberFiles(number, maxQueueNumber, audioDir)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения файлов для номера: %v", err)
 	}
 	audioFiles = append(audioFiles, numberFiles...)
 
-	// 4. Подойдите_к_окну_номер.wav
-	audioFiles = append(audioFiles, filepath.Join(audioDir, "Подойдите_к_окну_номер.wav"))
+	// 4. Фраза-приглашение, например Подойдите_к_окну_номер.wav
+	audioFiles = append(audioFiles, filepath.Join(audioDir, phraseFile))
 
-	// 5. Номер окна
-	windowFiles, err := getNumberFiles(windowNumber, audioDir)
+	// 5. Номер окна или кабинета
+	placeFiles, err := getNumberFiles(placeNumber, placeMax, audioDir)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка получения файлов для номера окна: %v", err)
+		return nil, fmt.Errorf("ошибка получения файлов для номера %s: %v", placeName, err)
 	}
-	audioFiles = append(audioFiles, windowFiles...)
+	audioFiles = append(audioFiles, placeFiles...)
 
 	// Загружаем и объединяем основные аудиофайлы
 	mainAudio, err := concatenateWavFiles(audioFiles)
@@ -130,19 +184,28 @@ func parseTicketNumber(ticket string) (string, string, error) {
 	return letter, strconv.Itoa(numberInt), nil
 }
 
-// getNumberFiles возвращает список файлов для озвучки числа
-func getNumberFiles(number, audioDir string) ([]string, error) {
+// getNumberFiles возвращает список файлов для озвучки числа от 1 до maxNumber
+func getNumberFiles(number string, maxNumber int, audioDir string) ([]string, error) {
 	num, err := strconv.Atoi(number)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка преобразования номера: %v", err)
 	}
 
-	if num < 1 || num > 99 {
-		return nil, fmt.Errorf("номер должен быть от 1 до 99")
+	if num < 1 || num > maxNumber {
+		return nil, fmt.Errorf("номер должен быть от 1 до %d", maxNumber)
 	}
 
 	var files []string
 
+	// Сотни озвучиваются отдельной записью (100.wav - 900.wav), остаток - как двузначное число
+	if num >= 100 {
+		files = append(files, filepath.Join(audioDir, fmt.Sprintf("%d.wav", (num/100)*100)))
+		num %= 100
+		if num == 0 {
+			return files, nil
+		}
+	}
+
 	if num <= 20 {
 		// Для чисел 1-20 есть отдельные файлы
 		files = append(files, filepath.Join(audioDir, fmt.Sprintf("%d.wav", num)))
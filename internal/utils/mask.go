@@ -0,0 +1,13 @@
+package utils
+
+import "strings"
+
+// MaskString заменяет все символы строки, кроме последних visible, на '*'.
+// Короткие строки маскируются целиком.
+func MaskString(value string, visible int) string {
+	runes := []rune(value)
+	if len(runes) <= visible {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-visible) + string(runes[len(runes)-visible:])
+}
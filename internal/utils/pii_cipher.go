@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PIICipher шифрует персональные данные (AES-256-GCM) и вычисляет для них слепые индексы (HMAC-SHA256).
+// Шифротекст имеет вид "<id ключа>:<base64(nonce|данные)>", поэтому после смены активного ключа
+// старые значения остаются читаемыми, пока их не перешифруют.
+type PIICipher struct {
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+	indexKey    []byte
+}
+
+// NewPIICipher создает шифратор из списка ключей вида "id1:base64,id2:base64" и ключа слепого индекса.
+// Первый ключ списка - активный, им шифруются новые значения; остальные используются только для чтения.
+// Все ключи должны иметь длину 32 байта. Ключ слепого индекса не ротируется: при его смене индексы придется пересчитать.
+func NewPIICipher(keys string, indexKey string) (*PIICipher, error) {
+	c := &PIICipher{keys: make(map[string]cipher.AEAD)}
+	for _, item := range strings.Split(keys, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(item, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("ключ шифрования должен иметь вид <id>:<base64>")
+		}
+		if _, exists := c.keys[id]; exists {
+			return nil, fmt.Errorf("ключ шифрования '%s' указан дважды", id)
+		}
+		raw, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("ключ шифрования '%s': %w", id, err)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("ключ шифрования '%s': %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("ключ шифрования '%s': %w", id, err)
+		}
+		c.keys[id] = aead
+		if c.activeKeyID == "" {
+			c.activeKeyID = id
+		}
+	}
+	if c.activeKeyID == "" {
+		return nil, fmt.Errorf("не задан ни один ключ шифрования персональных данных")
+	}
+
+	rawIndexKey, err := decodeKey(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("ключ слепого индекса: %w", err)
+	}
+	c.indexKey = rawIndexKey
+	return c, nil
+}
+
+// ActiveKeyID возвращает идентификатор ключа, которым шифруются новые значения.
+func (c *PIICipher) ActiveKeyID() string {
+	return c.activeKeyID
+}
+
+// Encrypt шифрует значение активным ключом.
+func (c *PIICipher) Encrypt(plaintext string) (string, error) {
+	aead := c.keys[c.activeKeyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(c.activeKeyID))
+	return c.activeKeyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение ключом, идентификатор которого указан в шифротексте.
+func (c *PIICipher) Decrypt(ciphertext string) (string, error) {
+	id, encoded, ok := strings.Cut(ciphertext, ":")
+	if !ok {
+		return "", fmt.Errorf("неверный формат шифротекста")
+	}
+	aead, exists := c.keys[id]
+	if !exists {
+		return "", fmt.Errorf("ключ шифрования '%s' не настроен", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("неверный формат шифротекста")
+	}
+	nonce, data := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("не удалось расшифровать значение ключом '%s'", id)
+	}
+	return string(plain), nil
+}
+
+// BlindIndex вычисляет детерминированный индекс значения для поиска и проверки уникальности
+// без расшифровки. domain разделяет индексы разных полей, чтобы одинаковые значения не совпадали.
+func (c *PIICipher) BlindIndex(domain, value string) string {
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(domain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("ключ должен быть в base64")
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("ключ должен иметь длину 32 байта, получено %d", len(raw))
+	}
+	return raw, nil
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter ограничивает количество запросов по ключу в фиксированном окне времени.
+type RateLimiter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	buckets map[string]*rateBucket
+}
+
+type rateBucket struct {
+	windowStart time.Time
+	count       int
+}
+
+// NewRateLimiter создает ограничитель с указанной длиной окна.
+func NewRateLimiter(window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		window:  window,
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// Allow учитывает запрос и возвращает false, если лимит для ключа в текущем окне исчерпан.
+// Лимит <= 0 означает отсутствие ограничений.
+func (l *RateLimiter) Allow(key string, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[key]
+	if !ok || now.Sub(bucket.windowStart) >= l.window {
+		// Заодно удаляем устаревшие окна, чтобы карта не росла бесконечно
+		if len(l.buckets) > 10000 {
+			l.evictExpired(now)
+		}
+		l.buckets[key] = &rateBucket{windowStart: now, count: 1}
+		return true
+	}
+
+	if bucket.count >= limit {
+		return false
+	}
+	bucket.count++
+	return true
+}
+
+func (l *RateLimiter) evictExpired(now time.Time) {
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.windowStart) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS schedule_templates;
\ No newline at end of file
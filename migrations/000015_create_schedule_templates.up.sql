@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS schedule_templates (
+    template_id SERIAL PRIMARY KEY,
+    doctor_id INTEGER NOT NULL REFERENCES doctors(doctor_id) ON DELETE CASCADE,
+    weekdays JSONB NOT NULL DEFAULT '[]'::jsonb,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    slot_minutes INTEGER NOT NULL CHECK (slot_minutes > 0),
+    cabinet INTEGER,
+    breaks JSONB NOT NULL DEFAULT '[]'::jsonb,
+    valid_from DATE NOT NULL,
+    valid_to DATE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_time > start_time),
+    CHECK (valid_to IS NULL OR valid_to >= valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_templates_doctor ON schedule_templates (doctor_id);
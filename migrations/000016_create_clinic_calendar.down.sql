@@ -0,0 +1,3 @@
+ALTER TABLE appointments DROP COLUMN IF EXISTS flag_reason;
+DROP TABLE IF EXISTS doctor_absences;
+DROP TABLE IF EXISTS clinic_calendar;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS clinic_calendar (
+    date DATE PRIMARY KEY,
+    day_type VARCHAR(20) NOT NULL CHECK (day_type IN ('выходной', 'сокращенный')),
+    work_start TIME,
+    work_end TIME,
+    description TEXT,
+    CHECK (day_type = 'выходной' OR (work_start IS NOT NULL AND work_end IS NOT NULL AND work_end > work_start))
+);
+
+CREATE TABLE IF NOT EXISTS doctor_absences (
+    absence_id SERIAL PRIMARY KEY,
+    doctor_id INTEGER NOT NULL REFERENCES doctors(doctor_id) ON DELETE CASCADE,
+    date_from DATE NOT NULL,
+    date_to DATE NOT NULL,
+    start_time TIME,
+    end_time TIME,
+    reason VARCHAR(30) NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (date_to >= date_from),
+    CHECK ((start_time IS NULL AND end_time IS NULL) OR (start_time IS NOT NULL AND end_time IS NOT NULL AND end_time > start_time))
+);
+
+CREATE INDEX IF NOT EXISTS idx_doctor_absences_doctor_dates ON doctor_absences (doctor_id, date_from, date_to);
+
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS flag_reason TEXT;
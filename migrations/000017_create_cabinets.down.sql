@@ -0,0 +1,3 @@
+ALTER TABLE schedule_templates DROP CONSTRAINT IF EXISTS fk_schedule_templates_cabinet;
+ALTER TABLE schedules DROP CONSTRAINT IF EXISTS fk_schedules_cabinet;
+DROP TABLE IF EXISTS cabinets;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS cabinets (
+    cabinet_number INTEGER PRIMARY KEY CHECK (cabinet_number > 0),
+    name VARCHAR(100) NOT NULL,
+    floor INTEGER,
+    wing VARCHAR(50),
+    directions TEXT,
+    equipment JSONB NOT NULL DEFAULT '[]'::jsonb,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Кабинеты, уже использующиеся в расписании и шаблонах, заводятся автоматически.
+INSERT INTO cabinets (cabinet_number, name)
+SELECT DISTINCT cabinet, 'Кабинет ' || cabinet
+FROM (
+    SELECT cabinet FROM schedules WHERE cabinet IS NOT NULL
+    UNION
+    SELECT cabinet FROM schedule_templates WHERE cabinet IS NOT NULL
+) used
+ON CONFLICT (cabinet_number) DO NOTHING;
+
+ALTER TABLE schedules DROP CONSTRAINT IF EXISTS fk_schedules_cabinet;
+ALTER TABLE schedules
+    ADD CONSTRAINT fk_schedules_cabinet FOREIGN KEY (cabinet) REFERENCES cabinets(cabinet_number) ON UPDATE CASCADE;
+
+ALTER TABLE schedule_templates DROP CONSTRAINT IF EXISTS fk_schedule_templates_cabinet;
+ALTER TABLE schedule_templates
+    ADD CONSTRAINT fk_schedule_templates_cabinet FOREIGN KEY (cabinet) REFERENCES cabinets(cabinet_number) ON UPDATE CASCADE;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_appointments_schedule_status;
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS chk_appointments_status;
+ALTER TABLE appointments
+    DROP COLUMN IF EXISTS rescheduled_to_id,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS cancelled_by,
+    DROP COLUMN IF EXISTS cancel_reason,
+    DROP COLUMN IF EXISTS status;
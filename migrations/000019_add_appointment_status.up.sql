@@ -0,0 +1,15 @@
+ALTER TABLE appointments
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'записан',
+    ADD COLUMN IF NOT EXISTS cancel_reason TEXT,
+    ADD COLUMN IF NOT EXISTS cancelled_by VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS rescheduled_to_id INTEGER REFERENCES appointments(appointment_id) ON DELETE SET NULL;
+
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS chk_appointments_status;
+ALTER TABLE appointments
+    ADD CONSTRAINT chk_appointments_status CHECK (status IN ('записан', 'отменен', 'перенесен', 'явился', 'не_явился'));
+
+-- Записи, по которым уже выдан талон, считаются состоявшимися.
+UPDATE appointments SET status = 'явился' WHERE ticket_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_appointments_schedule_status ON appointments (schedule_id, status);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    entry_id SERIAL PRIMARY KEY,
+    patient_id INTEGER NOT NULL REFERENCES patients(patient_id) ON DELETE CASCADE,
+    doctor_id INTEGER REFERENCES doctors(doctor_id) ON DELETE CASCADE,
+    specialization VARCHAR(100),
+    date_from DATE NOT NULL,
+    date_to DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ожидает' CHECK (status IN ('ожидает', 'предложено', 'записан', 'отменено')),
+    comment TEXT,
+    created_by VARCHAR(50),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (date_to >= date_from),
+    CHECK (doctor_id IS NOT NULL OR specialization IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_status_created ON waitlist_entries (status, created_at);
+
+CREATE TABLE IF NOT EXISTS waitlist_offers (
+    offer_id SERIAL PRIMARY KEY,
+    entry_id INTEGER NOT NULL REFERENCES waitlist_entries(entry_id) ON DELETE CASCADE,
+    schedule_id INTEGER NOT NULL REFERENCES schedules(schedule_id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'предложено' CHECK (status IN ('предложено', 'принято', 'отклонено', 'истекло')),
+    held_until TIMESTAMPTZ NOT NULL,
+    appointment_id INTEGER REFERENCES appointments(appointment_id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_waitlist_offers_schedule ON waitlist_offers (schedule_id);
+CREATE INDEX IF NOT EXISTS idx_waitlist_offers_pending ON waitlist_offers (held_until) WHERE status = 'предложено';
@@ -0,0 +1,3 @@
+DELETE FROM business_processes WHERE process_name = 'booking';
+DROP INDEX IF EXISTS idx_appointments_confirmation_code;
+ALTER TABLE appointments DROP COLUMN IF EXISTS confirmation_code;
@@ -0,0 +1,7 @@
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS confirmation_code VARCHAR(12);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appointments_confirmation_code ON appointments (confirmation_code) WHERE confirmation_code IS NOT NULL;
+
+-- Публичная самозапись пациентов по умолчанию выключена и включается администратором
+INSERT INTO business_processes (process_name, is_enabled) VALUES
+('booking', FALSE)
+ON CONFLICT (process_name) DO NOTHING;
@@ -0,0 +1,5 @@
+ALTER TABLE appointments DROP COLUMN IF EXISTS overridden_rules;
+ALTER TABLE appointments DROP COLUMN IF EXISTS overridden_by;
+ALTER TABLE appointments DROP COLUMN IF EXISTS override_reason;
+DROP TABLE IF EXISTS referrals;
+DROP TABLE IF EXISTS booking_rules;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS booking_rules (
+    rule_id SERIAL PRIMARY KEY,
+    specialization VARCHAR(100) NOT NULL,
+    max_active_appointments INTEGER CHECK (max_active_appointments > 0),
+    min_interval_days INTEGER CHECK (min_interval_days > 0),
+    min_age INTEGER CHECK (min_age >= 0),
+    max_age INTEGER CHECK (max_age >= 0),
+    referral_required BOOLEAN NOT NULL DEFAULT FALSE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (min_age IS NULL OR max_age IS NULL OR max_age >= min_age)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_booking_rules_specialization ON booking_rules (LOWER(specialization));
+
+CREATE TABLE IF NOT EXISTS referrals (
+    referral_id SERIAL PRIMARY KEY,
+    patient_id INTEGER NOT NULL REFERENCES patients(patient_id) ON DELETE CASCADE,
+    specialization VARCHAR(100) NOT NULL,
+    referral_number VARCHAR(50),
+    issued_by VARCHAR(150),
+    issued_at DATE NOT NULL,
+    valid_until DATE NOT NULL,
+    appointment_id INTEGER REFERENCES appointments(appointment_id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (valid_until >= issued_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_referrals_patient ON referrals (patient_id, valid_until);
+
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS override_reason TEXT;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS overridden_by VARCHAR(50);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS overridden_rules JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS patient_merges;
+DROP INDEX IF EXISTS idx_patients_birth_date;
+DROP INDEX IF EXISTS idx_patients_oms_number;
+DROP INDEX IF EXISTS idx_patients_passport_active;
+ALTER TABLE patients ADD CONSTRAINT patients_passport_series_passport_number_key UNIQUE (passport_series, passport_number);
+ALTER TABLE patients DROP COLUMN IF EXISTS merged_into_id;
+ALTER TABLE patients DROP COLUMN IF EXISTS delete_reason;
+ALTER TABLE patients DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE patients DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,27 @@
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(50);
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS delete_reason TEXT;
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS merged_into_id INTEGER REFERENCES patients(patient_id);
+
+-- Паспорт уникален только среди действующих пациентов: удаленная или объединенная карточка не мешает завести новую.
+ALTER TABLE patients DROP CONSTRAINT IF EXISTS patients_passport_series_passport_number_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patients_passport_active ON patients (passport_series, passport_number) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_patients_oms_number ON patients (oms_number);
+CREATE INDEX IF NOT EXISTS idx_patients_birth_date ON patients (birth_date);
+
+CREATE TABLE IF NOT EXISTS patient_merges (
+    merge_id SERIAL PRIMARY KEY,
+    source_patient_id INTEGER NOT NULL REFERENCES patients(patient_id),
+    target_patient_id INTEGER NOT NULL REFERENCES patients(patient_id),
+    reason TEXT,
+    merged_by VARCHAR(50),
+    moved_appointments INTEGER NOT NULL DEFAULT 0,
+    moved_waitlist_entries INTEGER NOT NULL DEFAULT 0,
+    moved_referrals INTEGER NOT NULL DEFAULT 0,
+    source_snapshot JSONB NOT NULL,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (source_patient_id <> target_patient_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_merges_target ON patient_merges (target_patient_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_patients_phone_digits_trgm;
+DROP INDEX IF EXISTS idx_patients_passport_trgm;
+DROP INDEX IF EXISTS idx_patients_oms_trgm;
+DROP INDEX IF EXISTS idx_patients_full_name_trgm;
+-- Расширение pg_trgm не удаляется: его могут использовать другие объекты базы.
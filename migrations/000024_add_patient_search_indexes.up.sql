@@ -0,0 +1,11 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Выражения индексов должны совпадать с выражениями в patientRepo.Search.
+CREATE INDEX IF NOT EXISTS idx_patients_full_name_trgm ON patients
+    USING gin ((replace(lower(full_name), 'ё', 'е')) gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_patients_oms_trgm ON patients
+    USING gin (oms_number gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_patients_passport_trgm ON patients
+    USING gin ((passport_series || passport_number) gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_patients_phone_digits_trgm ON patients
+    USING gin ((regexp_replace(phone, '[^0-9]+', '', 'g')) gin_trgm_ops) WHERE deleted_at IS NULL;
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS pii_access_logs;
+
+DROP INDEX IF EXISTS idx_patients_oms_index;
+DROP INDEX IF EXISTS idx_patients_passport_index_active;
+
+-- Зашифрованные значения не восстанавливаются в открытые столбцы: перед откатом
+-- карточки должны быть расшифрованы приложением, иначе документы будут потеряны.
+ALTER TABLE patients DROP COLUMN IF EXISTS oms_index;
+ALTER TABLE patients DROP COLUMN IF EXISTS passport_index;
+ALTER TABLE patients DROP COLUMN IF EXISTS oms_number_enc;
+ALTER TABLE patients DROP COLUMN IF EXISTS passport_number_enc;
+ALTER TABLE patients DROP COLUMN IF EXISTS passport_series_enc;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patients_passport_active ON patients (passport_series, passport_number) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_patients_oms_number ON patients (oms_number);
+CREATE INDEX IF NOT EXISTS idx_patients_oms_trgm ON patients
+    USING gin (oms_number gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_patients_passport_trgm ON patients
+    USING gin ((passport_series || passport_number) gin_trgm_ops) WHERE deleted_at IS NULL;
@@ -0,0 +1,33 @@
+-- Паспорт и полис ОМС хранятся зашифрованными; для поиска и проверки уникальности используются слепые индексы (HMAC).
+-- Существующие открытые значения шифруются приложением при запуске, после чего столбцы passport_series,
+-- passport_number и oms_number очищаются.
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS passport_series_enc TEXT;
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS passport_number_enc TEXT;
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS oms_number_enc TEXT;
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS passport_index VARCHAR(64);
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS oms_index VARCHAR(64);
+
+ALTER TABLE patients ALTER COLUMN passport_series DROP NOT NULL;
+ALTER TABLE patients ALTER COLUMN passport_number DROP NOT NULL;
+ALTER TABLE patients ALTER COLUMN oms_number DROP NOT NULL;
+
+-- Индексы по открытым значениям заменяются индексами по слепым индексам.
+DROP INDEX IF EXISTS idx_patients_passport_active;
+DROP INDEX IF EXISTS idx_patients_oms_number;
+DROP INDEX IF EXISTS idx_patients_oms_trgm;
+DROP INDEX IF EXISTS idx_patients_passport_trgm;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patients_passport_index_active ON patients (passport_index) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_patients_oms_index ON patients (oms_index);
+
+CREATE TABLE IF NOT EXISTS pii_access_logs (
+    access_id SERIAL PRIMARY KEY,
+    patient_id INTEGER NOT NULL REFERENCES patients(patient_id),
+    actor VARCHAR(50) NOT NULL,
+    fields JSONB NOT NULL,
+    reason TEXT NOT NULL,
+    client_ip VARCHAR(45),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pii_access_logs_patient ON pii_access_logs (patient_id, created_at);
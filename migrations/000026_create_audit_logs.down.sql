@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP FUNCTION IF EXISTS forbid_audit_log_change();
+DROP TABLE IF EXISTS audit_logs;
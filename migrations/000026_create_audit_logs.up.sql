@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    audit_id BIGSERIAL PRIMARY KEY,
+    request_id VARCHAR(64) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    role VARCHAR(50),
+    client_ip VARCHAR(45),
+    method VARCHAR(10) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    status_code INTEGER NOT NULL,
+    entity VARCHAR(100),
+    entity_id VARCHAR(100),
+    before JSONB,
+    after JSONB,
+    diff JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs (actor, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs (request_id);
+
+-- Журнал аудита только дополняется: изменение и удаление записей запрещены.
+CREATE OR REPLACE FUNCTION forbid_audit_log_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION forbid_audit_log_change();
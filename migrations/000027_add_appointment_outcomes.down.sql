@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_appointments_ticket_id;
+
+ALTER TABLE appointments DROP COLUMN IF EXISTS outcome_recorded_by;
+ALTER TABLE appointments DROP COLUMN IF EXISTS outcome_recorded_at;
+ALTER TABLE appointments DROP COLUMN IF EXISTS issued_referral_id;
+ALTER TABLE appointments DROP COLUMN IF EXISTS referral_specialization;
+ALTER TABLE appointments DROP COLUMN IF EXISTS follow_up_needed;
+ALTER TABLE appointments DROP COLUMN IF EXISTS visit_note;
+ALTER TABLE appointments DROP COLUMN IF EXISTS diagnosis_code;
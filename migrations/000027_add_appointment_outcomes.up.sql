@@ -0,0 +1,9 @@
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS diagnosis_code VARCHAR(10);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS visit_note TEXT;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS follow_up_needed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS referral_specialization VARCHAR(100);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS issued_referral_id INTEGER REFERENCES referrals(referral_id);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS outcome_recorded_at TIMESTAMPTZ;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS outcome_recorded_by VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_appointments_ticket_id ON appointments (ticket_id);
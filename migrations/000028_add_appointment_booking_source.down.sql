@@ -0,0 +1,3 @@
+ALTER TABLE appointments DROP COLUMN IF EXISTS source_appointment_id;
+ALTER TABLE appointments DROP COLUMN IF EXISTS booking_kind;
+ALTER TABLE appointments DROP COLUMN IF EXISTS booked_by;
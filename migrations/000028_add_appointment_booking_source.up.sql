@@ -0,0 +1,3 @@
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS booked_by VARCHAR(50);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS booking_kind VARCHAR(20);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS source_appointment_id INTEGER REFERENCES appointments(appointment_id);
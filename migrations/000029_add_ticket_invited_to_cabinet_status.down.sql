@@ -0,0 +1,12 @@
+-- Приглашенные в кабинет пациенты возвращаются в очередь к врачу.
+UPDATE tickets SET status = 'зарегистрирован' WHERE status = 'приглашен_в_кабинет';
+
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS tickets_status_check;
+ALTER TABLE tickets
+    ADD CONSTRAINT tickets_status_check CHECK (status IN (
+        'ожидает',
+        'приглашен',
+        'на_приеме',
+        'завершен',
+        'зарегистрирован'
+    ));
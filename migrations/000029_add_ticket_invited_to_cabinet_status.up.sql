@@ -0,0 +1,10 @@
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS tickets_status_check;
+ALTER TABLE tickets
+    ADD CONSTRAINT tickets_status_check CHECK (status IN (
+        'ожидает',
+        'приглашен',
+        'на_приеме',
+        'завершен',
+        'зарегистрирован',
+        'приглашен_в_кабинет'
+    ));
@@ -0,0 +1,22 @@
+-- Пометки записей хранятся отдельно по источнику (день календаря, отсутствие врача), чтобы снятие
+-- пометки одного источника не стирало пометку другого. appointments.flag_reason остается сводкой.
+CREATE TABLE IF NOT EXISTS appointment_flags (
+    appointment_id INTEGER NOT NULL REFERENCES appointments(appointment_id) ON DELETE CASCADE,
+    source VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (appointment_id, source)
+);
+
+CREATE INDEX IF NOT EXISTS idx_appointment_flags_source ON appointment_flags (source);
+
+INSERT INTO appointment_flags (appointment_id, source, reason)
+SELECT appointment_id,
+       CASE
+           WHEN flag_reason LIKE 'календарь клиники: %' THEN 'clinic_day:' || substring(flag_reason FROM 'календарь клиники: (\d{4}-\d{2}-\d{2})')
+           ELSE 'absence:' || substring(flag_reason FROM 'отсутствие врача #(\d+)')
+       END,
+       flag_reason
+FROM appointments
+WHERE flag_reason LIKE 'календарь клиники: %' OR flag_reason ~ '^отсутствие врача #\d+'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+-- Маскирование снимков необратимо: открытые значения документов не восстанавливаются.
+SELECT 1;
@@ -0,0 +1,16 @@
+-- Снимки карточек в журнале объединений, сохраненные до включения шифрования, содержат документы
+-- в открытом виде. Они маскируются так же, как при выдаче карточки через API (необратимо).
+UPDATE patient_merges
+SET source_snapshot = source_snapshot || jsonb_build_object(
+    'passport_series', repeat('*', char_length(COALESCE(source_snapshot->>'passport_series', ''))),
+    'passport_number', CASE
+        WHEN char_length(COALESCE(source_snapshot->>'passport_number', '')) <= 2
+            THEN repeat('*', char_length(COALESCE(source_snapshot->>'passport_number', '')))
+        ELSE repeat('*', char_length(source_snapshot->>'passport_number') - 2) || right(source_snapshot->>'passport_number', 2)
+    END,
+    'oms_number', CASE
+        WHEN char_length(COALESCE(source_snapshot->>'oms_number', '')) <= 4
+            THEN repeat('*', char_length(COALESCE(source_snapshot->>'oms_number', '')))
+        ELSE repeat('*', char_length(source_snapshot->>'oms_number') - 4) || right(source_snapshot->>'oms_number', 4)
+    END
+);
@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
@@ -0,0 +1,5 @@
+-- Журнал аудита нельзя и очистить: триггер на строки не срабатывает при TRUNCATE.
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+CREATE TRIGGER audit_logs_no_truncate
+    BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION forbid_audit_log_change();
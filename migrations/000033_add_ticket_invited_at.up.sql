@@ -0,0 +1,2 @@
+-- Время приглашения в кабинет хранится отдельно от времени вызова к окну регистратуры (called_at).
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS invited_at TIMESTAMP;